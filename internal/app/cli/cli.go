@@ -4,12 +4,17 @@ package cli
 import (
 	"asvsoft/internal/app/cli/command/camera"
 	"asvsoft/internal/app/cli/command/check"
+	"asvsoft/internal/app/cli/command/config"
 	"asvsoft/internal/app/cli/command/controller"
 	depthmeter "asvsoft/internal/app/cli/command/depth-meter"
+	"asvsoft/internal/app/cli/command/export"
 	"asvsoft/internal/app/cli/command/lidar"
+	"asvsoft/internal/app/cli/command/mux"
 	neom8t "asvsoft/internal/app/cli/command/neo-m8t"
 	"asvsoft/internal/app/cli/command/registrar"
+	"asvsoft/internal/app/cli/command/replay"
 	sensehat "asvsoft/internal/app/cli/command/sense-hat"
+	"asvsoft/internal/app/cli/command/sessions"
 	"asvsoft/internal/app/ctxutils"
 	"os"
 	"time"
@@ -41,6 +46,11 @@ func RootCmd() *cobra.Command {
 		check.Cmd(),
 		camera.Cmd(),
 		registrar.Cmd(),
+		mux.Cmd(),
+		export.Cmd(),
+		replay.Cmd(),
+		sessions.Cmd(),
+		config.Cmd(),
 	)
 
 	return &rootCmd
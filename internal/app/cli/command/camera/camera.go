@@ -26,5 +26,7 @@ func Cmd() *cobra.Command {
 
 	cfg.ControllerSerialPort = common.AddSerialDestinationFlags(cmd)
 
+	common.AddModuleConfigFlags(cmd)
+
 	return cmd
 }
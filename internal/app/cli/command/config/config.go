@@ -0,0 +1,37 @@
+// Package config предоставляет подкоманду config
+package config
+
+import (
+	"asvsoft/internal/app/cli/common"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ctrlCfgPath string
+)
+
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Работа с конфигурацией контроллера",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Проверка конфигурации контроллера",
+		Args:  cobra.NoArgs,
+		RunE:  common.ConfigValidateHandler(&ctrlCfgPath),
+		// ошибки конфигурации выводятся построчно, справка по флагам их только скрывает
+		SilenceUsage: true,
+	}
+	validateCmd.Flags().StringVarP(
+		&ctrlCfgPath, "config", "c",
+		"/etc/asvsoft/config.yaml",
+		"Path to config",
+	)
+
+	cmd.AddCommand(validateCmd)
+
+	return cmd
+}
@@ -9,17 +9,17 @@ import (
 )
 
 var (
-	ctrlCfgPath *string
+	ctrlCfgPath string
 )
 
 func Cmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "controller",
 		Short: "Контроллер управления",
-		RunE:  common.ControllerHandler(proto.ControlModuleID, ctrlCfgPath),
+		RunE:  common.ControllerHandler(proto.ControlModuleID, &ctrlCfgPath),
 	}
 	cmd.Flags().StringVarP(
-		ctrlCfgPath, "config", "c",
+		&ctrlCfgPath, "config", "c",
 		"/etc/asvsoft/config.yaml",
 		"Path to config",
 	)
@@ -21,5 +21,7 @@ func Cmd() *cobra.Command {
 	cfg.SensorSerialPort = common.AddSerialSourceFlags(cmd)
 	cfg.ControllerSerialPort = common.AddSerialDestinationFlags(cmd)
 
+	common.AddModuleConfigFlags(cmd)
+
 	return cmd
 }
@@ -0,0 +1,41 @@
+// Package export предоставляет подкоманду export
+package export
+
+import (
+	"asvsoft/internal/app/cli/common"
+
+	"github.com/spf13/cobra"
+)
+
+var opts common.ExportOptions
+
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [flags] file...",
+		Short: "Экспорт записей регистратора и дампов в таблицы CSV и JSON Lines",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  common.ExportHandler(&opts),
+	}
+	cmd.Flags().StringVarP(
+		&opts.Output, "output", "o",
+		".",
+		"Directory for module tables",
+	)
+	cmd.Flags().StringVarP(
+		&opts.Format, "format", "f",
+		"csv",
+		"Tables format: csv or jsonl",
+	)
+	cmd.Flags().StringVar(
+		&opts.From, "from",
+		"",
+		"Export frames received since RFC 3339 time or duration from the first frame",
+	)
+	cmd.Flags().StringVar(
+		&opts.To, "to",
+		"",
+		"Export frames received before RFC 3339 time or duration from the first frame",
+	)
+
+	return cmd
+}
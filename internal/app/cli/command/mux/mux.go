@@ -0,0 +1,27 @@
+// Package mux предоставляет подкоманду mux
+package mux
+
+import (
+	"asvsoft/internal/app/cli/common"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	muxCfgPath string
+)
+
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mux",
+		Short: "Мультиплексор модулей в общий канал связи",
+		RunE:  common.MuxHandler(&muxCfgPath),
+	}
+	cmd.Flags().StringVarP(
+		&muxCfgPath, "config", "c",
+		"/etc/asvsoft/mux.yaml",
+		"Path to config",
+	)
+
+	return cmd
+}
@@ -26,6 +26,9 @@ func Cmd() *cobra.Command {
 		&cfg.NeoM8t.Rate, "rate",
 		1, "navigation solution rate in second",
 	)
+	common.BindConfigKey(cmd, "rate", "neo_m8t.rate")
+
+	common.AddModuleConfigFlags(cmd)
 
 	return cmd
 }
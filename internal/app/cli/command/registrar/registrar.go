@@ -9,17 +9,17 @@ import (
 )
 
 var (
-	ctrlCfgPath *string
+	ctrlCfgPath string
 )
 
 func Cmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "registrar",
 		Short: "Бортовой регистратор",
-		RunE:  common.ControllerHandler(proto.RegistratorModuleID, ctrlCfgPath),
+		RunE:  common.ControllerHandler(proto.RegistratorModuleID, &ctrlCfgPath),
 	}
 	cmd.Flags().StringVarP(
-		ctrlCfgPath, "config", "c",
+		&ctrlCfgPath, "config", "c",
 		"/etc/asvsoft/config.yaml",
 		"Path to config",
 	)
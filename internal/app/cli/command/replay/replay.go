@@ -0,0 +1,46 @@
+// Package replay предоставляет подкоманду replay
+package replay
+
+import (
+	"asvsoft/internal/app/cli/common"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var opts common.ReplayOptions
+
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay [flags] file...",
+		Short: "Воспроизведение записей регистратора в канал связи",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  common.ReplayHandler(&opts),
+	}
+
+	opts.Port = common.AddSerialPortFlags(cmd, "dst")
+
+	cmd.Flags().Float64Var(
+		&opts.Speed, "speed",
+		1, "replay speed factor, 0 - without delays",
+	)
+	cmd.Flags().BoolVar(
+		&opts.Loop, "loop",
+		false, "replay recordings in a loop until interrupted",
+	)
+	cmd.Flags().StringSliceVar(
+		&opts.Modules, "modules",
+		nil, "replay frames of these modules only (e.g. gnss,imu)",
+	)
+	cmd.Flags().BoolVar(
+		&opts.Restamp, "restamp",
+		false, "replace frame system time with milliseconds since replay start",
+	)
+
+	cmd.Flags().DurationVar(
+		&opts.Hold, "hold",
+		3*time.Second, "keep the port open after the last frame for the receiver to process it",
+	)
+
+	return cmd
+}
@@ -5,6 +5,7 @@ import (
 	"asvsoft/internal/app/cli/common"
 	"asvsoft/internal/app/config"
 	sensehat "asvsoft/internal/app/sensors/sense-hat"
+	"asvsoft/internal/pkg/ahrs"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -32,7 +33,17 @@ func Cmd() *cobra.Command {
 
 	cmd.Flags().StringVar(
 		&cfg.SenseHAT.Mode, "mode",
-		sensehat.IntertialMode, "режим чтения данных",
+		sensehat.IntertialMode, "режим чтения данных: inertial, full или ahrs",
+	)
+
+	cmd.Flags().Float64Var(
+		&cfg.SenseHAT.AHRS.Beta, "ahrs-beta",
+		ahrs.DefaultBeta, "коэффициент коррекции фильтра Маджвика в режиме ahrs в рад/с",
+	)
+
+	cmd.Flags().Float64Var(
+		&cfg.SenseHAT.AHRS.Declination, "declination",
+		0, "магнитное склонение в градусах в режиме ahrs, восточное положительное",
 	)
 
 	cmd.Flags().Float32Var(
@@ -65,5 +76,22 @@ func Cmd() *cobra.Command {
 		false, "флаг удаления постоянного сдвига гироскопов",
 	)
 
+	for name, key := range map[string]string{
+		"period":        "period",
+		"mode":          "mode",
+		"acc-order":     "acc.order",
+		"gyr-order":     "gyr.order",
+		"mag-order":     "mag.order",
+		"acc-range":     "acc.range",
+		"gyr-range":     "gyr.range",
+		"remove-offset": "gyr.remove_offset",
+		"ahrs-beta":     "ahrs.beta",
+		"declination":   "ahrs.declination",
+	} {
+		common.BindConfigKey(cmd, name, "sense_hat."+key)
+	}
+
+	common.AddModuleConfigFlags(cmd)
+
 	return cmd
 }
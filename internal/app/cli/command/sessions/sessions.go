@@ -0,0 +1,48 @@
+// Package sessions предоставляет подкоманду sessions
+package sessions
+
+import (
+	"asvsoft/internal/app/cli/common"
+
+	"github.com/spf13/cobra"
+)
+
+var opts common.SessionsOptions
+
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Сессии записи контроллера и регистратора",
+	}
+	cmd.PersistentFlags().StringVarP(
+		&opts.Config, "config", "c",
+		"/etc/asvsoft/config.yaml",
+		"Path to config",
+	)
+	cmd.PersistentFlags().StringVar(
+		&opts.Dir, "dir", "",
+		"Sessions directory, overrides config",
+	)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Список сессий от старых к новым",
+		Args:  cobra.NoArgs,
+		RunE:  common.SessionsListHandler(&opts),
+	}
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Удаление старых сессий при нехватке места на диске",
+		Args:  cobra.NoArgs,
+		RunE:  common.SessionsPruneHandler(&opts),
+	}
+	pruneCmd.Flags().Uint64Var(
+		&opts.MinFree, "min-free", 0,
+		"Minimum free disk space in bytes, overrides config",
+	)
+
+	cmd.AddCommand(listCmd, pruneCmd)
+
+	return cmd
+}
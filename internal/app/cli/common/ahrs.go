@@ -0,0 +1,72 @@
+package common
+
+import (
+	"asvsoft/internal/pkg/ahrs"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/imu"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"time"
+)
+
+// ahrsFilter фильтр сообщений ИНС с инерциальными измерениями
+var ahrsFilter = bus.Filter{
+	Modules:  []proto.ModuleID{proto.IMUModuleID},
+	Messages: []proto.MessageID{proto.WritingModeA, proto.WritingModeB},
+}
+
+// ahrsRunner оценивает ориентацию по каждому сообщению ИНС и публикует ее сообщением модуля
+// navigation (режим WritingModeB) с системным временем измерения. Сообщения обрабатываются
+// последовательно одним подписчиком шины.
+type ahrsRunner struct {
+	store  *state.Store
+	msgBus *bus.Bus
+
+	filter *ahrs.Madgwick
+	// last системное время предыдущего измерения
+	last    uint32
+	lastSet bool
+}
+
+func newAHRSRunner(filter *ahrs.Madgwick, store *state.Store, msgBus *bus.Bus) *ahrsRunner {
+	return &ahrsRunner{
+		store:  store,
+		msgBus: msgBus,
+		filter: filter,
+	}
+}
+
+// Handle учитывает измерение ИНС и публикует ориентацию.
+func (r *ahrsRunner) Handle(msg proto.Message) error {
+	data, ok := msg.Payload.(*proto.IMUData)
+	if !ok {
+		return nil
+	}
+
+	s, ok := imu.FromProto(data, msg.MsgID)
+	if !ok {
+		return nil
+	}
+
+	var dt float64
+	if r.lastSet {
+		// с учетом переполнения системного времени
+		dt = (time.Duration(int32(msg.SystemTime-r.last)) * time.Millisecond).Seconds()
+	}
+
+	r.last, r.lastSet = msg.SystemTime, true
+
+	r.filter.Update(s, dt)
+
+	out := proto.Message{
+		ModuleID:   proto.NavigationModuleID,
+		MsgID:      proto.WritingModeB,
+		SystemTime: msg.SystemTime,
+		Payload:    r.filter.Attitude().NavigationData(),
+	}
+
+	r.store.Update(out)
+	r.msgBus.Publish(out)
+
+	return nil
+}
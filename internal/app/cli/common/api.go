@@ -0,0 +1,192 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/api"
+	"asvsoft/internal/pkg/export"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// modules реализует api.Controller
+var _ api.Controller = (*modules)(nil)
+
+// Modules возвращает состояние настроенных модулей.
+func (ms *modules) Modules() []api.Module {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	result := make([]api.Module, 0, len(ms.cfgs))
+
+	for _, name := range slices.Sorted(maps.Keys(ms.cfgs)) {
+		result = append(result, ms.status(name))
+	}
+
+	return result
+}
+
+// Module возвращает состояние модуля name.
+func (ms *modules) Module(name string) (api.Module, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.cfgs[name]; !ok {
+		return api.Module{}, fmt.Errorf("%w: %s", api.ErrNotFound, name)
+	}
+
+	return ms.status(name), nil
+}
+
+// SetEnabled запускает или останавливает модуль name до следующего изменения файла конфигурации.
+func (ms *modules) SetEnabled(name string, enabled bool) error {
+	ms.ops.Lock()
+	defer ms.ops.Unlock()
+
+	connCfg, ok := ms.cfgs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", api.ErrNotFound, name)
+	}
+
+	ms.mu.Lock()
+	ms.enabled[name] = enabled
+	ms.mu.Unlock()
+
+	_, running := ms.running[name]
+
+	switch {
+	case enabled && !running:
+		cfg := *connCfg
+		cfg.Enabled = true
+
+		return ms.start(name, &cfg)
+	case !enabled && running:
+		ms.remove(name)
+	}
+
+	return nil
+}
+
+// Resync переоткрывает порт модуля name: сбрасываются поиск заголовка фрейма, собираемые
+// изображения и подключение.
+func (ms *modules) Resync(name string) error {
+	ms.ops.Lock()
+	defer ms.ops.Unlock()
+
+	if _, ok := ms.cfgs[name]; !ok {
+		return fmt.Errorf("%w: %s", api.ErrNotFound, name)
+	}
+
+	m, ok := ms.running[name]
+	if !ok {
+		return fmt.Errorf("module %s is not running", name)
+	}
+
+	ms.stop(name)
+
+	return ms.start(name, m.cfg)
+}
+
+// status возвращает состояние модуля name. Вызывается с ms.mu.
+func (ms *modules) status(name string) api.Module {
+	connCfg := ms.cfgs[name]
+
+	status := api.Module{
+		Name:    name,
+		Enabled: connCfg.Enabled,
+		Port:    apiPort(connCfg.Listener),
+		Modules: connCfg.Modules,
+		Sync:    api.SyncState{StartStamp: proto.GetStartStamp()},
+		Signals: []api.Signal{},
+	}
+
+	if enabled, ok := ms.enabled[name]; ok {
+		status.Enabled = enabled
+	}
+
+	m, ok := ms.running[name]
+	if !ok {
+		return status
+	}
+
+	status.Running = true
+	status.Port = apiPort(m.cfg.Listener)
+
+	link := m.rcvr.Stats()
+	status.Link = &link
+
+	status.Sync.Requests = m.syncRequests.Load()
+	if last := m.lastSync.Load(); last != 0 {
+		t := time.Unix(0, last)
+		status.Sync.LastRequest = &t
+	}
+
+	for _, id := range m.received() {
+		for _, e := range ms.store.Module(id) {
+			status.Signals = append(status.Signals, apiSignal(e))
+		}
+	}
+
+	return status
+}
+
+func apiPort(cfg *config.SerialPortConfig) api.Port {
+	if cfg == nil {
+		return api.Port{}
+	}
+
+	return api.Port{
+		Address:  cfg.Port,
+		BaudRate: cfg.BaudRate,
+		Timeout:  cfg.Timeout.String(),
+		Sync:     cfg.Sync,
+	}
+}
+
+func apiSignal(e state.Entry) api.Signal {
+	signal := api.Signal{
+		Module:     proto.ModuleName(e.Module),
+		Msg:        fmt.Sprintf("%#x", uint8(e.Msg)),
+		SystemTime: e.Latest.SystemTime,
+		RecvTime:   e.Latest.RecvTime,
+		Count:      e.Count,
+		Rate:       e.Rate,
+		AgeMs:      e.Age.Milliseconds(),
+		Stale:      e.Stale,
+	}
+
+	signal.Fields = apiFields(proto.Message{
+		ModuleID:   e.Module,
+		MsgID:      e.Msg,
+		SystemTime: e.Latest.SystemTime,
+		Payload:    e.Latest.Payload,
+	})
+
+	return signal
+}
+
+// apiFields возвращает поля данных сообщения в единицах СИ, nil - поля не определены.
+func apiFields(msg proto.Message) map[string]*float64 {
+	fields, ok := export.Fields(msg)
+	if !ok {
+		return nil
+	}
+
+	result := make(map[string]*float64, len(fields))
+
+	for _, f := range fields {
+		if math.IsNaN(f.Value) || math.IsInf(f.Value, 0) {
+			result[f.Name] = nil
+			continue
+		}
+
+		v := f.Value
+		result[f.Name] = &v
+	}
+
+	return result
+}
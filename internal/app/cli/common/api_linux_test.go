@@ -0,0 +1,68 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/api"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIModuleControl(t *testing.T) {
+	ms := newTestModules(t)
+
+	require.NoError(t, ms.startAll(map[string]*config.ModuleConnectionConfig{"imu": ptyModule(t)}))
+
+	srv := httptest.NewServer(api.NewServer(ms).Handler())
+	defer srv.Close()
+
+	client := srv.Client()
+	client.Timeout = 5 * time.Second
+
+	post := func(t *testing.T, path string) api.Module {
+		t.Helper()
+
+		// прием должен дойти до чтения порта
+		time.Sleep(100 * time.Millisecond)
+
+		resp, err := client.Post(srv.URL+path, "application/json", nil)
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var m api.Module
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+
+		return m
+	}
+
+	t.Run("переоткрытие порта модуля", func(t *testing.T) {
+		old := ms.running["imu"]
+
+		m := post(t, "/api/v1/modules/imu/resync")
+		require.True(t, m.Running)
+
+		requireClosed(t, old)
+		require.NotSame(t, old, ms.running["imu"])
+	})
+
+	t.Run("выключение и включение модуля", func(t *testing.T) {
+		old := ms.running["imu"]
+
+		m := post(t, "/api/v1/modules/imu/disable")
+		require.False(t, m.Enabled)
+		require.False(t, m.Running)
+
+		requireStopped(t, old)
+
+		m = post(t, "/api/v1/modules/imu/enable")
+		require.True(t, m.Enabled)
+		require.True(t, m.Running)
+	})
+}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/geofence"
+	"asvsoft/internal/pkg/mission"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// loadControllerConfig читает и проверяет конфигурацию контроллера, включая типы обработчиков
+// сообщений и файлы миссии и зон плавания. Возвращает конфигурацию и результат проверки;
+// при ошибках проверки конфигурация nil.
+func loadControllerConfig(cfgPath string) (*config.ControllerConfig, *config.Validation, error) {
+	cfg, err := config.ReadControllerConfig(cfgPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v := cfg.Validate()
+
+	for i, h := range cfg.Handlers {
+		if h == nil || h.Type == "" {
+			continue
+		}
+
+		if _, ok := handlerFactories[h.Type]; !ok {
+			v.Errors = append(v.Errors, config.Problem{
+				Path:    fmt.Sprintf("handlers[%d].type", i),
+				Message: fmt.Sprintf("unknown handler type %q, want one of %v", h.Type, slices.Sorted(maps.Keys(handlerFactories))),
+			})
+		}
+	}
+
+	validateFiles(v, cfg)
+
+	err = v.Err()
+	if err != nil {
+		return nil, v, err
+	}
+
+	return cfg, v, nil
+}
+
+// validateFiles добавляет в v ошибки загрузки файлов миссии и зон плавания, указанных в cfg.
+func validateFiles(v *config.Validation, cfg *config.ControllerConfig) {
+	if m := cfg.Mission; m != nil && m.File != "" {
+		if _, err := mission.Load(m.File); err != nil {
+			v.Errors = append(v.Errors, config.Problem{Path: "mission.file", Message: err.Error()})
+		}
+	}
+
+	if g := cfg.Geofence; g != nil && g.File != "" {
+		if _, err := geofence.Load(g.File); err != nil {
+			v.Errors = append(v.Errors, config.Problem{Path: "geofence.file", Message: err.Error()})
+		}
+	}
+}
+
+// ConfigValidateHandler проверяет конфигурацию контроллера и выводит все найденные ошибки
+// и предупреждения.
+func ConfigValidateHandler(cfgPath *string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		_, v, err := loadControllerConfig(*cfgPath)
+		if v == nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+
+		for _, p := range v.Warnings {
+			fmt.Fprintf(out, "warning: %s\n", p)
+		}
+
+		for _, p := range v.Errors {
+			fmt.Fprintf(out, "error: %s\n", p)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %d errors found", *cfgPath, len(v.Errors))
+		}
+
+		fmt.Fprintf(out, "%s: config is valid\n", *cfgPath)
+
+		return nil
+	}
+}
+
+// loadModuleConfig загружает конфигурацию модуля из флагов, переменных окружения и файла,
+// заданного флагом --config.
+func loadModuleConfig(cmd *cobra.Command, cfg *config.ModuleConfig) error {
+	cfgPath, err := cmd.Flags().GetString(configFlag)
+	if err != nil {
+		// команда без флагов конфигурации модуля
+		return nil
+	}
+
+	err = config.LoadModuleConfig(cfgPath, cmd.Flags(), configKeys(cmd.Flags()), cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get module config: %w", err)
+	}
+
+	return nil
+}
+
+// printModuleConfig выводит конфигурацию модуля в формате yaml.
+func printModuleConfig(w io.Writer, cfg *config.ModuleConfig) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	err := enc.Encode(cfg)
+	if err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
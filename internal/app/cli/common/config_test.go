@@ -0,0 +1,137 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/communication"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// runModuleCmd выполняет команду модуля с аргументами args и флагом --print-config и возвращает
+// выведенную конфигурацию.
+func runModuleCmd(t *testing.T, args ...string) (*config.ModuleConfig, error) {
+	t.Helper()
+
+	var cfg config.ModuleConfig
+
+	cmd := &cobra.Command{
+		Use:           "module",
+		RunE:          ModuleHandler(&cfg, NeoM8tMode),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	cfg.ControllerSerialPort = AddSerialDestinationFlags(cmd)
+	AddModuleConfigFlags(cmd)
+
+	var out bytes.Buffer
+
+	cmd.SetOut(&out)
+	cmd.SetArgs(append(args, "--print-config"))
+
+	err := cmd.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var printed config.ModuleConfig
+
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &printed))
+
+	return &printed, nil
+}
+
+func writeModuleConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	cfgPath := filepath.Join(t.TempDir(), "module.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0o600))
+
+	return cfgPath
+}
+
+func TestLoadModuleConfig(t *testing.T) {
+	const (
+		fileValue = 11
+		envValue  = 12
+		flagValue = 13
+	)
+
+	tests := []struct {
+		name string
+		file bool
+		env  bool
+		flag bool
+		want int
+	}{
+		{name: "значение по умолчанию", want: communication.DefaultRetriesLimit},
+		{name: "файл важнее значения по умолчанию", file: true, want: fileValue},
+		{name: "окружение важнее значения по умолчанию", env: true, want: envValue},
+		{name: "флаг важнее значения по умолчанию", flag: true, want: flagValue},
+		{name: "окружение важнее файла", file: true, env: true, want: envValue},
+		{name: "флаг важнее файла", file: true, flag: true, want: flagValue},
+		{name: "флаг важнее окружения", env: true, flag: true, want: flagValue},
+		{name: "флаг важнее окружения и файла", file: true, env: true, flag: true, want: flagValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []string
+
+			if tt.file {
+				args = append(args, "--config", writeModuleConfig(t, "controller:\n  retries_limit: 11\n"))
+			}
+
+			if tt.env {
+				t.Setenv(config.EnvPrefix+"_CONTROLLER_RETRIES_LIMIT", "12")
+			}
+
+			if tt.flag {
+				args = append(args, "--dst-retries-limit", "13")
+			}
+
+			cfg, err := runModuleCmd(t, args...)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.ControllerSerialPort.RetriesLimit)
+		})
+	}
+
+	t.Run("неизвестный ключ файла", func(t *testing.T) {
+		_, err := runModuleCmd(t, "--config", writeModuleConfig(t, "controller:\n  parity: even\n"))
+		require.ErrorContains(t, err, "parity")
+	})
+
+	t.Run("вывод итоговой конфигурации", func(t *testing.T) {
+		cfg, err := runModuleCmd(t,
+			"--config", writeModuleConfig(t, "controller:\n  port: tcp://localhost:9000\n  sync: false\n"),
+			"--dst-baudrate", "9600",
+			"--metrics-listen", "localhost:9100",
+		)
+		require.NoError(t, err)
+		require.Equal(t, "tcp://localhost:9000", cfg.ControllerSerialPort.Port)
+		require.Equal(t, 9600, cfg.ControllerSerialPort.BaudRate)
+		require.Equal(t, DefaultSerialPortTimeout, cfg.ControllerSerialPort.Timeout)
+		require.False(t, cfg.ControllerSerialPort.Sync)
+		require.NotNil(t, cfg.Metrics)
+		require.Equal(t, "localhost:9100", cfg.Metrics.Listen)
+	})
+}
+
+func TestLoadControllerConfig(t *testing.T) {
+	t.Run("файл миссии не читается", func(t *testing.T) {
+		dir := t.TempDir()
+		cfgPath := filepath.Join(dir, "controller.yaml")
+
+		require.NoError(t, os.WriteFile(cfgPath, []byte("mission:\n  file: "+filepath.Join(dir, "mission.yaml")+"\n"), 0o600))
+
+		_, v, err := loadControllerConfig(cfgPath)
+		require.Error(t, err)
+		require.Len(t, v.Errors, 1)
+		require.Equal(t, "mission.file", v.Errors[0].Path)
+	})
+}
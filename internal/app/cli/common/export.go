@@ -0,0 +1,77 @@
+package common
+
+import (
+	"asvsoft/internal/pkg/export"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type ExportOptions struct {
+	Output string
+	Format string
+	From   string
+	To     string
+}
+
+// ExportHandler экспортирует данные фреймов из записей регистратора и сырых дампов в таблицы
+// по модулям и режимам сообщений и выводит итоги экспорта.
+func ExportHandler(opts *ExportOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		format, err := export.ParseFormat(opts.Format)
+		if err != nil {
+			return err
+		}
+
+		from, err := export.ParseBound(opts.From)
+		if err != nil {
+			return err
+		}
+
+		to, err := export.ParseBound(opts.To)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(opts.Output, 0755) // nolint:gosec
+		if err != nil {
+			return fmt.Errorf("cannot create output directory: %w", err)
+		}
+
+		e := export.NewExporter(opts.Output, format).WithInterval(from, to)
+
+		for _, path := range args {
+			err = exportFile(e, path)
+			if err != nil {
+				_, _ = e.Close()
+				return err
+			}
+		}
+
+		summary, err := e.Close()
+		if err != nil {
+			return fmt.Errorf("cannot write tables: %w", err)
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), summary)
+
+		return nil
+	}
+}
+
+func exportFile(e *export.Exporter, path string) error {
+	src, err := export.OpenSource(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", path, err)
+	}
+
+	defer src.Close()
+
+	err = e.Export(src)
+	if err != nil {
+		return fmt.Errorf("cannot export %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/failsafe"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/router"
+	"asvsoft/internal/pkg/state"
+	"context"
+	"fmt"
+	"time"
+)
+
+// failsafeOverride источник поведения при аварийной реакции (см. guidanceRunner.Override)
+const failsafeOverride = "failsafe"
+
+// failsafeRoute маршрут, связь по которому подтверждается ростом числа пересланных сообщений
+type failsafeRoute struct {
+	trigger   int
+	route     *router.Route
+	forwarded int
+}
+
+// failsafeRunner выполняет политику аварийных реакций: подтверждает нормальное состояние
+// признаков по сообщениям шины и статистике маршрутов и выполняет действующую реакцию.
+type failsafeRunner struct {
+	triggers []*config.FailsafeTriggerConfig
+	policy   *failsafe.Policy
+	routes   []*failsafeRoute
+	// guide регуляторы курса и скорости, nil - не настроены
+	guide  *guidanceRunner
+	store  *state.Store
+	msgBus *bus.Bus
+	log    logger.Logger
+}
+
+func newFailsafeRunner(
+	cfg *config.FailsafeConfig,
+	rtr *router.Router,
+	guide *guidanceRunner,
+	store *state.Store,
+	msgBus *bus.Bus,
+	log logger.Logger,
+) (*failsafeRunner, error) {
+	r := &failsafeRunner{
+		triggers: cfg.Triggers,
+		policy:   failsafe.NewPolicy(),
+		guide:    guide,
+		store:    store,
+		msgBus:   msgBus,
+		log:      log,
+	}
+
+	start := time.Now()
+
+	for _, t := range cfg.Triggers {
+		i := r.policy.Add(t.Name(), t.Timeout, failsafe.Response(t.Response), t.Latch, start)
+
+		if t.Type != config.FailsafeLinkSilence {
+			continue
+		}
+
+		var route *router.Route
+
+		for _, rt := range rtr.Routes() {
+			if rt.Name() == t.Route {
+				route = rt
+			}
+		}
+
+		if route == nil {
+			return nil, fmt.Errorf("route %q is not configured", t.Route)
+		}
+
+		r.routes = append(r.routes, &failsafeRoute{trigger: i, route: route})
+	}
+
+	log.Infof("%d triggers", len(cfg.Triggers))
+
+	return r, nil
+}
+
+// Handle подтверждает нормальное состояние признаков по сообщению.
+func (r *failsafeRunner) Handle(msg proto.Message) error {
+	now := time.Now()
+
+	for i, t := range r.triggers {
+		if r.healthy(t, msg) {
+			r.policy.Feed(i, now)
+		}
+	}
+
+	return nil
+}
+
+// healthy сообщает, подтверждает ли сообщение msg нормальное состояние признака t.
+func (r *failsafeRunner) healthy(t *config.FailsafeTriggerConfig, msg proto.Message) bool {
+	switch t.Type {
+	case config.FailsafeGNSSLost:
+		if _, ok := gnssPosition(msg); !ok {
+			return false
+		}
+
+		data := msg.Payload.(*proto.GNSSData)
+
+		// HAcc в мм
+		return t.MaxHAcc == 0 || float64(data.HAcc) <= t.MaxHAcc*1000
+	case config.FailsafeModuleSilent:
+		return proto.ModuleName(msg.ModuleID) == t.Module
+	case config.FailsafeLowBattery:
+		data, ok := msg.Payload.(*proto.PowerData)
+		if !ok {
+			return false
+		}
+
+		if t.MinVoltage > 0 && float64(data.Voltage) < t.MinVoltage*1000 {
+			return false
+		}
+
+		return t.MinCharge == 0 || data.Charge == proto.PowerChargeUnknown || float64(data.Charge) >= t.MinCharge
+	default:
+		return false
+	}
+}
+
+// run проверяет признаки до отмены контекста.
+func (r *failsafeRunner) run(ctx context.Context) {
+	ticker := time.NewTicker(watchdogCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, rt := range r.routes {
+				forwarded := rt.route.Stats().Forwarded
+				if forwarded > rt.forwarded {
+					rt.forwarded = forwarded
+					r.policy.Feed(rt.trigger, now)
+				}
+			}
+
+			for _, t := range r.policy.Check(now) {
+				r.onTransition(t)
+			}
+		}
+	}
+}
+
+func (r *failsafeRunner) onTransition(t failsafe.Transition) {
+	var active uint8
+
+	if t.Active {
+		active = 1
+
+		r.log.Warnf("%s", t)
+	} else {
+		r.log.Infof("%s", t)
+	}
+
+	publishControl(r.store, r.msgBus, proto.WritingModeB, &proto.ControlData{
+		Event:  proto.EventFailsafe,
+		Active: active,
+		Value:  int32(t.Index),
+	})
+
+	if t.Response != t.Previous {
+		r.respond(t.Response)
+	}
+}
+
+// respond выполняет реакцию response.
+func (r *failsafeRunner) respond(response failsafe.Response) {
+	if r.guide == nil {
+		// без регуляторов допустим только дрейф
+		if response == failsafe.Drift {
+			publishControl(r.store, r.msgBus, proto.WritingModeA,
+				config.SetpointConfig{}.ControlData(proto.ControlSourceFailsafe))
+		}
+
+		return
+	}
+
+	switch response {
+	case failsafe.None:
+		r.guide.Release(failsafeOverride)
+	case failsafe.Drift:
+		r.guide.Override(failsafeOverride, nil)
+	case failsafe.Hold, failsafe.Return:
+		// при потере ГНСС удерживается последнее известное положение
+		target, ok := r.guide.LastPosition()
+
+		if response == failsafe.Return {
+			// точка запуска известна с первого положения после запуска контроллера
+			target, ok = r.guide.Launch()
+		}
+
+		if !ok {
+			r.log.Warnf("%s: no gnss fix yet, drift", response)
+			r.guide.Override(failsafeOverride, nil)
+
+			return
+		}
+
+		r.guide.Override(failsafeOverride, &target)
+	}
+}
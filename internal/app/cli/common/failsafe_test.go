@@ -0,0 +1,83 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/failsafe"
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/mission"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailsafeReturn(t *testing.T) {
+	fix := func(p geo.Point) proto.Message {
+		return proto.Message{
+			ModuleID: proto.GNSSModuleID,
+			MsgID:    proto.WritingModeB,
+			Payload:  &proto.GNSSData{Lat: int32(p.Lat * 1e7), Lon: int32(p.Lon * 1e7)},
+		}
+	}
+
+	newRunner := func(m *missionRunner) *failsafeRunner {
+		store, msgBus := state.New(), bus.New()
+
+		return &failsafeRunner{
+			guide:  newGuidanceRunner(&config.GuidanceConfig{Rate: 10}, m, store, msgBus, logger.DummyLogger{}),
+			store:  store,
+			msgBus: msgBus,
+			log:    logger.DummyLogger{},
+		}
+	}
+
+	launch := geo.Point{Lat: 59.93, Lon: 30.31}
+
+	t.Run("дрейф до первого положения", func(t *testing.T) {
+		r := newRunner(nil)
+
+		r.respond(failsafe.Return)
+
+		o, ok := r.guide.override()
+		require.True(t, ok)
+		require.Nil(t, o.target)
+	})
+
+	t.Run("точка запуска - первое положение", func(t *testing.T) {
+		r := newRunner(nil)
+
+		require.NoError(t, r.guide.Handle(fix(launch)))
+		require.NoError(t, r.guide.Handle(fix(geo.Offset(launch, 100, 0))))
+
+		r.respond(failsafe.Return)
+
+		o, ok := r.guide.override()
+		require.True(t, ok)
+		require.NotNil(t, o.target)
+		require.InDelta(t, 0, geo.Distance(launch, *o.target), 0.1)
+	})
+
+	t.Run("начало миссии важнее первого положения", func(t *testing.T) {
+		origin := geo.Offset(launch, 0, 200)
+
+		tracker := mission.NewTracker(&mission.Mission{
+			Waypoints: []mission.Waypoint{{Point: geo.Offset(launch, 500, 0), AcceptanceRadius: 10}},
+		})
+		tracker.Update(origin, time.Now())
+
+		r := newRunner(&missionRunner{tracker: tracker})
+
+		require.NoError(t, r.guide.Handle(fix(launch)))
+
+		r.respond(failsafe.Return)
+
+		o, ok := r.guide.override()
+		require.True(t, ok)
+		require.NotNil(t, o.target)
+		require.InDelta(t, 0, geo.Distance(origin, *o.target), 0.1)
+	})
+}
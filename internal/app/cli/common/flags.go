@@ -4,10 +4,13 @@ package common
 import (
 	"asvsoft/internal/app/config"
 	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/linkstats"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const (
@@ -16,11 +19,64 @@ const (
 	DefaultSerialPortTimeout  = 500 * time.Millisecond
 )
 
+const (
+	configFlag      = "config"
+	printConfigFlag = "print-config"
+	metricsFlag     = "metrics-listen"
+	// configKeyAnnotation аннотация флага с ключом конфигурации модуля (см. config.LoadModuleConfig)
+	configKeyAnnotation = "config-key"
+)
+
+// AddModuleConfigFlags добавляет команде модуля флаги yaml-файла конфигурации, вывода
+// итоговой конфигурации и адреса метрик. Значения флагов, связанных с ключами конфигурации
+// (см. BindConfigKey), могут задаваться в файле и переменных окружения.
+func AddModuleConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(
+		configFlag, "c",
+		"", "path to module config, flags and environment override it",
+	)
+
+	cmd.Flags().Bool(
+		printConfigFlag,
+		false, "print effective config and exit",
+	)
+
+	cmd.Flags().String(
+		metricsFlag,
+		"", "serve prometheus metrics on host:port, empty disables metrics",
+	)
+
+	BindConfigKey(cmd, metricsFlag, "metrics.listen")
+}
+
+// BindConfigKey связывает флаг name команды с ключом key конфигурации модуля, например
+// controller.port.
+func BindConfigKey(cmd *cobra.Command, name, key string) {
+	err := cmd.Flags().SetAnnotation(name, configKeyAnnotation, []string{key})
+	if err != nil {
+		panic(fmt.Sprintf("cannot bind flag to config key %s: %v", key, err))
+	}
+}
+
+// configKeys возвращает ключи конфигурации модуля, связанные с флагами.
+func configKeys(flags *pflag.FlagSet) map[string]string {
+	keys := make(map[string]string)
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if key := f.Annotations[configKeyAnnotation]; len(key) > 0 {
+			keys[f.Name] = key[0]
+		}
+	})
+
+	return keys
+}
+
 // AddSerialDestinationFlags добавляем команде флаги последовательного конфигурации
 // последовательного интерфейса назначения и возвращает его конфиг. По умолчанию используется порт
 // /dev/ttySOFT0 со скоростью 4800 bit/sec
 func AddSerialDestinationFlags(cmd *cobra.Command) *config.SerialPortConfig {
 	config := addSerialSourceFlagsWithPrefix(cmd, "dst")
+	bindSerialPortFlags(cmd, "dst", "controller")
 
 	cmd.Flags().DurationVar(
 		&config.Sleep, "dst-sleep",
@@ -34,12 +90,27 @@ func AddSerialDestinationFlags(cmd *cobra.Command) *config.SerialPortConfig {
 
 	cmd.Flags().IntVar(
 		&config.ChunkSize, "dst-chunk-size",
-		communication.DefaultChunkSize, "wait ok message after sending own message",
+		communication.DefaultChunkSize, "max payload size of chunked message",
 	)
 
 	cmd.Flags().IntVar(
-		&config.ChunkSize, "dst-retries-limit",
-		communication.DefaultRetriesLimit, "wait ok message after sending own message",
+		&config.RetriesLimit, "dst-retries-limit",
+		communication.DefaultRetriesLimit, "max attempts to send message",
+	)
+
+	cmd.Flags().IntVar(
+		&config.Budget, "dst-budget",
+		0, "link budget in bytes/sec: 0 - derived from serial baud rate, negative - unlimited",
+	)
+
+	cmd.Flags().DurationVar(
+		&config.StatsInterval, "dst-stats-interval",
+		linkstats.DefaultLogInterval, "period of link stats logging, negative disables logging",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.StatsReport, "dst-stats-report",
+		false, "send link stats to destination every stats interval",
 	)
 
 	cmd.Flags().BoolVar(
@@ -47,6 +118,19 @@ func AddSerialDestinationFlags(cmd *cobra.Command) *config.SerialPortConfig {
 		false, "disble transmitting to destination port",
 	)
 
+	for name, key := range map[string]string{
+		"dst-sleep":             "sleep",
+		"dst-sync":              "sync",
+		"dst-chunk-size":        "chunk_size",
+		"dst-retries-limit":     "retries_limit",
+		"dst-budget":            "budget",
+		"dst-stats-interval":    "stats_interval",
+		"dst-stats-report":      "stats_report",
+		"transmitting-disabled": "transmitting_disabled",
+	} {
+		BindConfigKey(cmd, name, "controller."+key)
+	}
+
 	return config
 }
 
@@ -54,7 +138,16 @@ func AddSerialDestinationFlags(cmd *cobra.Command) *config.SerialPortConfig {
 // последовательного интерфейса источника и возвращает его конфиг. По умолчанию используется порт
 // /dev/ttyAMA0 со скоростью 4800 bit/sec и таймаутом 5 секунд .
 func AddSerialSourceFlags(cmd *cobra.Command) *config.SerialPortConfig {
-	return addSerialSourceFlagsWithPrefix(cmd, "")
+	config := addSerialSourceFlagsWithPrefix(cmd, "")
+	bindSerialPortFlags(cmd, "", "sensor")
+
+	return config
+}
+
+// AddSerialPortFlags добавляет команде флаги порта, адрес, скорость и таймаут, с префиксом
+// prefix и возвращает его конфиг.
+func AddSerialPortFlags(cmd *cobra.Command, prefix string) *config.SerialPortConfig {
+	return addSerialSourceFlagsWithPrefix(cmd, prefix)
 }
 
 func addSerialSourceFlagsWithPrefix(cmd *cobra.Command, prefix string) *config.SerialPortConfig {
@@ -62,7 +155,7 @@ func addSerialSourceFlagsWithPrefix(cmd *cobra.Command, prefix string) *config.S
 
 	cmd.Flags().StringVar(
 		&config.Port, strings.Trim(prefix+"-"+"port", "-"),
-		DefaultSerialPort, "target port to sending measures",
+		DefaultSerialPort, "serial port path or endpoint url (serial://, tcp://, udp://, unix://)",
 	)
 
 	cmd.Flags().IntVar(
@@ -77,3 +170,11 @@ func addSerialSourceFlagsWithPrefix(cmd *cobra.Command, prefix string) *config.S
 
 	return &config
 }
+
+// bindSerialPortFlags связывает флаги порта с префиксом prefix с ключами раздела section
+// конфигурации модуля.
+func bindSerialPortFlags(cmd *cobra.Command, prefix, section string) {
+	for _, name := range []string{"port", "baudrate", "timeout"} {
+		BindConfigKey(cmd, strings.Trim(prefix+"-"+name, "-"), section+"."+name)
+	}
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/geofence"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"sync"
+	"time"
+)
+
+// geofenceOverride источник поведения при нарушении зоны (см. guidanceRunner.Override)
+const geofenceOverride = "geofence"
+
+// geofenceRunner контролирует зоны плавания по сообщениям ГНСС: публикует события нарушения
+// зон и выполняет реакцию конфигурации, пока нарушена хотя бы одна зона.
+type geofenceRunner struct {
+	cfg     *config.GeofenceConfig
+	monitor *geofence.Monitor
+	// guide регуляторы курса и скорости, nil - не настроены
+	guide  *guidanceRunner
+	store  *state.Store
+	msgBus *bus.Bus
+	log    logger.Logger
+
+	mu sync.Mutex
+}
+
+func newGeofenceRunner(
+	cfg *config.GeofenceConfig,
+	guide *guidanceRunner,
+	store *state.Store,
+	msgBus *bus.Bus,
+	log logger.Logger,
+) (*geofenceRunner, error) {
+	fences, err := geofence.Load(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("%d fences, margin %v m, hysteresis %v m, action %s", len(fences), cfg.Margin, cfg.Hysteresis, cfg.Action)
+
+	return &geofenceRunner{
+		cfg:     cfg,
+		monitor: geofence.NewMonitor(fences, cfg.Margin, cfg.Hysteresis),
+		guide:   guide,
+		store:   store,
+		msgBus:  msgBus,
+		log:     log,
+	}, nil
+}
+
+// Handle учитывает положение из сообщения ГНСС.
+func (r *geofenceRunner) Handle(msg proto.Message) error {
+	pos, ok := gnssPosition(msg)
+	if !ok {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	breached := r.monitor.Breached()
+
+	for _, e := range r.monitor.Update(pos, time.Now()) {
+		var active uint8
+
+		if e.Kind == geofence.Breached {
+			active = 1
+
+			r.log.Warnf("%s", e)
+		} else {
+			r.log.Infof("%s", e)
+		}
+
+		publishControl(r.store, r.msgBus, proto.WritingModeB, &proto.ControlData{
+			Event:  proto.EventGeofenceBreach,
+			Active: active,
+			Value:  int32(e.Index),
+		})
+	}
+
+	switch {
+	case !breached && r.monitor.Breached():
+		r.act(pos)
+	case breached && !r.monitor.Breached():
+		if r.guide != nil {
+			r.guide.Release(geofenceOverride)
+		}
+	}
+
+	return nil
+}
+
+// act выполняет реакцию на нарушение в положении pos.
+func (r *geofenceRunner) act(pos geo.Point) {
+	switch r.cfg.Action {
+	case config.GeofenceStop:
+		if r.guide != nil {
+			r.guide.Override(geofenceOverride, nil)
+			return
+		}
+
+		publishControl(r.store, r.msgBus, proto.WritingModeA,
+			config.SetpointConfig{}.ControlData(proto.ControlSourceGeofence))
+	case config.GeofenceReturn:
+		launch, ok := r.guide.Launch()
+		if !ok {
+			r.log.Warnf("launch point is unknown, hold position")
+
+			launch = pos
+		}
+
+		r.guide.Override(geofenceOverride, &launch)
+	case config.GeofenceHold:
+		r.guide.Override(geofenceOverride, &pos)
+	}
+}
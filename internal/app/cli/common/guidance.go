@@ -0,0 +1,347 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/guidance"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// guidanceOverride поведение, заменяющее движение по миссии
+type guidanceOverride struct {
+	// source источник поведения: нарушение зоны, аварийная реакция
+	source string
+	// target точка, к которой нужно вернуться и в которой нужно удерживаться; nil - остановка
+	target *geo.Point
+	// setpoint аварийная уставка, выдаваемая вместо остановки и движения к точке; nil - не задана
+	setpoint *config.SetpointConfig
+}
+
+func (o guidanceOverride) String() string {
+	if o.setpoint != nil {
+		return fmt.Sprintf("hold setpoint thrust %g%%, rudder %g deg", o.setpoint.Thrust, o.setpoint.Rudder)
+	}
+
+	if o.target == nil {
+		return "stop"
+	}
+
+	return fmt.Sprintf("go to %.7f, %.7f", o.target.Lat, o.target.Lon)
+}
+
+// guidanceRunner выдает уставки регуляторов курса и скорости для движения к целевой точке
+// миссии или точке поведения, заданного поверх миссии. Курс и скорость измеряются по
+// сообщениям ГНСС, курс - также по сообщениям IMU, навигационному решению или оценке
+// ориентации.
+type guidanceRunner struct {
+	cfg     *config.GuidanceConfig
+	ctrl    *guidance.Controller
+	mission *missionRunner
+	store   *state.Store
+	msgBus  *bus.Bus
+	log     logger.Logger
+
+	mu        sync.Mutex
+	heading   float64
+	headingAt time.Time
+	speed     float64
+	speedAt   time.Time
+	position  geo.Point
+	// positionAt время последнего положения, нулевое - положения не было
+	positionAt time.Time
+	// launch первое положение после запуска контроллера, nil - положения не было
+	launch *geo.Point
+	// overrides поведения поверх миссии, действует последнее
+	overrides []guidanceOverride
+
+	// stopped последней выдана уставка остановки или уставки не выдавались
+	stopped bool
+}
+
+func newGuidanceRunner(
+	cfg *config.GuidanceConfig,
+	mission *missionRunner,
+	store *state.Store,
+	msgBus *bus.Bus,
+	log logger.Logger,
+) *guidanceRunner {
+	return &guidanceRunner{
+		cfg:     cfg,
+		ctrl:    guidance.NewController(cfg.Heading.PID(), cfg.Speed.PID()),
+		mission: mission,
+		store:   store,
+		msgBus:  msgBus,
+		log:     log,
+		stopped: true,
+	}
+}
+
+// filter возвращает фильтр сообщений с измерениями курса и скорости.
+func (g *guidanceRunner) filter() bus.Filter {
+	f := bus.Filter{
+		Modules:  []proto.ModuleID{proto.GNSSModuleID},
+		Messages: []proto.MessageID{proto.WritingModeA, proto.WritingModeB, proto.WritingModeC},
+	}
+
+	// фильтр не связывает модули с режимами, лишние режимы отбрасываются в Handle
+	switch g.cfg.HeadingSource {
+	case config.HeadingSourceIMU:
+		f.Modules = append(f.Modules, proto.IMUModuleID)
+	case config.HeadingSourceNavigation, config.HeadingSourceAHRS:
+		f.Modules = append(f.Modules, proto.NavigationModuleID)
+	}
+
+	return f
+}
+
+// Handle учитывает измерения курса и скорости из сообщения.
+func (g *guidanceRunner) Handle(msg proto.Message) error {
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch data := msg.Payload.(type) {
+	case *proto.GNSSData:
+		if pos, ok := gnssPosition(msg); ok {
+			g.position, g.positionAt = pos, now
+
+			if g.launch == nil {
+				g.launch = &pos
+				g.log.Infof("launch point %.7f, %.7f", pos.Lat, pos.Lon)
+			}
+		}
+
+		// в режиме WritingModeB скорости нет
+		if msg.MsgID == proto.WritingModeB {
+			return nil
+		}
+
+		// скорость навигационного решения учитывается вместо скорости ГНСС
+		if g.cfg.HeadingSource == config.HeadingSourceNavigation {
+			return nil
+		}
+
+		course, speed := guidance.GNSSCourse(data)
+		g.speed, g.speedAt = speed, now
+
+		if g.cfg.HeadingSource == config.HeadingSourceGNSS && speed >= g.cfg.MinCourseSpeed {
+			g.heading, g.headingAt = course, now
+		}
+	case *proto.IMUData:
+		if msg.MsgID == proto.WritingModeB || msg.MsgID == proto.WritingModeC {
+			g.heading, g.headingAt = guidance.MagneticHeading(data), now
+		}
+	case *proto.NavigationData:
+		// в режиме WritingModeB только ориентация
+		if msg.MsgID == proto.WritingModeB {
+			if g.cfg.HeadingSource == config.HeadingSourceAHRS && data.Status&proto.NavigationHeadingValid != 0 {
+				g.heading, g.headingAt = float64(data.Heading)/100, now
+			}
+
+			return nil
+		}
+
+		if g.cfg.HeadingSource != config.HeadingSourceNavigation || msg.MsgID != proto.WritingModeA ||
+			data.Status&proto.NavigationPositionValid == 0 {
+			return nil
+		}
+
+		// VelN, VelE в см/с
+		g.speed, g.speedAt = math.Hypot(float64(data.VelN), float64(data.VelE))/100, now
+
+		if data.Status&proto.NavigationHeadingValid != 0 {
+			// Heading в 0.01 градуса
+			g.heading, g.headingAt = float64(data.Heading)/100, now
+		}
+	}
+
+	return nil
+}
+
+// measurement возвращает измерения, не старше таймаута на момент now.
+func (g *guidanceRunner) measurement(now time.Time) guidance.Measurement {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return guidance.Measurement{
+		Heading:      g.heading,
+		HeadingValid: !g.headingAt.IsZero() && now.Sub(g.headingAt) <= g.cfg.Timeout,
+		Speed:        g.speed,
+		SpeedValid:   !g.speedAt.IsZero() && now.Sub(g.speedAt) <= g.cfg.Timeout,
+	}
+}
+
+// run выдает уставки с частотой конфигурации до отмены контекста.
+func (g *guidanceRunner) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / g.cfg.Rate))
+	defer ticker.Stop()
+
+	last := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.step(now, now.Sub(last).Seconds())
+			last = now
+		}
+	}
+}
+
+func (g *guidanceRunner) step(now time.Time, dt float64) {
+	if o, ok := g.override(); ok {
+		g.stepOverride(o, now, dt)
+		return
+	}
+
+	s, ok := g.mission.State()
+
+	// до первого положения уставки не выдаются; при удержании в точке и после завершения
+	// миссии однократно выдается уставка остановки
+	if !ok || s.Complete || s.Loitering {
+		g.stop()
+		return
+	}
+
+	if g.stopped {
+		g.log.Infof("guide to waypoint %d (%s)", s.Index, s.Target.Name)
+	}
+
+	g.update(guidance.Command{Course: s.Bearing, Speed: s.Speed}, now, dt)
+}
+
+// stepOverride выдает уставку поведения o: аварийную уставку, остановку или движение к точке
+// с удержанием в ней.
+func (g *guidanceRunner) stepOverride(o guidanceOverride, now time.Time, dt float64) {
+	if o.setpoint != nil {
+		g.stopped = false
+		publishControl(g.store, g.msgBus, proto.WritingModeA, o.setpoint.ControlData(proto.ControlSourceFailsafe))
+
+		return
+	}
+
+	if o.target == nil {
+		g.stop()
+		return
+	}
+
+	pos, ok := g.Position(now)
+	if !ok || geo.Distance(pos, *o.target) <= g.cfg.HoldRadius {
+		g.stop()
+		return
+	}
+
+	g.update(guidance.Command{Course: geo.Bearing(pos, *o.target), Speed: g.cfg.ReturnSpeed}, now, dt)
+}
+
+func (g *guidanceRunner) update(cmd guidance.Command, now time.Time, dt float64) {
+	g.stopped = false
+	g.publish(g.ctrl.Update(cmd, g.measurement(now), dt))
+}
+
+// stop однократно выдает уставку остановки.
+func (g *guidanceRunner) stop() {
+	if g.stopped {
+		return
+	}
+
+	g.stopped = true
+	g.publish(g.ctrl.Stop())
+}
+
+// Override заменяет движение по миссии поведением источника source: остановкой (target nil)
+// или движением к target с удержанием в ней. Поведение того же источника заменяется.
+func (g *guidanceRunner) Override(source string, target *geo.Point) {
+	g.setOverride(guidanceOverride{source: source, target: target})
+}
+
+// OverrideSetpoint заменяет движение по миссии аварийной уставкой sp источника source.
+// Поведение того же источника заменяется.
+func (g *guidanceRunner) OverrideSetpoint(source string, sp config.SetpointConfig) {
+	g.setOverride(guidanceOverride{source: source, setpoint: &sp})
+}
+
+func (g *guidanceRunner) setOverride(o guidanceOverride) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.overrides = slices.DeleteFunc(g.overrides, func(other guidanceOverride) bool { return other.source == o.source })
+	g.overrides = append(g.overrides, o)
+
+	g.log.Warnf("%s: %s", o.source, o)
+}
+
+// Release отменяет поведение источника source.
+func (g *guidanceRunner) Release(source string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := len(g.overrides)
+	g.overrides = slices.DeleteFunc(g.overrides, func(o guidanceOverride) bool { return o.source == source })
+
+	if len(g.overrides) < n {
+		g.log.Infof("%s: released", source)
+	}
+}
+
+func (g *guidanceRunner) override() (guidanceOverride, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.overrides) == 0 {
+		return guidanceOverride{}, false
+	}
+
+	return g.overrides[len(g.overrides)-1], true
+}
+
+// Position возвращает последнее положение, не старше таймаута на момент now.
+func (g *guidanceRunner) Position(now time.Time) (geo.Point, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.position, !g.positionAt.IsZero() && now.Sub(g.positionAt) <= g.cfg.Timeout
+}
+
+// LastPosition возвращает последнее известное положение независимо от его возраста.
+func (g *guidanceRunner) LastPosition() (geo.Point, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.position, !g.positionAt.IsZero()
+}
+
+// Launch возвращает точку запуска: начало миссии, если миссия запущена, иначе первое положение
+// после запуска контроллера; false - положений еще не было.
+func (g *guidanceRunner) Launch() (geo.Point, bool) {
+	if g.mission != nil {
+		if origin, ok := g.mission.Launch(); ok {
+			return origin, true
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.launch == nil {
+		return geo.Point{}, false
+	}
+
+	return *g.launch, true
+}
+
+func (g *guidanceRunner) publish(sp guidance.Setpoint) {
+	setpoint := config.SetpointConfig{Thrust: sp.Thrust, Rudder: sp.Rudder}
+	publishControl(g.store, g.msgBus, proto.WritingModeA, setpoint.ControlData(proto.ControlSourceGuidance))
+}
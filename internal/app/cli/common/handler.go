@@ -2,15 +2,22 @@ package common
 
 import (
 	"asvsoft/internal/app/config"
-	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/api"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/linkstats"
 	"asvsoft/internal/pkg/logger"
 	"asvsoft/internal/pkg/proto"
-	serialport "asvsoft/internal/pkg/serial-port"
+	"asvsoft/internal/pkg/recording"
+	"asvsoft/internal/pkg/state"
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -23,13 +30,35 @@ type ModuleOptions struct {
 // ModuleHandler инициализирует sender и syncer, запускает sender.
 func ModuleHandler(cfg *config.ModuleConfig, mode RunMode, opts ...ModuleOptions) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		ctx := config.WrapContext(cmd.Context(), cfg)
+		err := loadModuleConfig(cmd, cfg)
+		if err != nil {
+			return err
+		}
+
+		if printConfig, _ := cmd.Flags().GetBool(printConfigFlag); printConfig {
+			return printModuleConfig(cmd.OutOrStdout(), cfg)
+		}
+
+		mode, opts := resolveRunMode(cfg, mode, opts)
+
+		ctx, cancel := context.WithCancel(config.WrapContext(cmd.Context(), cfg))
+		defer cancel()
 
 		sndr, sncr, err := Init(ctx, mode, opts...)
 		if err != nil {
 			return err
 		}
 
+		if cfg.Metrics != nil && cfg.Metrics.Listen != "" {
+			name := proto.ModuleName(runModeModuleIDs[mode])
+
+			err = serveMetrics(ctx, cfg.Metrics.Listen, logger.Wrap(logrus.StandardLogger(), "[metrics]"),
+				collectSender(name, sndr))
+			if err != nil {
+				return err
+			}
+		}
+
 		err = sncr.SyncSystemTime()
 		if err != nil {
 			return fmt.Errorf("cannot sync: %v", err)
@@ -44,63 +73,146 @@ func ModuleHandler(cfg *config.ModuleConfig, mode RunMode, opts ...ModuleOptions
 	}
 }
 
-type module struct {
-	rcvr *communication.Receiver
-	sncr *communication.Syncer
-}
-
 // ControllerHandler ...
 func ControllerHandler(moduleID proto.ModuleID, ctrlCfgPath *string) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		log := logger.Wrap(logrus.StandardLogger(), "[main]")
 
-		ctrlCfg, err := config.NewControllerConfig(*ctrlCfgPath)
+		ctrlCfg, validation, err := loadControllerConfig(*ctrlCfgPath)
 		if err != nil {
 			return fmt.Errorf("failed to get controller config: %w", err)
 		}
 
-		moduleCfg := ctrlCfg.Modules
-		modules := make(map[string]module, len(moduleCfg))
+		for _, p := range validation.Warnings {
+			log.Warnf("config: %s", p)
+		}
 
-		for name, connCfg := range moduleCfg {
-			if !connCfg.Enabled {
-				continue
-			}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-			srcPort, err := serialport.New(connCfg.Listener.Short())
+		// каталог, от которого отсчитываются относительные пути записи и обработчиков
+		baseDir := "."
+
+		if ctrlCfg.Sessions != nil {
+			sess, err := startSession(ctx, cmd, *ctrlCfgPath, ctrlCfg)
 			if err != nil {
-				return fmt.Errorf("cannot open serial port %s: %w", connCfg.Listener, err)
+				return err
 			}
 
-			srcPort.SetLogger(logger.Wrap(logrus.StandardLogger(), fmt.Sprintf("[%s]", name)))
+			defer func() {
+				err := sess.Close()
+				if err != nil {
+					log.Errorf("cannot close session: %v", err)
+				}
+			}()
 
-			log.Debugf("successfull create serail port: %s", connCfg.Listener)
+			baseDir = sess.Dir
+		}
 
-			sncr := communication.NewSyncer(moduleID).WithReadWriter(srcPort)
+		msgBus := bus.New()
 
-			rcvr := communication.NewReceiver(srcPort, moduleID).
-				WithSync(connCfg.Listener.Sync).
-				WithChunkSize(connCfg.Listener.ChunkSize).
-				WithRetriesLimit(connCfg.Listener.RetriesLimit)
+		err = subscribeHandlers(msgBus, ctrlCfg.Handlers, baseDir)
+		if err != nil {
+			return err
+		}
+
+		var recorder *recording.Writer
+
+		if ctrlCfg.Recording != nil {
+			recorder, err = newRecorder(ctrlCfg.Recording, baseDir)
+			if err != nil {
+				return err
+			}
 
 			defer func() {
-				err = rcvr.Close()
+				err := recorder.Close()
 				if err != nil {
-					log.Errorf("cannot close receiver: %v", err)
+					log.Errorf("cannot close recording: %v", err)
 				}
 			}()
 
-			modules[name] = module{rcvr: rcvr, sncr: sncr}
+			h, err := recordControl(recorder, logger.Wrap(logrus.StandardLogger(), "[recording]"))
+			if err != nil {
+				return err
+			}
+
+			msgBus.Subscribe("recording", bus.Filter{
+				Modules: []proto.ModuleID{proto.ControlModuleID, proto.NavigationModuleID},
+			}, h)
+
+			log.Infof("recording frames to %s", filepath.Join(baseDir, ctrlCfg.Recording.Dir))
 		}
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		store := newStateStore(ctrlCfg.State)
+		go store.Run(ctx)
+		go logStaleSignals(ctx, store, logger.Wrap(logrus.StandardLogger(), "[state]"))
+
+		modules := newModules(ctx, moduleID, msgBus, store, recorder, log)
+		defer modules.stopAll()
+
+		err = modules.startAll(ctrlCfg.Modules)
+		if err != nil {
+			return err
+		}
+
+		if ctrlCfg.API != nil {
+			l, err := net.Listen("tcp", ctrlCfg.API.Listen)
+			if err != nil {
+				return fmt.Errorf("cannot listen api: %w", err)
+			}
+
+			apiServer := api.NewServer(modules).
+				WithTelemetry(telemetry{store: store}).
+				WithLogger(logger.Wrap(logrus.StandardLogger(), "[api]"))
 
-		closeCount := 0
-		closeChannel := make(chan struct{}, len(modules))
+			go func() {
+				err := apiServer.Serve(ctx, l)
+				if err != nil {
+					log.Errorf("api server failed: %v", err)
+				}
+			}()
+		}
 
-		for moduleName, module := range modules {
-			go receiving(ctx, moduleName, module, closeChannel)
+		rtr, dstPorts, err := newRouter(ctx, ctrlCfg.Routes)
+		if err != nil {
+			return fmt.Errorf("cannot create routes: %w", err)
+		}
+
+		defer func() {
+			for _, port := range dstPorts {
+				err := port.Close()
+				if err != nil {
+					log.Errorf("cannot close route destination: %v", err)
+				}
+			}
+		}()
+
+		modules.guide, err = subscribeNavigation(ctx, ctrlCfg, rtr, store, msgBus)
+		if err != nil {
+			return err
+		}
+
+		go modules.watch(ctx)
+
+		msgBus.Subscribe("routes", bus.Filter{}, bus.HandlerFunc(func(msg proto.Message) error {
+			rtr.Dispatch(msg)
+			return nil
+		}))
+
+		go rtr.Run(ctx)
+		go msgBus.Run(ctx)
+
+		if ctrlCfg.Metrics != nil {
+			err = serveMetrics(ctx, ctrlCfg.Metrics.Listen, logger.Wrap(logrus.StandardLogger(), "[metrics]"),
+				modules, collectSignals(store), collectQueues(msgBus, rtr))
+			if err != nil {
+				return err
+			}
+		}
+
+		reloads, err := watchReloads(ctx, *ctrlCfgPath, log)
+		if err != nil {
+			return fmt.Errorf("cannot watch config: %w", err)
 		}
 
 		quitChannel := make(chan os.Signal, 2)
@@ -111,45 +223,108 @@ func ControllerHandler(moduleID proto.ModuleID, ctrlCfgPath *string) func(cmd *c
 			case signal := <-quitChannel:
 				log.Infof("%s signal called, cancel operations", signal.String())
 				cancel()
-			case <-closeChannel:
-				closeCount++
-				if closeCount == len(modules) {
-					return nil
+				modules.stopAll()
+
+				return nil
+			case cfg := <-reloads:
+				log.Infof("config %s changed, apply modules", *ctrlCfgPath)
+
+				if !ctrlCfg.EqualExceptModules(cfg) {
+					log.Warnf("only modules are reloaded, restart to apply other changes")
 				}
+
+				modules.apply(cfg.Modules)
 			}
 		}
 	}
 }
 
+// watchReloads отслеживает изменения файла конфигурации контроллера cfgPath до отмены контекста
+// и передает в канал новые конфигурации, прошедшие проверку. Канал хранит только последнюю
+// необработанную конфигурацию, отклоненные конфигурации записываются в лог.
+func watchReloads(ctx context.Context, cfgPath string, log logger.Logger) (<-chan *config.ControllerConfig, error) {
+	reloads := make(chan *config.ControllerConfig, 1)
+
+	err := config.WatchControllerConfig(ctx, cfgPath, func(cfg *config.ControllerConfig, err error) {
+		if err != nil {
+			log.Errorf("new config rejected, keep current: %v", err)
+			return
+		}
+
+		// после отмены контекста конфигурации некому применять
+		if ctx.Err() != nil {
+			return
+		}
+
+		// необработанная конфигурация заменяется более новой
+		select {
+		case <-reloads:
+		default:
+		}
+
+		reloads <- cfg
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reloads, nil
+}
+
 func receiving(
 	ctx context.Context,
 	moduleName string,
-	module module,
-	closeChannel chan struct{},
+	module *module,
+	msgBus *bus.Bus,
+	store *state.Store,
 ) {
+	defer close(module.done)
+
 	log := logger.Wrap(logrus.StandardLogger(), fmt.Sprintf("[%s]", moduleName))
 
 	log.Infof("starting receive message...")
 
 	module.rcvr.WithLogger(log)
 
+	go linkstats.Log(ctx, log, module.cfg.Listener.StatsInterval, module.rcvr)
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Infof("stop receiving, context done")
-			closeChannel <- struct{}{}
 
 			return
 		default:
 			msg, err := module.rcvr.Receive()
 			if err != nil {
-				log.Errorf("receive failed: %v", err)
+				// приемник закрыт при завершении работы
+				if ctx.Err() == nil {
+					log.Errorf("receive failed: %v", err)
+				}
+
 				continue
 			}
 
+			module.feed(time.Now())
+
+			log := log
+
+			if len(module.moduleIDs) > 0 {
+				if !slices.Contains(module.moduleIDs, msg.ModuleID) {
+					log.Warnf("drop message of unexpected module %s: %v", proto.ModuleName(msg.ModuleID), msg)
+					continue
+				}
+
+				log = logger.Wrap(log, fmt.Sprintf("[%s]", proto.ModuleName(msg.ModuleID)))
+			}
+
+			module.see(msg.ModuleID)
+
 			log.Infof("received message: %v", msg)
 
 			if msg.MsgID == proto.SyncRequest {
+				module.synced(time.Now())
+
 				resp, err := module.sncr.ProcessSyncRequest(msg)
 				if err != nil {
 					log.Errorf("failed to process sync request: %v", msg)
@@ -160,32 +335,48 @@ func receiving(
 				continue
 			}
 
-			// TODO: использовать общий подход к обработке сообщения каждого модуля
-			if msg.ModuleID == proto.CameraModuleID && msg.MsgID == proto.WritingModeB {
-				err = handleCameraRegistratorMsg(log, msg)
-				if err != nil {
-					log.Errorf("failed to handle camera message: %v", err)
-					continue
-				}
+			if msg.MsgID == proto.StatsReport {
+				log.Infof("module link stats: %s", msg.Payload)
+				continue
 			}
+
+			store.Update(msg)
+			msgBus.Publish(msg)
 		}
 	}
 }
 
-func handleCameraRegistratorMsg(log logger.Logger, msg proto.Message) error {
-	payload, ok := msg.Payload.(*proto.CameraData)
-	if !ok {
-		return fmt.Errorf("unexecpted message payload type")
+// newStateStore создает хранилище состояния по конфигурации cfg; nil - значения по умолчанию.
+func newStateStore(cfg *config.StateConfig) *state.Store {
+	store := state.New()
+
+	if cfg != nil {
+		store.WithHistorySize(cfg.HistorySize).WithStaleAfter(cfg.StaleAfter)
 	}
 
-	fileName := fmt.Sprintf("camera_%d.jpeg", msg.SystemTime)
+	return store
+}
 
-	err := os.WriteFile(fileName, payload.RawImagePart, 0666) // nolint:gosec
-	if err != nil {
-		return fmt.Errorf("failed to write image to file: %w", err)
-	}
+// logStaleSignals выводит в лог переходы сигналов в устаревшие и возобновление сообщений.
+func logStaleSignals(ctx context.Context, store *state.Store, log logger.Logger) {
+	sub := store.Subscribe(state.DefaultSubscriptionSize)
+	defer store.Unsubscribe(sub)
 
-	log.Infof("successfully saved recieved camera image to %s", fileName)
+	stale := make(map[state.Key]bool)
 
-	return nil
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-sub.C:
+			switch {
+			case e.Stale && !stale[e.Key]:
+				log.Warnf("%s is stale: no messages for %v", e.Key, e.Age.Round(time.Millisecond))
+			case !e.Stale && stale[e.Key]:
+				log.Infof("%s resumed", e.Key)
+			}
+
+			stale[e.Key] = e.Stale
+		}
+	}
 }
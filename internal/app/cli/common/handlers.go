@@ -0,0 +1,389 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// handlerFactory создает обработчик сообщений по параметрам из конфигурации. Относительные пути
+// в параметрах отсчитываются от baseDir. Возвращаемый фильтр используется, если модули
+// и сообщения обработчика не заданы в конфигурации.
+type handlerFactory func(params map[string]any, baseDir string, log logger.Logger) (bus.Handler, bus.Filter, error)
+
+var handlerFactories = map[string]handlerFactory{
+	"log":        newLogHandler,
+	"save-image": newSaveImageHandler,
+	"alarm":      newAlarmHandler,
+}
+
+// defaultHandlers обработчики, используемые при отсутствии секции handlers в конфигурации
+var defaultHandlers = []*config.HandlerConfig{
+	{Type: "save-image"},
+}
+
+// subscribeHandlers подписывает на шину обработчики, заданные в конфигурации.
+func subscribeHandlers(b *bus.Bus, cfgs []*config.HandlerConfig, baseDir string) error {
+	if len(cfgs) == 0 {
+		cfgs = defaultHandlers
+	}
+
+	for i, cfg := range cfgs {
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", cfg.Type, i)
+		}
+
+		factory, ok := handlerFactories[cfg.Type]
+		if !ok {
+			return fmt.Errorf("unknown type %q of handler %s", cfg.Type, name)
+		}
+
+		log := logger.Wrap(logrus.StandardLogger(), fmt.Sprintf("[handler %s]", name))
+
+		h, filter, err := factory(cfg.Params, baseDir, log)
+		if err != nil {
+			return fmt.Errorf("cannot create handler %s: %w", name, err)
+		}
+
+		modules, err := cfg.ModuleIDs()
+		if err != nil {
+			return fmt.Errorf("bad modules of handler %s: %w", name, err)
+		}
+
+		if len(modules) > 0 {
+			filter.Modules = modules
+		}
+
+		if len(cfg.Messages) > 0 {
+			filter.Messages = make([]proto.MessageID, 0, len(cfg.Messages))
+			for _, id := range cfg.Messages {
+				filter.Messages = append(filter.Messages, proto.MessageID(id))
+			}
+		}
+
+		b.Subscribe(name, filter, h).WithQueueSize(cfg.QueueSize).WithLogger(log)
+	}
+
+	return nil
+}
+
+// decodeParams декодирует параметры обработчика в структуру out, неизвестные параметры
+// считаются ошибкой.
+func decodeParams(params map[string]any, out any) error {
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		Result:           out,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dec.Decode(params)
+}
+
+type logHandlerParams struct {
+	Level string `mapstructure:"level"`
+}
+
+// newLogHandler создает обработчик, записывающий сообщения в лог с уровнем level (по умолчанию info).
+func newLogHandler(params map[string]any, _ string, log logger.Logger) (bus.Handler, bus.Filter, error) {
+	p := logHandlerParams{Level: "info"}
+
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, bus.Filter{}, err
+	}
+
+	level, err := logrus.ParseLevel(p.Level)
+	if err != nil {
+		return nil, bus.Filter{}, err
+	}
+
+	h := bus.HandlerFunc(func(msg proto.Message) error {
+		switch level {
+		case logrus.TraceLevel:
+			log.Tracef("message: %v", msg)
+		case logrus.DebugLevel:
+			log.Debugf("message: %v", msg)
+		case logrus.WarnLevel:
+			log.Warnf("message: %v", msg)
+		case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+			log.Errorf("message: %v", msg)
+		default:
+			log.Infof("message: %v", msg)
+		}
+
+		return nil
+	})
+
+	return h, bus.Filter{}, nil
+}
+
+type saveImageHandlerParams struct {
+	Dir string `mapstructure:"dir"`
+}
+
+// newSaveImageHandler создает обработчик, сохраняющий изображения камеры в директорию dir
+// (по умолчанию текущая или каталог сессии).
+func newSaveImageHandler(params map[string]any, baseDir string, log logger.Logger) (bus.Handler, bus.Filter, error) {
+	p := saveImageHandlerParams{Dir: "."}
+
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, bus.Filter{}, err
+	}
+
+	if !filepath.IsAbs(p.Dir) {
+		p.Dir = filepath.Join(baseDir, p.Dir)
+	}
+
+	err = os.MkdirAll(p.Dir, 0755) // nolint:gosec
+	if err != nil {
+		return nil, bus.Filter{}, fmt.Errorf("cannot create images directory: %w", err)
+	}
+
+	filter := bus.Filter{
+		Modules:  []proto.ModuleID{proto.CameraModuleID},
+		Messages: []proto.MessageID{proto.WritingModeB},
+	}
+
+	h := bus.HandlerFunc(func(msg proto.Message) error {
+		payload, ok := msg.Payload.(*proto.CameraData)
+		if !ok {
+			return fmt.Errorf("unexecpted message payload type")
+		}
+
+		fileName := filepath.Join(p.Dir, fmt.Sprintf("camera_%d.jpeg", msg.SystemTime))
+
+		err := os.WriteFile(fileName, payload.RawImagePart, 0666) // nolint:gosec
+		if err != nil {
+			return fmt.Errorf("failed to write image to file: %w", err)
+		}
+
+		log.Infof("successfully saved recieved camera image to %s", fileName)
+
+		return nil
+	})
+
+	return h, filter, nil
+}
+
+type alarmHandlerParams struct {
+	// Field имя числового поля данных сообщения, например Distance
+	Field string   `mapstructure:"field"`
+	Min   *float64 `mapstructure:"min"`
+	Max   *float64 `mapstructure:"max"`
+}
+
+// alarmHandler сообщает о выходе значения поля сообщения за допустимые пределы и о возврате
+// в них. Состояние отслеживается отдельно для каждого модуля.
+type alarmHandler struct {
+	params alarmHandlerParams
+	log    logger.Logger
+	active map[proto.ModuleID]bool
+}
+
+func newAlarmHandler(params map[string]any, _ string, log logger.Logger) (bus.Handler, bus.Filter, error) {
+	var p alarmHandlerParams
+
+	err := decodeParams(params, &p)
+	if err != nil {
+		return nil, bus.Filter{}, err
+	}
+
+	if p.Field == "" {
+		return nil, bus.Filter{}, fmt.Errorf("field is not configured")
+	}
+
+	if !isNumericField(p.Field) {
+		return nil, bus.Filter{}, fmt.Errorf("unknown numeric field %q", p.Field)
+	}
+
+	if p.Min == nil && p.Max == nil {
+		return nil, bus.Filter{}, fmt.Errorf("neither min nor max is configured")
+	}
+
+	h := &alarmHandler{
+		params: p,
+		log:    log,
+		active: make(map[proto.ModuleID]bool),
+	}
+
+	return h, bus.Filter{}, nil
+}
+
+func (h *alarmHandler) Handle(msg proto.Message) error {
+	value, ok := numericField(msg.Payload, h.params.Field)
+	if !ok {
+		return nil
+	}
+
+	outOfRange := (h.params.Min != nil && value < *h.params.Min) || (h.params.Max != nil && value > *h.params.Max)
+
+	switch {
+	case outOfRange && !h.active[msg.ModuleID]:
+		h.log.Warnf("alarm: %s %s = %s is out of range %s", proto.ModuleName(msg.ModuleID), h.params.Field, formatFloat(value), h.limits())
+	case !outOfRange && h.active[msg.ModuleID]:
+		h.log.Infof("alarm cleared: %s %s = %s", proto.ModuleName(msg.ModuleID), h.params.Field, formatFloat(value))
+	}
+
+	h.active[msg.ModuleID] = outOfRange
+
+	return nil
+}
+
+func (h *alarmHandler) limits() string {
+	lower, upper := "-inf", "+inf"
+
+	if h.params.Min != nil {
+		lower = formatFloat(*h.params.Min)
+	}
+
+	if h.params.Max != nil {
+		upper = formatFloat(*h.params.Max)
+	}
+
+	return fmt.Sprintf("[%s, %s]", lower, upper)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Числовые поля данных сообщений модулей по именам полей структур proto (см. numericField)
+var (
+	depthMeterFields = map[string]func(d *proto.DepthMeterData) float64{
+		"ID":         func(d *proto.DepthMeterData) float64 { return float64(d.ID) },
+		"SystemTime": func(d *proto.DepthMeterData) float64 { return float64(d.SystemTime) },
+		"Distance":   func(d *proto.DepthMeterData) float64 { return float64(d.Distance) },
+		"Status":     func(d *proto.DepthMeterData) float64 { return float64(d.Status) },
+		"Strength":   func(d *proto.DepthMeterData) float64 { return float64(d.Strength) },
+		"Precision":  func(d *proto.DepthMeterData) float64 { return float64(d.Precision) },
+	}
+	lidarFields = map[string]func(d *proto.LidarData) float64{
+		"Speed":      func(d *proto.LidarData) float64 { return float64(d.Speed) },
+		"StartAngle": func(d *proto.LidarData) float64 { return float64(d.StartAngle) },
+		"EndAngle":   func(d *proto.LidarData) float64 { return float64(d.EndAngle) },
+		"Timestamp":  func(d *proto.LidarData) float64 { return float64(d.Timestamp) },
+	}
+	imuFields = map[string]func(d *proto.IMUData) float64{
+		"AccFactor": func(d *proto.IMUData) float64 { return float64(d.AccFactor) },
+		"GyrFactor": func(d *proto.IMUData) float64 { return float64(d.GyrFactor) },
+		"Gx":        func(d *proto.IMUData) float64 { return float64(d.Gx) },
+		"Gy":        func(d *proto.IMUData) float64 { return float64(d.Gy) },
+		"Gz":        func(d *proto.IMUData) float64 { return float64(d.Gz) },
+		"Ax":        func(d *proto.IMUData) float64 { return float64(d.Ax) },
+		"Ay":        func(d *proto.IMUData) float64 { return float64(d.Ay) },
+		"Az":        func(d *proto.IMUData) float64 { return float64(d.Az) },
+		"Mx":        func(d *proto.IMUData) float64 { return float64(d.Mx) },
+		"My":        func(d *proto.IMUData) float64 { return float64(d.My) },
+		"Mz":        func(d *proto.IMUData) float64 { return float64(d.Mz) },
+	}
+	gnssFields = map[string]func(d *proto.GNSSData) float64{
+		"ITowNAVPOSLLH": func(d *proto.GNSSData) float64 { return float64(d.ITowNAVPOSLLH) },
+		"Lon":           func(d *proto.GNSSData) float64 { return float64(d.Lon) },
+		"Lat":           func(d *proto.GNSSData) float64 { return float64(d.Lat) },
+		"Height":        func(d *proto.GNSSData) float64 { return float64(d.Height) },
+		"HMSL":          func(d *proto.GNSSData) float64 { return float64(d.HMSL) },
+		"HAcc":          func(d *proto.GNSSData) float64 { return float64(d.HAcc) },
+		"VAcc":          func(d *proto.GNSSData) float64 { return float64(d.VAcc) },
+		"ITowNAVVELNED": func(d *proto.GNSSData) float64 { return float64(d.ITowNAVVELNED) },
+		"VelN":          func(d *proto.GNSSData) float64 { return float64(d.VelN) },
+		"VelE":          func(d *proto.GNSSData) float64 { return float64(d.VelE) },
+		"VelD":          func(d *proto.GNSSData) float64 { return float64(d.VelD) },
+		"Speed":         func(d *proto.GNSSData) float64 { return float64(d.Speed) },
+		"GSppeed":       func(d *proto.GNSSData) float64 { return float64(d.GSppeed) },
+		"Heading":       func(d *proto.GNSSData) float64 { return float64(d.Heading) },
+		"SAcc":          func(d *proto.GNSSData) float64 { return float64(d.SAcc) },
+		"CAcc":          func(d *proto.GNSSData) float64 { return float64(d.CAcc) },
+	}
+	navigationFields = map[string]func(d *proto.NavigationData) float64{
+		"Lat":         func(d *proto.NavigationData) float64 { return float64(d.Lat) },
+		"Lon":         func(d *proto.NavigationData) float64 { return float64(d.Lon) },
+		"VelN":        func(d *proto.NavigationData) float64 { return float64(d.VelN) },
+		"VelE":        func(d *proto.NavigationData) float64 { return float64(d.VelE) },
+		"Heading":     func(d *proto.NavigationData) float64 { return float64(d.Heading) },
+		"Roll":        func(d *proto.NavigationData) float64 { return float64(d.Roll) },
+		"Pitch":       func(d *proto.NavigationData) float64 { return float64(d.Pitch) },
+		"GyroBias":    func(d *proto.NavigationData) float64 { return float64(d.GyroBias) },
+		"AccBiasX":    func(d *proto.NavigationData) float64 { return float64(d.AccBiasX) },
+		"AccBiasY":    func(d *proto.NavigationData) float64 { return float64(d.AccBiasY) },
+		"PositionStd": func(d *proto.NavigationData) float64 { return float64(d.PositionStd) },
+		"HeadingStd":  func(d *proto.NavigationData) float64 { return float64(d.HeadingStd) },
+		"Status":      func(d *proto.NavigationData) float64 { return float64(d.Status) },
+	}
+	cameraFields = map[string]func(d *proto.CameraData) float64{
+		"Yaw":   func(d *proto.CameraData) float64 { return float64(d.Yaw) },
+		"Pitch": func(d *proto.CameraData) float64 { return float64(d.Pitch) },
+		"Roll":  func(d *proto.CameraData) float64 { return float64(d.Roll) },
+	}
+	checkFields = map[string]func(d *proto.CheckData) float64{
+		"Value": func(d *proto.CheckData) float64 { return float64(d.Value) },
+	}
+	controlFields = map[string]func(d *proto.ControlData) float64{
+		"Thrust":  func(d *proto.ControlData) float64 { return float64(d.Thrust) },
+		"Rudder":  func(d *proto.ControlData) float64 { return float64(d.Rudder) },
+		"Source":  func(d *proto.ControlData) float64 { return float64(d.Source) },
+		"Event":   func(d *proto.ControlData) float64 { return float64(d.Event) },
+		"Subject": func(d *proto.ControlData) float64 { return float64(d.Subject) },
+		"Active":  func(d *proto.ControlData) float64 { return float64(d.Active) },
+		"Value":   func(d *proto.ControlData) float64 { return float64(d.Value) },
+	}
+	powerFields = map[string]func(d *proto.PowerData) float64{
+		"Voltage": func(d *proto.PowerData) float64 { return float64(d.Voltage) },
+		"Current": func(d *proto.PowerData) float64 { return float64(d.Current) },
+		"Charge":  func(d *proto.PowerData) float64 { return float64(d.Charge) },
+	}
+)
+
+// isNumericField сообщает, есть ли числовое поле name в данных сообщений какого-либо модуля.
+func isNumericField(name string) bool {
+	return depthMeterFields[name] != nil || lidarFields[name] != nil || imuFields[name] != nil ||
+		gnssFields[name] != nil || navigationFields[name] != nil || cameraFields[name] != nil ||
+		checkFields[name] != nil || controlFields[name] != nil || powerFields[name] != nil
+}
+
+// numericField возвращает значение числового поля name данных сообщения.
+func numericField(payload proto.Packer, name string) (float64, bool) {
+	switch d := payload.(type) {
+	case *proto.DepthMeterData:
+		return field(depthMeterFields, d, name)
+	case *proto.LidarData:
+		return field(lidarFields, d, name)
+	case *proto.IMUData:
+		return field(imuFields, d, name)
+	case *proto.GNSSData:
+		return field(gnssFields, d, name)
+	case *proto.NavigationData:
+		return field(navigationFields, d, name)
+	case *proto.CameraData:
+		return field(cameraFields, d, name)
+	case *proto.CheckData:
+		return field(checkFields, d, name)
+	case *proto.ControlData:
+		return field(controlFields, d, name)
+	case *proto.PowerData:
+		return field(powerFields, d, name)
+	default:
+		return 0, false
+	}
+}
+
+func field[T any](fields map[string]func(d *T) float64, d *T, name string) (float64, bool) {
+	get, ok := fields[name]
+	if !ok || d == nil {
+		return 0, false
+	}
+
+	return get(d), true
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlarmHandler(t *testing.T) {
+	t.Run("значение числового поля", func(t *testing.T) {
+		value, ok := numericField(&proto.DepthMeterData{Distance: 120}, "Distance")
+		require.True(t, ok)
+		require.Equal(t, 120.0, value)
+
+		_, ok = numericField(&proto.PowerData{Voltage: 12000}, "Distance")
+		require.False(t, ok)
+	})
+
+	t.Run("неизвестное поле", func(t *testing.T) {
+		_, _, err := newAlarmHandler(map[string]any{"field": "Depth", "min": 150}, "", logger.DummyLogger{})
+		require.ErrorContains(t, err, "Depth")
+	})
+
+	t.Run("известное поле", func(t *testing.T) {
+		h, _, err := newAlarmHandler(map[string]any{"field": "Distance", "min": 150}, "", logger.DummyLogger{})
+		require.NoError(t, err)
+		require.NoError(t, h.Handle(proto.Message{ModuleID: proto.DepthMeterModuleID, Payload: &proto.DepthMeterData{Distance: 100}}))
+		require.True(t, h.(*alarmHandler).active[proto.DepthMeterModuleID])
+	})
+}
@@ -11,10 +11,12 @@ import (
 	"asvsoft/internal/pkg/communication"
 	"asvsoft/internal/pkg/logger"
 	"asvsoft/internal/pkg/proto"
-	serialport "asvsoft/internal/pkg/serial-port"
+	"asvsoft/internal/pkg/transport"
 	"context"
 	"fmt"
+	"io"
 	"slices"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
@@ -40,125 +42,176 @@ var (
 	}
 )
 
+var runModeModuleIDs = map[RunMode]proto.ModuleID{
+	DepthMeterMode: proto.DepthMeterModuleID,
+	LidarMode:      proto.LidarModuleID,
+	NeoM8tMode:     proto.GNSSModuleID,
+	ImuMode:        proto.IMUModuleID,
+	NavMode:        proto.NavigationModuleID,
+	CheckMode:      proto.CheckModuleID,
+	CameraMode:     proto.CameraModuleID,
+	MockCameraMode: proto.CameraModuleID,
+}
+
 // Init общая функция инициализации модуля камеры, лидара, ИНС и ГНСС, измерителя глубины ,
 // модуля навигации и модуля проверки. Требуемые для работы модуля порты-источники и
-// порты-назначения обернуты в объекте sender'a.
+// порты-назначения обернуты в объекте sender'a. Измеритель открывается под наблюдением
+// супервизора, который переоткрывает его после серии ошибок.
 func Init(ctx context.Context, mode RunMode, opts ...ModuleOptions) (*communication.Sender, *communication.Syncer, error) {
 	cfg := config.FromContext(ctx)
 
-	var (
-		srcPort *serialport.Wrapper
-		err     error
-	)
-
-	if slices.Contains(requiredSrcSerialPortRunMode, mode) {
-		srcPort, err = serialport.New(cfg.SensorSerialPort.Short())
-		if err != nil {
-			return nil, nil, err
-		}
+	addr, ok := runModeModuleIDs[mode]
+	if !ok {
+		panic(fmt.Sprintf("unknown run mode: %d", mode))
+	}
 
-		srcPort.SetLogger(log.StandardLogger())
+	m := communication.NewSupervisor(func() (communication.MeasureCloser, error) {
+		return openMeasurer(cfg, mode)
+	}).WithLogger(logger.Wrap(logrus.StandardLogger(), "[supervisor]"))
 
-		err = srcPort.ResetInputBuffer()
-		if err != nil {
-			log.Errorf("cannot reset input buffer: %v", err)
-		}
+	// TODO: найти другой способ конфигурации send mode
+	sendMode := proto.WritingModeA
+	if len(opts) > 0 && opts[0].SendMode != 0 {
+		sendMode = opts[0].SendMode
 	}
 
-	var (
-		m    communication.MeasureCloser
-		addr proto.ModuleID
-	)
+	sndr := communication.NewSender(m, addr, sendMode)
+	sncr := communication.NewSyncer(addr)
 
-	switch mode {
-	case DepthMeterMode:
-		m = depthmeter.New(srcPort)
-		addr = proto.DepthMeterModuleID
-	case LidarMode:
-		m = lidar.New(srcPort)
-		addr = proto.LidarModuleID
-	case NeoM8tMode:
-		m, err = neom8t.New(cfg.NeoM8t, srcPort)
+	if !cfg.ControllerSerialPort.TransmittingDisabled {
+		dstPort, err := OpenPort(cfg.ControllerSerialPort, log.StandardLogger())
 		if err != nil {
 			return nil, nil, err
 		}
 
-		addr = proto.GNSSModuleID
-	case ImuMode:
-		m, err = sensehat.New(cfg.SenseHAT)
-		if err != nil {
-			return nil, nil, err
+		if r, ok := dstPort.(transport.Resetter); ok {
+			err = r.ResetOutputBuffer()
+			if err != nil {
+				log.Errorf("cannot reset output buffer: %v", err)
+			}
+
+			err = r.ResetInputBuffer()
+			if err != nil {
+				log.Errorf("cannot reset input buffer: %v", err)
+			}
 		}
 
-		addr = proto.IMUModuleID
-	case NavMode:
-		panic("implement me")
-	case CheckMode:
-		addr = proto.CheckModuleID
-		m = check.New()
-	case CameraMode:
-		cam, err := camera.New()
+		// TODO: научиться конфигурировать несколько destination'ов (контроллер, регистратор)
+		sndr.WithReadWriteCloser(dstPort).
+			WithSleep(cfg.ControllerSerialPort.Sleep).
+			WithSync(cfg.ControllerSerialPort.Sync).
+			WithChunkSize(cfg.ControllerSerialPort.ChunkSize).
+			WithRetriesLimit(cfg.ControllerSerialPort.RetriesLimit).
+			WithStatsInterval(cfg.ControllerSerialPort.StatsInterval).
+			WithStatsReport(cfg.ControllerSerialPort.StatsReport)
+
+		budget, baudRate, err := cfg.ControllerSerialPort.LinkBudget()
 		if err != nil {
 			return nil, nil, err
 		}
 
-		m = cam.WithLogger(
-			logger.Wrap(logrus.StandardLogger(), "[camera]"),
-		)
+		sndr.WithBudget(budget, baudRate)
 
-		addr = proto.CameraModuleID
-	case MockCameraMode:
-		// hardcode
-		m, err = camera.NewMockCamera([][3]int16{
-			{1090, -12711, -16544},
-			{1087, -12768, -16545},
-			{1085, -12669, -16545},
-		})
+		err = checkRate(sndr, cfg, mode)
 		if err != nil {
-			return nil, nil, err
+			log.Errorf("cannot check measures rate: %v", err)
 		}
 
-		addr = proto.CameraModuleID
+		sncr.WithReadWriter(dstPort)
+	}
+
+	return sndr, sncr, nil
+}
+
+// resolveRunMode уточняет режим работы модуля по его конфигурации: Sense HAT в режиме ahrs
+// передает ориентацию как модуль навигации (режим WritingModeB).
+func resolveRunMode(cfg *config.ModuleConfig, mode RunMode, opts []ModuleOptions) (RunMode, []ModuleOptions) {
+	if mode == ImuMode && cfg.SenseHAT != nil && cfg.SenseHAT.Mode == sensehat.AHRSMode {
+		return NavMode, []ModuleOptions{{SendMode: proto.WritingModeB}}
+	}
+
+	return mode, opts
+}
+
+// checkRate предупреждает о несоответствии бюджету линии для модулей с известным периодом измерений.
+func checkRate(sndr *communication.Sender, cfg *config.ModuleConfig, mode RunMode) error {
+	switch mode {
+	case ImuMode:
+		return sndr.CheckRate(&proto.IMUData{}, cfg.SenseHAT.Period)
+	case NavMode:
+		return sndr.CheckRate(&proto.NavigationData{}, cfg.SenseHAT.Period)
+	case NeoM8tMode:
+		return sndr.CheckRate(&proto.GNSSData{}, time.Duration(cfg.NeoM8t.Rate)*time.Second)
 	default:
-		panic(fmt.Sprintf("unknown run mode: %q", addr))
+		return nil
 	}
+}
 
-	// TODO: найти другой способ конфигурации send mode
-	sendMode := proto.WritingModeA
-	if len(opts) > 0 && opts[0].SendMode != 0 {
-		sendMode = opts[0].SendMode
+// OpenPort открывает канал связи, описанный в cfg: последовательный порт, TCP, UDP или Unix-сокет.
+func OpenPort(cfg *config.SerialPortConfig, log logger.Logger) (io.ReadWriteCloser, error) {
+	ep, err := cfg.Endpoint()
+	if err != nil {
+		return nil, err
 	}
 
-	sndr := communication.NewSender(m, addr, sendMode)
-	sncr := communication.NewSyncer(addr)
+	return transport.Open(ep, log)
+}
 
-	if !cfg.ControllerSerialPort.TransmittingDisabled {
-		dstPort, err := serialport.New(cfg.ControllerSerialPort.Short())
+// openMeasurer открывает порт-источник и инициализирует датчик модуля: для Sense HAT заново
+// выполняется конфигурация по I2C, для NEO-M8T - конфигурация частоты UBX сообщений.
+func openMeasurer(cfg *config.ModuleConfig, mode RunMode) (m communication.MeasureCloser, err error) {
+	var srcPort io.ReadWriteCloser
+
+	if slices.Contains(requiredSrcSerialPortRunMode, mode) {
+		srcPort, err = OpenPort(cfg.SensorSerialPort, log.StandardLogger())
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
-		err = dstPort.ResetOutputBuffer()
-		if err != nil {
-			log.Errorf("cannot reset output buffer: %v", err)
+		defer func() {
+			if err != nil {
+				_ = srcPort.Close()
+			}
+		}()
+
+		if r, ok := srcPort.(transport.Resetter); ok {
+			err = r.ResetInputBuffer()
+			if err != nil {
+				log.Errorf("cannot reset input buffer: %v", err)
+			}
 		}
+	}
 
-		err = dstPort.ResetInputBuffer()
+	switch mode {
+	case DepthMeterMode:
+		return depthmeter.New(srcPort), nil
+	case LidarMode:
+		return lidar.New(srcPort), nil
+	case NeoM8tMode:
+		return neom8t.New(cfg.NeoM8t, srcPort)
+	case ImuMode:
+		return sensehat.New(cfg.SenseHAT)
+	case NavMode:
+		return sensehat.NewAHRS(cfg.SenseHAT)
+	case CheckMode:
+		return check.New(), nil
+	case CameraMode:
+		cam, err := camera.New()
 		if err != nil {
-			log.Errorf("cannot reset input buffer: %v", err)
+			return nil, err
 		}
 
-		dstPort.SetLogger(log.StandardLogger())
-
-		// TODO: научиться конфигурировать несколько destination'ов (контроллер, регистратор)
-		sndr.WithReadWriteCloser(dstPort).
-			WithSleep(cfg.ControllerSerialPort.Sleep).
-			WithSync(cfg.ControllerSerialPort.Sync).
-			WithChunkSize(cfg.ControllerSerialPort.ChunkSize).
-			WithRetriesLimit(cfg.ControllerSerialPort.RetriesLimit)
-
-		sncr.WithReadWriter(dstPort)
+		return cam.WithLogger(
+			logger.Wrap(logrus.StandardLogger(), "[camera]"),
+		), nil
+	case MockCameraMode:
+		// hardcode
+		return camera.NewMockCamera([][3]int16{
+			{1090, -12711, -16544},
+			{1087, -12768, -16545},
+			{1085, -12669, -16545},
+		})
+	default:
+		panic(fmt.Sprintf("unknown run mode: %d", mode))
 	}
-
-	return sndr, sncr, nil
 }
@@ -0,0 +1,150 @@
+package common
+
+import (
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/linkstats"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/metrics"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/router"
+	"asvsoft/internal/pkg/state"
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"time"
+)
+
+// modules реализует metrics.Collector
+var _ metrics.Collector = (*modules)(nil)
+
+// Collect добавляет состояние и статистику каналов связи модулей.
+func (ms *modules) Collect(s *metrics.Set) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, name := range slices.Sorted(maps.Keys(ms.cfgs)) {
+		channel := metrics.L("channel", name)
+
+		m, ok := ms.running[name]
+		if !ok {
+			s.Gauge("module_running", "Module receiving is running.", 0, channel)
+			continue
+		}
+
+		s.Gauge("module_running", "Module receiving is running.", 1, channel)
+		s.Counter("sync_requests_total", "Time sync requests of the module.", float64(m.syncRequests.Load()), channel)
+		s.Link(m.rcvr.Stats(), channel)
+	}
+}
+
+// collectSignals добавляет количество, частоту и задержку сообщений каждого модуля и режима.
+// Смещение синхронизации - разница между временем получения сообщения и его системным временем,
+// включающая задержку передачи.
+func collectSignals(store *state.Store) metrics.Collector {
+	return metrics.CollectorFunc(func(s *metrics.Set) {
+		start := time.Unix(int64(proto.GetStartStamp()), 0)
+
+		for _, e := range store.Snapshot() {
+			labels := []metrics.Label{
+				metrics.L("module", proto.ModuleName(e.Module)),
+				metrics.L("msg", fmt.Sprintf("%#x", uint8(e.Msg))),
+			}
+
+			stale := 0.0
+			if e.Stale {
+				stale = 1
+			}
+
+			sent := start.Add(time.Duration(e.Latest.SystemTime) * time.Millisecond)
+
+			s.Counter("messages_total", "Messages received.", float64(e.Count), labels...)
+			s.Gauge("message_rate", "Messages per second over the recent history.", e.Rate, labels...)
+			s.Gauge("message_age_seconds", "Time since the latest message.", e.Age.Seconds(), labels...)
+			s.Gauge("message_stale", "No messages for longer than the stale timeout.", stale, labels...)
+			s.Gauge("sync_offset_seconds", "Receive time minus system time of the latest message.",
+				e.Latest.RecvTime.Sub(sent).Seconds(), labels...)
+		}
+	})
+}
+
+// collectQueues добавляет заполненность очередей подписчиков шины и маршрутов, а также
+// статистику каналов связи получателей маршрутов.
+func collectQueues(msgBus *bus.Bus, rtr *router.Router) metrics.Collector {
+	return metrics.CollectorFunc(func(s *metrics.Set) {
+		for _, sub := range msgBus.Subscriptions() {
+			labels := []metrics.Label{metrics.L("queue", "bus"), metrics.L("name", sub.Name())}
+
+			s.Gauge("queue_depth", "Messages waiting in the queue.", float64(sub.QueueLen()), labels...)
+			s.Counter("queue_dropped_total", "Messages dropped on a full queue.", float64(sub.Stats().Dropped), labels...)
+		}
+
+		for _, route := range rtr.Routes() {
+			labels := []metrics.Label{metrics.L("queue", "route"), metrics.L("name", route.Name())}
+			stats := route.Stats()
+
+			s.Gauge("queue_depth", "Messages waiting in the queue.", float64(route.QueueLen()), labels...)
+			s.Counter("queue_dropped_total", "Messages dropped on a full queue.", float64(stats.Dropped), labels...)
+			s.Counter("route_forwarded_total", "Messages forwarded by the route.", float64(stats.Forwarded),
+				metrics.L("route", route.Name()))
+			s.Counter("route_failed_total", "Messages the route failed to forward.", float64(stats.Failed),
+				metrics.L("route", route.Name()))
+
+			if p, ok := route.Forwarder().(linkstats.Provider); ok {
+				s.Link(p.Stats(), metrics.L("channel", "route/"+route.Name()))
+			}
+		}
+	})
+}
+
+// collectSender добавляет статистику канала связи модуля name с контроллером, включая задержку
+// измерений, состояние супервизора измерителя и начало отсчета системного времени.
+func collectSender(name string, sndr *communication.Sender) metrics.Collector {
+	return metrics.CollectorFunc(func(s *metrics.Set) {
+		s.Link(sndr.Stats(), metrics.L("channel", name))
+		s.Gauge("sync_start_stamp_seconds", "Start of the system time in unix seconds.", float64(proto.GetStartStamp()))
+
+		if sup, ok := sndr.Measurer().(*communication.Supervisor); ok {
+			collectSupervisor(s, name, sup.Status())
+		}
+	})
+}
+
+// collectSupervisor добавляет состояние супервизора измерителя модуля name: текущему состоянию
+// соответствует значение 1, остальным - 0.
+func collectSupervisor(s *metrics.Set, name string, status communication.SupervisorStatus) {
+	module := metrics.L("module", name)
+
+	for _, state := range communication.SupervisorStates {
+		value := 0.0
+		if state == status.State {
+			value = 1
+		}
+
+		s.Gauge("measurer_state", "Measurer supervisor state.", value, module, metrics.L("state", state.String()))
+	}
+
+	s.Gauge("measurer_failures", "Consecutive measurer failures.", float64(status.Failures), module)
+	s.Counter("measurer_reopens_total", "Measurer reopens after a series of failures.", float64(status.Reopens), module)
+}
+
+// serveMetrics запускает сервер метрик на адресе listen до отмены контекста.
+func serveMetrics(ctx context.Context, listen string, log logger.Logger, collectors ...metrics.Collector) error {
+	l, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("cannot listen metrics: %w", err)
+	}
+
+	srv := metrics.NewServer(collectors...).WithLogger(log)
+
+	go func() {
+		err := srv.Serve(ctx, l)
+		if err != nil {
+			log.Errorf("metrics server failed: %v", err)
+		}
+	}()
+
+	return nil
+}
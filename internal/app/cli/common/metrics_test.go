@@ -0,0 +1,30 @@
+package common
+
+import (
+	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/metrics"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectSupervisor(t *testing.T) {
+	set := metrics.NewSet()
+	collectSupervisor(set, "depthmeter", communication.SupervisorStatus{
+		State:    communication.StateBackoff,
+		Failures: 3,
+		Reopens:  1,
+	})
+
+	var buf bytes.Buffer
+
+	_, err := set.WriteTo(&buf)
+	require.NoError(t, err)
+
+	out := buf.String()
+	require.Contains(t, out, `asvsoft_measurer_state{module="depthmeter",state="backoff"} 1`)
+	require.Contains(t, out, `asvsoft_measurer_state{module="depthmeter",state="running"} 0`)
+	require.Contains(t, out, `asvsoft_measurer_failures{module="depthmeter"} 3`)
+	require.Contains(t, out, `asvsoft_measurer_reopens_total{module="depthmeter"} 1`)
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/mission"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// missionRunner выполняет миссию по сообщениям ГНСС: отслеживает прохождение точек маршрута,
+// публикует события миссии и сохраняет прогресс после каждого события.
+type missionRunner struct {
+	tracker      *mission.Tracker
+	progressPath string
+	store        *state.Store
+	msgBus       *bus.Bus
+	log          logger.Logger
+
+	mu    sync.Mutex
+	state mission.State
+	valid bool
+}
+
+func newMissionRunner(
+	cfg *config.MissionConfig,
+	store *state.Store,
+	msgBus *bus.Bus,
+	log logger.Logger,
+) (*missionRunner, error) {
+	m, err := mission.Load(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := mission.NewTracker(m)
+
+	p, err := mission.LoadProgress(cfg.ProgressFile)
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		err = tracker.Resume(p)
+		if errors.Is(err, mission.ErrOtherMission) {
+			log.Warnf("mission %s changed, start from the first waypoint: %v", m.Name, err)
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot resume mission %s: %w", m.Name, err)
+		}
+	}
+
+	log.Infof("mission %s: %d waypoints, target waypoint %d", m.Name, len(m.Waypoints), tracker.Progress().Index)
+
+	return &missionRunner{
+		tracker:      tracker,
+		progressPath: cfg.ProgressFile,
+		store:        store,
+		msgBus:       msgBus,
+		log:          log,
+	}, nil
+}
+
+// Handle учитывает положение из сообщения ГНСС.
+func (r *missionRunner) Handle(msg proto.Message) error {
+	pos, ok := gnssPosition(msg)
+	if !ok {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, events := r.tracker.Update(pos, time.Now())
+	r.state, r.valid = s, true
+
+	for _, e := range events {
+		r.log.Infof("mission %s: %s", r.tracker.Mission().Name, e)
+		r.publish(e)
+
+		if e.Kind == mission.Started {
+			r.log.Infof("armed, launch point %.7f, %.7f", e.Position.Lat, e.Position.Lon)
+		}
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	err := mission.SaveProgress(r.progressPath, r.tracker.Progress())
+	if err != nil {
+		return fmt.Errorf("cannot save mission progress: %w", err)
+	}
+
+	return nil
+}
+
+// publish передает событие миссии как событие контроллера.
+func (r *missionRunner) publish(e mission.Event) {
+	var event uint8
+
+	switch e.Kind {
+	case mission.Started, mission.Resumed:
+		event = proto.EventMissionStarted
+	case mission.Reached:
+		event = proto.EventWaypointReached
+	case mission.Completed:
+		event = proto.EventMissionCompleted
+	default:
+		return
+	}
+
+	publishControl(r.store, r.msgBus, proto.WritingModeB, &proto.ControlData{
+		Event:  event,
+		Active: 1,
+		Value:  int32(e.Index),
+	})
+}
+
+// State возвращает положение относительно целевой точки по последнему сообщению ГНСС; false -
+// сообщений ГНСС с координатами еще не было.
+func (r *missionRunner) State() (mission.State, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state, r.valid
+}
+
+// Launch возвращает точку запуска: положение при запуске миссии, сохраняемое в прогрессе.
+func (r *missionRunner) Launch() (geo.Point, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	origin := r.tracker.Progress().Origin
+	if origin == nil {
+		return geo.Point{}, false
+	}
+
+	return *origin, true
+}
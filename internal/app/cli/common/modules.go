@@ -0,0 +1,326 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
+	"asvsoft/internal/pkg/state"
+	"asvsoft/internal/pkg/watchdog"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type module struct {
+	rcvr *communication.Receiver
+	sncr *communication.Syncer
+	cfg  *config.ModuleConnectionConfig
+	// moduleIDs модули, разделяющие канал связи; пустой список - канал одного модуля
+	moduleIDs []proto.ModuleID
+	cancel    context.CancelFunc
+	close     func() error
+	// done закрывается после завершения приема сообщений
+	done chan struct{}
+	// wd контроль молчания модуля, nil - не контролируется
+	wd *watchdog.Watchdog
+
+	// syncRequests количество запросов синхронизации, lastSync - время последнего в нс
+	syncRequests atomic.Uint64
+	lastSync     atomic.Int64
+
+	mu sync.Mutex
+	// seen модули, сообщения которых получены по каналу связи
+	seen map[proto.ModuleID]struct{}
+}
+
+// synced учитывает запрос синхронизации модуля.
+func (m *module) synced(t time.Time) {
+	m.syncRequests.Add(1)
+	m.lastSync.Store(t.UnixNano())
+}
+
+// feed отмечает сообщение, полученное по каналу связи в момент t.
+func (m *module) feed(t time.Time) {
+	if m.wd != nil {
+		m.wd.Feed(t)
+	}
+}
+
+// see учитывает модуль, сообщение которого получено по каналу связи.
+func (m *module) see(id proto.ModuleID) {
+	m.mu.Lock()
+	m.seen[id] = struct{}{}
+	m.mu.Unlock()
+}
+
+// received возвращает модули канала связи: настроенные и те, сообщения которых получены.
+func (m *module) received() []proto.ModuleID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := slices.Clone(m.moduleIDs)
+
+	for id := range m.seen {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.Sort(ids)
+
+	return ids
+}
+
+// stop прекращает прием сообщений модуля и закрывает его порт.
+func (m *module) stop() error {
+	m.cancel()
+
+	// приемник закрывается, чтобы прервать ожидание фрейма
+	err := m.close()
+
+	<-m.done
+
+	return err
+}
+
+// modules каналы связи модулей контроллера. Каналы запускаются и останавливаются по отдельности,
+// поэтому изменение конфигурации одного модуля не затрагивает остальные. Каналы работают
+// до отмены контекста ctx. Безопасно для одновременного использования.
+type modules struct {
+	ctx      context.Context
+	moduleID proto.ModuleID
+	recorder *recording.Writer
+	msgBus   *bus.Bus
+	store    *state.Store
+	log      logger.Logger
+	// guide регуляторы курса и скорости, nil - не настроены; задаются до запуска контроля
+	// молчания (см. watch)
+	guide *guidanceRunner
+
+	// ops упорядочивает запуск и остановку модулей. Остановка ожидает завершения приема
+	// сообщений без mu, поэтому чтение состояния не блокируется остановкой модуля.
+	ops sync.Mutex
+	// mu защищает поля ниже; изменяются они только с ops, поэтому с ops их можно читать
+	// без mu
+	mu sync.Mutex
+	// cfgs настроенные модули, включая выключенные
+	cfgs map[string]*config.ModuleConnectionConfig
+	// enabled модули, включенные или выключенные через API до изменения конфигурации
+	enabled map[string]bool
+	running map[string]*module
+	// watchdogs контроль молчания запущенных модулей, сохраняется при переоткрытии порта
+	watchdogs map[string]*watchdog.Watchdog
+}
+
+func newModules(
+	ctx context.Context,
+	moduleID proto.ModuleID,
+	msgBus *bus.Bus,
+	store *state.Store,
+	recorder *recording.Writer,
+	log logger.Logger,
+) *modules {
+	return &modules{
+		ctx:       ctx,
+		moduleID:  moduleID,
+		recorder:  recorder,
+		msgBus:    msgBus,
+		store:     store,
+		log:       log,
+		cfgs:      make(map[string]*config.ModuleConnectionConfig),
+		enabled:   make(map[string]bool),
+		running:   make(map[string]*module),
+		watchdogs: make(map[string]*watchdog.Watchdog),
+	}
+}
+
+// startAll запускает включенные модули конфигурации cfgs. При ошибке запуска модули,
+// запущенные ранее, продолжают работу.
+func (ms *modules) startAll(cfgs map[string]*config.ModuleConnectionConfig) error {
+	ms.ops.Lock()
+	defer ms.ops.Unlock()
+
+	ms.mu.Lock()
+	ms.cfgs = cfgs
+	ms.mu.Unlock()
+
+	for _, name := range slices.Sorted(maps.Keys(cfgs)) {
+		connCfg := cfgs[name]
+		if !connCfg.Enabled {
+			continue
+		}
+
+		err := ms.start(name, connCfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// start открывает порт модуля name и запускает прием сообщений. Вызывается с ms.ops.
+func (ms *modules) start(name string, connCfg *config.ModuleConnectionConfig) error {
+	moduleIDs, err := connCfg.ModuleIDs()
+	if err != nil {
+		return fmt.Errorf("bad modules of %s: %w", name, err)
+	}
+
+	portLog := logger.Wrap(logrus.StandardLogger(), fmt.Sprintf("[%s]", name))
+
+	srcPort, err := OpenPort(connCfg.Listener, portLog)
+	if err != nil {
+		return fmt.Errorf("cannot open port %s: %w", connCfg.Listener, err)
+	}
+
+	ms.log.Debugf("successfull open port: %s", connCfg.Listener)
+
+	sncr := communication.NewSyncer(ms.moduleID).WithReadWriter(srcPort)
+
+	rcvr := communication.NewReceiver(srcPort, ms.moduleID).
+		WithSync(connCfg.Listener.Sync).
+		WithChunkSize(connCfg.Listener.ChunkSize).
+		WithRetriesLimit(connCfg.Listener.RetriesLimit)
+
+	if ms.recorder != nil {
+		hook, err := recordFrames(ms.recorder, connCfg.Listener.Port, portLog)
+		if err != nil {
+			_ = srcPort.Close()
+			return fmt.Errorf("cannot record %s: %w", name, err)
+		}
+
+		rcvr.WithFrameHook(hook)
+	}
+
+	ctx, cancel := context.WithCancel(ms.ctx)
+
+	m := &module{
+		rcvr:      rcvr,
+		sncr:      sncr,
+		cfg:       connCfg,
+		moduleIDs: moduleIDs,
+		cancel:    cancel,
+		close:     sync.OnceValue(rcvr.Close),
+		done:      make(chan struct{}),
+		seen:      make(map[proto.ModuleID]struct{}),
+	}
+
+	ms.mu.Lock()
+	ms.setWatchdog(name, m, connCfg)
+	ms.running[name] = m
+	ms.mu.Unlock()
+
+	go receiving(ctx, name, m, ms.msgBus, ms.store)
+
+	return nil
+}
+
+// stop останавливает модуль name и ожидает завершения приема сообщений. Контроль молчания
+// модуля сохраняется. Вызывается с ms.ops.
+func (ms *modules) stop(name string) {
+	m, ok := ms.running[name]
+	if !ok {
+		return
+	}
+
+	ms.mu.Lock()
+	delete(ms.running, name)
+	ms.mu.Unlock()
+
+	err := m.stop()
+	if err != nil {
+		ms.log.Errorf("cannot close receiver of %s: %v", name, err)
+	}
+}
+
+// remove останавливает модуль name и удаляет его контроль молчания вместе с аварийной уставкой
+// молчания. Вызывается с ms.ops.
+func (ms *modules) remove(name string) {
+	ms.stop(name)
+
+	ms.mu.Lock()
+	delete(ms.watchdogs, name)
+	ms.mu.Unlock()
+
+	if ms.guide != nil {
+		ms.guide.Release(watchdogOverride(name))
+	}
+}
+
+// stopAll останавливает все модули.
+func (ms *modules) stopAll() {
+	ms.ops.Lock()
+	defer ms.ops.Unlock()
+
+	for _, name := range slices.Sorted(maps.Keys(ms.running)) {
+		ms.stop(name)
+	}
+}
+
+// apply приводит запущенные модули к конфигурации cfgs: запускает включенные модули, останавливает
+// выключенные и переоткрывает порты модулей с измененными настройками. Модули без изменений
+// продолжают работу.
+func (ms *modules) apply(cfgs map[string]*config.ModuleConnectionConfig) {
+	ms.ops.Lock()
+	defer ms.ops.Unlock()
+
+	ms.mu.Lock()
+	ms.cfgs = cfgs
+	clear(ms.enabled)
+	ms.mu.Unlock()
+
+	for _, name := range slices.Sorted(maps.Keys(ms.running)) {
+		connCfg, ok := cfgs[name]
+		if ok && connCfg.Enabled {
+			continue
+		}
+
+		ms.log.Infof("module %s disabled, stop receiving", name)
+		ms.remove(name)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfgs)) {
+		connCfg := cfgs[name]
+		if !connCfg.Enabled {
+			continue
+		}
+
+		old, ok := ms.running[name]
+		if ok && old.cfg.Equal(connCfg) {
+			continue
+		}
+
+		if ok {
+			ms.log.Infof("settings of module %s changed, reopen port %s", name, connCfg.Listener)
+			ms.stop(name)
+		} else {
+			ms.log.Infof("module %s enabled, start receiving", name)
+		}
+
+		err := ms.start(name, connCfg)
+		if err == nil {
+			continue
+		}
+
+		ms.log.Errorf("cannot start module %s: %v", name, err)
+
+		if !ok {
+			continue
+		}
+
+		// новые настройки не применились, модуль возвращается к прежним
+		err = ms.start(name, old.cfg)
+		if err != nil {
+			ms.log.Errorf("cannot restore module %s: %v", name, err)
+		}
+	}
+}
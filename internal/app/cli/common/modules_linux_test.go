@@ -0,0 +1,240 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	serialport "asvsoft/internal/pkg/serial-port"
+	"asvsoft/internal/pkg/serial-port/test"
+	"asvsoft/internal/pkg/state"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// newTestModules возвращает каналы связи модулей контроллера, работающие до завершения теста.
+func newTestModules(t *testing.T) *modules {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ms := newModules(ctx, proto.ControlModuleID, bus.New(), state.New(), nil, logger.DummyLogger{})
+
+	t.Cleanup(func() {
+		ms.stopAll()
+		cancel()
+	})
+
+	return ms
+}
+
+// ptyModule возвращает конфигурацию модуля, принимающего сообщения по псевдотерминалу.
+func ptyModule(t *testing.T) *config.ModuleConnectionConfig {
+	cfg, _ := ptyModuleWithMaster(t)
+	return cfg
+}
+
+// ptyModuleWithMaster аналогично ptyModule, но также возвращает ведущую сторону
+// псевдотерминала для передачи сообщений модулю.
+func ptyModuleWithMaster(t *testing.T) (*config.ModuleConnectionConfig, *os.File) {
+	master, slave := test.OpenPTY(t)
+
+	listener := &config.SerialPortConfig{Config: serialport.Config{Port: slave, BaudRate: 9600, Timeout: 100 * time.Millisecond}}
+	listener.SetDefaults()
+
+	return &config.ModuleConnectionConfig{Enabled: true, Listener: listener}, master
+}
+
+// requireClosed проверяет, что прием сообщений модуля m завершился без переоткрытия порта.
+func requireClosed(t *testing.T, m *module) {
+	t.Helper()
+
+	select {
+	case <-m.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("module is still receiving after stop")
+	}
+
+	require.Zero(t, m.rcvr.Stats().Reopens)
+}
+
+// requireStopped проверяет, что прием сообщений модуля m завершился, а его порт закрыт без
+// переоткрытия и открывается повторно.
+func requireStopped(t *testing.T, m *module) {
+	t.Helper()
+
+	requireClosed(t, m)
+
+	port, err := serialport.New(m.cfg.Listener.Config)
+	require.NoError(t, err)
+	require.NoError(t, port.Close())
+}
+
+func TestModulesStop(t *testing.T) {
+	t.Run("остановка модуля на последовательном порту", func(t *testing.T) {
+		ms := newTestModules(t)
+
+		require.NoError(t, ms.startAll(map[string]*config.ModuleConnectionConfig{"imu": ptyModule(t)}))
+
+		m := ms.running["imu"]
+		require.NotNil(t, m)
+
+		// прием должен дойти до чтения порта
+		time.Sleep(100 * time.Millisecond)
+
+		stopped := make(chan struct{})
+
+		go func() {
+			ms.stopAll()
+			close(stopped)
+		}()
+
+		requireStopped(t, m)
+		<-stopped
+
+		require.Empty(t, ms.running)
+	})
+}
+
+func TestModulesApply(t *testing.T) {
+	t.Run("добавление модуля", func(t *testing.T) {
+		imu, gnss := ptyModule(t), ptyModule(t)
+
+		ms := newTestModules(t)
+		require.NoError(t, ms.startAll(map[string]*config.ModuleConnectionConfig{"imu": imu}))
+
+		m := ms.running["imu"]
+
+		ms.apply(map[string]*config.ModuleConnectionConfig{"imu": imu, "gnss": gnss})
+
+		require.Len(t, ms.running, 2)
+		require.Same(t, m, ms.running["imu"])
+		require.NotNil(t, ms.running["gnss"])
+	})
+
+	t.Run("удаление модуля", func(t *testing.T) {
+		imu, gnss := ptyModule(t), ptyModule(t)
+
+		ms := newTestModules(t)
+		require.NoError(t, ms.startAll(map[string]*config.ModuleConnectionConfig{"imu": imu, "gnss": gnss}))
+
+		m := ms.running["gnss"]
+
+		disabled := *gnss
+		disabled.Enabled = false
+
+		ms.apply(map[string]*config.ModuleConnectionConfig{"imu": imu, "gnss": &disabled})
+		requireStopped(t, m)
+		require.Len(t, ms.running, 1)
+
+		ms.apply(map[string]*config.ModuleConnectionConfig{"imu": imu})
+		require.Len(t, ms.running, 1)
+		require.NotNil(t, ms.running["imu"])
+	})
+
+	t.Run("изменение настроек модуля", func(t *testing.T) {
+		imu := ptyModule(t)
+
+		ms := newTestModules(t)
+		require.NoError(t, ms.startAll(map[string]*config.ModuleConnectionConfig{"imu": imu}))
+
+		m := ms.running["imu"]
+
+		listener := *imu.Listener
+		listener.BaudRate = 19200
+
+		changed := *imu
+		changed.Listener = &listener
+
+		ms.apply(map[string]*config.ModuleConnectionConfig{"imu": &changed})
+		requireClosed(t, m)
+
+		require.NotSame(t, m, ms.running["imu"])
+		require.Equal(t, 19200, ms.running["imu"].cfg.Listener.BaudRate)
+	})
+}
+
+// writeControllerConfig заменяет файл конфигурации контроллера cfgPath конфигурацией с модулями
+// cfgs. Файл заменяется переименованием, чтобы отслеживание не прочитало его частично.
+func writeControllerConfig(t *testing.T, cfgPath string, cfgs map[string]*config.ModuleConnectionConfig) {
+	t.Helper()
+
+	data, err := yaml.Marshal(config.ControllerConfig{Modules: cfgs})
+	require.NoError(t, err)
+
+	tmpPath := cfgPath + ".tmp"
+	require.NoError(t, os.WriteFile(tmpPath, data, 0o600))
+	require.NoError(t, os.Rename(tmpPath, cfgPath))
+}
+
+func TestWatchReloads(t *testing.T) {
+	imu, gnss := ptyModule(t), ptyModule(t)
+
+	cfgPath := filepath.Join(t.TempDir(), "controller.yaml")
+	writeControllerConfig(t, cfgPath, map[string]*config.ModuleConnectionConfig{"imu": imu})
+
+	ctrlCfg, err := config.NewControllerConfig(cfgPath)
+	require.NoError(t, err)
+
+	ms := newTestModules(t)
+	require.NoError(t, ms.startAll(ctrlCfg.Modules))
+
+	m := ms.running["imu"]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads, err := watchReloads(ctx, cfgPath, logger.DummyLogger{})
+	require.NoError(t, err)
+
+	t.Run("отклоненная конфигурация", func(t *testing.T) {
+		listener := *imu.Listener
+		listener.BaudRate = 1000
+
+		invalid := *imu
+		invalid.Listener = &listener
+
+		writeControllerConfig(t, cfgPath, map[string]*config.ModuleConnectionConfig{"imu": &invalid})
+
+		select {
+		case cfg := <-reloads:
+			t.Fatalf("invalid config is reloaded: %v", cfg.Modules)
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		require.Len(t, ms.running, 1)
+		require.Same(t, m, ms.running["imu"])
+	})
+
+	t.Run("принятая конфигурация", func(t *testing.T) {
+		writeControllerConfig(t, cfgPath, map[string]*config.ModuleConnectionConfig{"imu": imu, "gnss": gnss})
+
+		select {
+		case cfg := <-reloads:
+			ms.apply(cfg.Modules)
+		case <-time.After(2 * time.Second):
+			t.Fatal("config is not reloaded")
+		}
+
+		require.Len(t, ms.running, 2)
+		require.Same(t, m, ms.running["imu"])
+	})
+	t.Run("отмена отслеживания", func(t *testing.T) {
+		cancel()
+
+		// отслеживание завершается асинхронно
+		time.Sleep(100 * time.Millisecond)
+
+		writeControllerConfig(t, cfgPath, map[string]*config.ModuleConnectionConfig{"imu": imu})
+
+		select {
+		case cfg := <-reloads:
+			t.Fatalf("config is reloaded after cancel: %v", cfg.Modules)
+		case <-time.After(500 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/linkstats"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/mux"
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// MuxHandler открывает общий канал связи с контроллером и каналы модулей и запускает мультиплексор.
+func MuxHandler(muxCfgPath *string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		log := logger.Wrap(logrus.StandardLogger(), "[mux]")
+
+		cfg, err := config.NewMuxConfig(*muxCfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to get mux config: %w", err)
+		}
+
+		uplink, err := OpenPort(cfg.Uplink, logger.Wrap(logrus.StandardLogger(), "[uplink]"))
+		if err != nil {
+			return fmt.Errorf("cannot open uplink %s: %w", cfg.Uplink, err)
+		}
+
+		defer uplink.Close()
+
+		m := mux.New(uplink).
+			WithSync(cfg.Uplink.Sync).
+			WithRetriesLimit(cfg.Uplink.RetriesLimit).
+			WithLogger(log)
+
+		for name, inCfg := range cfg.Inputs {
+			in, err := OpenPort(inCfg.Listener, logger.Wrap(logrus.StandardLogger(), fmt.Sprintf("[%s]", name)))
+			if err != nil {
+				return fmt.Errorf("cannot open input %s: %w", name, err)
+			}
+
+			defer in.Close()
+
+			m.WithInput(mux.Input{
+				Name:      name,
+				RW:        in,
+				Priority:  inCfg.Priority,
+				Weight:    inCfg.Weight,
+				QueueSize: inCfg.QueueSize,
+				Sync:      inCfg.Listener.Sync,
+			})
+
+			log.Infof("input %s: %s, priority: %d, weight: %d", name, inCfg.Listener.Port, inCfg.Priority, inCfg.Weight)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		go linkstats.Log(ctx, log, cfg.Uplink.StatsInterval, m)
+
+		err = m.Run(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+
+		log.Infof("signal called, stop multiplexing")
+
+		return nil
+	}
+}
@@ -0,0 +1,184 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/guidance"
+	"asvsoft/internal/pkg/imu"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/navfilter"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// minGNSSStd наименьшая СКО измерений ГНСС в метрах и м/с: приемник может не передавать
+// точность решения
+const minGNSSStd = 0.1
+
+// navigationFilter фильтр сообщений ИНС и ГНСС; лишние режимы отбрасываются в Handle
+var navigationFilter = bus.Filter{
+	Modules:  []proto.ModuleID{proto.IMUModuleID, proto.GNSSModuleID},
+	Messages: []proto.MessageID{proto.WritingModeA, proto.WritingModeB, proto.WritingModeC},
+}
+
+// navigationRunner формирует навигационное решение фильтром ИНС и ГНСС по системному времени
+// сообщений и публикует его сообщениями модуля navigation (режим WritingModeA).
+type navigationRunner struct {
+	cfg    *config.NavigationConfig
+	store  *state.Store
+	msgBus *bus.Bus
+	log    logger.Logger
+
+	mu     sync.Mutex
+	filter *navfilter.Filter
+	// updatedAt время последнего измерения, нулевое - измерений не было
+	updatedAt time.Time
+	// stale решение не публикуется из-за отсутствия измерений
+	stale bool
+}
+
+func newNavigationRunner(
+	cfg *config.NavigationConfig,
+	store *state.Store,
+	msgBus *bus.Bus,
+	log logger.Logger,
+) *navigationRunner {
+	return &navigationRunner{
+		cfg:    cfg,
+		store:  store,
+		msgBus: msgBus,
+		log:    log,
+		filter: navfilter.NewFilter(cfg.Noise()),
+	}
+}
+
+// Handle учитывает измерения ИНС и ГНСС из сообщения.
+func (r *navigationRunner) Handle(msg proto.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch data := msg.Payload.(type) {
+	case *proto.IMUData:
+		s, ok := imu.FromProto(data, msg.MsgID)
+		if !ok {
+			return nil
+		}
+
+		r.filter.UpdateIMU(msg.SystemTime, s)
+	case *proto.GNSSData:
+		if pos, ok := gnssPosition(msg); ok {
+			// HAcc в мм
+			std := math.Max(float64(data.HAcc)/1000, minGNSSStd)
+			r.filter.UpdatePosition(msg.SystemTime, pos, std)
+		}
+
+		// в режиме WritingModeB скорости нет
+		if msg.MsgID == proto.WritingModeB {
+			return nil
+		}
+
+		// VelN, VelE и SAcc в см/с
+		std := math.Max(float64(data.SAcc)/100, minGNSSStd)
+		r.filter.UpdateVelocity(msg.SystemTime, float64(data.VelN)/100, float64(data.VelE)/100, std)
+
+		course, speed := guidance.GNSSCourse(data)
+		if speed >= r.cfg.MinCourseSpeed {
+			r.filter.UpdateCourse(msg.SystemTime, course)
+		}
+	default:
+		return nil
+	}
+
+	r.updatedAt = time.Now()
+
+	return nil
+}
+
+// run публикует решение с частотой конфигурации до отмены контекста.
+func (r *navigationRunner) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / r.cfg.Rate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.publish(now)
+		}
+	}
+}
+
+// publish публикует решение, если оно определено и измерения не старше таймаута на момент now.
+func (r *navigationRunner) publish(now time.Time) {
+	r.mu.Lock()
+	s := r.filter.State()
+	updatedAt := r.updatedAt
+	r.mu.Unlock()
+
+	if !s.PositionValid {
+		return
+	}
+
+	stale := now.Sub(updatedAt) > r.cfg.Timeout
+	if stale != r.stale {
+		if stale {
+			r.log.Warnf("no measurements for %v, solution is not published", r.cfg.Timeout)
+		} else {
+			r.log.Infof("measurements resumed")
+		}
+
+		r.stale = stale
+	}
+
+	if stale {
+		return
+	}
+
+	msg := proto.Message{
+		ModuleID:   proto.NavigationModuleID,
+		MsgID:      proto.WritingModeA,
+		SystemTime: s.Time,
+		Payload:    navigationData(s, r.cfg.Timeout),
+	}
+
+	r.store.Update(msg)
+	r.msgBus.Publish(msg)
+}
+
+// navigationData возвращает сообщение с решением s; положение счислено, если решения ГНСС
+// не было дольше timeout.
+func navigationData(s navfilter.State, timeout time.Duration) *proto.NavigationData {
+	status := proto.NavigationPositionValid
+
+	if s.HeadingValid {
+		status |= proto.NavigationHeadingValid
+	}
+
+	if s.FixAge > timeout {
+		status |= proto.NavigationDeadReckoning
+	}
+
+	return &proto.NavigationData{
+		Lat:         int32(math.Round(s.Position.Lat * 1e7)),
+		Lon:         int32(math.Round(s.Position.Lon * 1e7)),
+		VelN:        int16(saturate(s.VelN*100, math.MinInt16, math.MaxInt16)),
+		VelE:        int16(saturate(s.VelE*100, math.MinInt16, math.MaxInt16)),
+		Heading:     uint16(math.Round(s.Heading*100)) % 36000,
+		GyroBias:    int16(saturate(s.GyroBias*1000, math.MinInt16, math.MaxInt16)),
+		AccBiasX:    int16(saturate(s.AccBiasX*1000, math.MinInt16, math.MaxInt16)),
+		AccBiasY:    int16(saturate(s.AccBiasY*1000, math.MinInt16, math.MaxInt16)),
+		PositionStd: uint16(saturate(s.PositionStd*100, 0, math.MaxUint16)),
+		HeadingStd:  uint16(saturate(s.HeadingStd*100, 0, math.MaxUint16)),
+		Status:      status,
+	}
+}
+
+// saturate округляет v и ограничивает диапазоном [lo, hi].
+func saturate(v, lo, hi float64) float64 {
+	return math.Min(math.Max(math.Round(v), lo), hi)
+}
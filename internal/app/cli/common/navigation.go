@@ -0,0 +1,95 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/router"
+	"asvsoft/internal/pkg/state"
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// positionFilter фильтр сообщений ГНСС с координатами
+var positionFilter = bus.Filter{
+	Modules:  []proto.ModuleID{proto.GNSSModuleID},
+	Messages: []proto.MessageID{proto.WritingModeA, proto.WritingModeB},
+}
+
+// gnssPosition возвращает положение из сообщения ГНСС msg. Возвращает false, если сообщение
+// не содержит положения: режим WritingModeC передает только скорость, а нулевые координаты
+// передаются приемником до получения решения.
+func gnssPosition(msg proto.Message) (geo.Point, bool) {
+	data, ok := msg.Payload.(*proto.GNSSData)
+	if !ok || msg.MsgID == proto.WritingModeC || (data.Lat == 0 && data.Lon == 0) {
+		return geo.Point{}, false
+	}
+
+	return geo.FromE7(data.Lat, data.Lon), true
+}
+
+// subscribeNavigation подписывает на шину msgBus фильтр ИНС и ГНСС, оценку ориентации, миссию,
+// регуляторы курса и скорости, контроль зон плавания и аварийные реакции, настроенные в cfg.
+// Должна вызываться до запуска шины. Возвращает регуляторы курса и скорости, nil - не настроены.
+func subscribeNavigation(
+	ctx context.Context,
+	cfg *config.ControllerConfig,
+	rtr *router.Router,
+	store *state.Store,
+	msgBus *bus.Bus,
+) (*guidanceRunner, error) {
+	var guide *guidanceRunner
+
+	if cfg.Navigation != nil {
+		runner := newNavigationRunner(cfg.Navigation, store, msgBus, logger.Wrap(logrus.StandardLogger(), "[navigation]"))
+		msgBus.Subscribe("navigation", navigationFilter, runner)
+
+		go runner.run(ctx)
+	}
+
+	if cfg.AHRS != nil {
+		runner := newAHRSRunner(cfg.AHRS.Madgwick(), store, msgBus)
+		msgBus.Subscribe("ahrs", ahrsFilter, runner)
+	}
+
+	if cfg.Mission != nil {
+		runner, err := newMissionRunner(cfg.Mission, store, msgBus, logger.Wrap(logrus.StandardLogger(), "[mission]"))
+		if err != nil {
+			return nil, err
+		}
+
+		msgBus.Subscribe("mission", positionFilter, runner)
+
+		if cfg.Guidance != nil {
+			guide = newGuidanceRunner(cfg.Guidance, runner, store, msgBus, logger.Wrap(logrus.StandardLogger(), "[guidance]"))
+			msgBus.Subscribe("guidance", guide.filter(), guide)
+
+			go guide.run(ctx)
+		}
+	}
+
+	if cfg.Geofence != nil {
+		runner, err := newGeofenceRunner(cfg.Geofence, guide, store, msgBus, logger.Wrap(logrus.StandardLogger(), "[geofence]"))
+		if err != nil {
+			return nil, err
+		}
+
+		msgBus.Subscribe("geofence", positionFilter, runner)
+	}
+
+	if cfg.Failsafe != nil {
+		runner, err := newFailsafeRunner(cfg.Failsafe, rtr, guide, store, msgBus, logger.Wrap(logrus.StandardLogger(), "[failsafe]"))
+		if err != nil {
+			return nil, err
+		}
+
+		msgBus.Subscribe("failsafe", bus.Filter{}, runner)
+
+		go runner.run(ctx)
+	}
+
+	return guide, nil
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// newRecorder создает запись фреймов; относительный каталог записи отсчитывается от baseDir.
+func newRecorder(cfg *config.RecordingConfig, baseDir string) (*recording.Writer, error) {
+	dir := cfg.Dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(baseDir, dir)
+	}
+
+	err := os.MkdirAll(dir, 0755) // nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("cannot create recording directory: %w", err)
+	}
+
+	return recording.NewWriter(dir, cfg.Prefix).
+		WithMaxSize(cfg.MaxSize).
+		WithMaxDuration(cfg.MaxDuration).
+		WithIndexInterval(cfg.IndexInterval).
+		WithSyncInterval(cfg.SyncInterval), nil
+}
+
+// recordFrames возвращает обработчик фреймов приемника, записывающий каждый прочитанный фрейм
+// источника source, включая поврежденные.
+func recordFrames(w *recording.Writer, source string, log logger.Logger) (communication.FrameHook, error) {
+	id, err := w.Source(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(frame []byte, err error) {
+		var flags recording.Flags
+
+		switch {
+		case errors.Is(err, proto.ErrChecksumMismatch):
+			flags |= recording.FlagChecksumFailed
+		case err != nil:
+			flags |= recording.FlagInvalid
+		}
+
+		err = w.Write(id, time.Now(), flags, frame)
+		if err != nil {
+			log.Errorf("cannot record frame: %v", err)
+		}
+	}, nil
+}
+
+// controlSource источник записи сообщений, сформированных контроллером
+const controlSource = "controller"
+
+// recordControl возвращает обработчик шины, записывающий сообщения, сформированные
+// контроллером: события и уставки исполнительных механизмов и навигационное решение.
+func recordControl(w *recording.Writer, log logger.Logger) (bus.Handler, error) {
+	id, err := w.Source(controlSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return bus.HandlerFunc(func(msg proto.Message) error {
+		frame, err := msg.MarshalKeepTime()
+		if err != nil {
+			return fmt.Errorf("cannot marshal controller message: %w", err)
+		}
+
+		err = w.Write(id, time.Now(), 0, frame)
+		if err != nil {
+			log.Errorf("cannot record controller message: %v", err)
+		}
+
+		return nil
+	}), nil
+}
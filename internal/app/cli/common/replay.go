@@ -0,0 +1,98 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/replay"
+	"asvsoft/internal/pkg/transport"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type ReplayOptions struct {
+	Port    *config.SerialPortConfig
+	Speed   float64
+	Loop    bool
+	Modules []string
+	Restamp bool
+	// Hold время, в течение которого канал остается открытым после последнего фрейма, чтобы
+	// получатель успел обработать принятые данные
+	Hold time.Duration
+}
+
+// ReplayHandler воспроизводит записи регистратора в канал связи.
+func ReplayHandler(opts *ReplayOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		log := logger.Wrap(logrus.StandardLogger(), "[replay]")
+
+		modules, err := config.ModuleIDs(opts.Modules)
+		if err != nil {
+			return err
+		}
+
+		port, err := OpenPort(opts.Port, log)
+		if err != nil {
+			return fmt.Errorf("cannot open port %s: %w", opts.Port, err)
+		}
+
+		defer port.Close()
+
+		// ответы получателя не нужны, но должны вычитываться, чтобы не заполнить буфер канала
+		go drain(port)
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		stats, err := replay.New(port).
+			WithSpeed(opts.Speed).
+			WithLoop(opts.Loop).
+			WithModules(modules).
+			WithRestamp(opts.Restamp).
+			WithLogger(log).
+			Play(ctx, args)
+
+		log.Infof("replayed %d frames (%d bytes), skipped %d, loops %d", stats.Frames, stats.Bytes, stats.Skipped, stats.Loops)
+
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(opts.Hold):
+		}
+
+		return nil
+	}
+}
+
+// drain читает и отбрасывает ответы получателя, чтобы подтверждения и ответы синхронизации
+// не копились в буфере порта. Завершается при закрытии порта или конце данных.
+func drain(r io.Reader) {
+	buf := make([]byte, 256)
+
+	for {
+		n, err := r.Read(buf)
+
+		switch {
+		case errors.Is(err, transport.ErrReadTimeout), errors.Is(err, transport.ErrNotConnected):
+			time.Sleep(10 * time.Millisecond)
+		case err != nil:
+			return
+		case n == 0:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
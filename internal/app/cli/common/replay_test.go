@@ -0,0 +1,57 @@
+package common
+
+import (
+	"asvsoft/internal/pkg/transport"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedReader возвращает ошибки чтения по порядку и считает прочитанные байты.
+type scriptedReader struct {
+	errs []error
+	read int
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if len(r.errs) == 0 {
+		return 0, io.EOF
+	}
+
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+
+	if err != nil {
+		return 0, err
+	}
+
+	r.read += len(p)
+
+	return len(p), nil
+}
+
+func TestDrain(t *testing.T) {
+	t.Run("чтение продолжается после таймаута до конца данных", func(t *testing.T) {
+		r := &scriptedReader{errs: []error{
+			transport.ErrReadTimeout, nil, transport.ErrNotConnected, transport.ErrReadTimeout, nil,
+		}}
+
+		done := make(chan struct{})
+
+		go func() {
+			drain(r)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("drain did not return at end of data")
+		}
+
+		require.Equal(t, 512, r.read)
+		require.Empty(t, r.errs)
+	})
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/linkstats"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/router"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newRouter открывает каналы связи получателей маршрутов и создает маршрутизатор. Для каждого
+// получателя создается отдельный отправитель со своей гарантированной доставкой и статистикой.
+func newRouter(ctx context.Context, cfgs []*config.RouteConfig) (*router.Router, []io.Closer, error) {
+	routes := make([]*router.Route, 0, len(cfgs))
+	closers := make([]io.Closer, 0, len(cfgs))
+
+	closeAll := func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}
+
+	for i, cfg := range cfgs {
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("route-%d", i)
+		}
+
+		log := logger.Wrap(logrus.StandardLogger(), fmt.Sprintf("[route %s]", name))
+
+		modules, err := cfg.ModuleIDs()
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("bad modules of route %s: %w", name, err)
+		}
+
+		messages := make([]proto.MessageID, 0, len(cfg.Messages))
+		for _, id := range cfg.Messages {
+			messages = append(messages, proto.MessageID(id))
+		}
+
+		dstPort, err := OpenPort(cfg.Destination, log)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("cannot open destination of route %s: %w", name, err)
+		}
+
+		closers = append(closers, dstPort)
+
+		budget, baudRate, err := cfg.Destination.LinkBudget()
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+
+		sndr := communication.NewSender(nil, proto.ControlModuleID, 0).
+			WithReadWriteCloser(dstPort).
+			WithSync(cfg.Destination.Sync).
+			WithChunkSize(cfg.Destination.ChunkSize).
+			WithRetriesLimit(cfg.Destination.RetriesLimit).
+			WithBudget(budget, baudRate)
+
+		route := router.NewRoute(name, router.Filter{Modules: modules, Messages: messages}, sndr).
+			WithRate(cfg.Rate).
+			WithQueueSize(cfg.QueueSize).
+			WithLogger(log)
+
+		if cfg.Reencode != 0 {
+			route, err = route.WithReencode(proto.MessageID(cfg.Reencode))
+			if err != nil {
+				closeAll()
+				return nil, nil, fmt.Errorf("bad reencode mode of route %s: %w", name, err)
+			}
+		}
+
+		go linkstats.Log(ctx, log, cfg.Destination.StatsInterval, sndr)
+
+		log.Infof("forward %v messages %v to %s", cfg.Modules, cfg.Messages, cfg.Destination.Port)
+
+		routes = append(routes, route)
+	}
+
+	return router.New(routes...), closers, nil
+}
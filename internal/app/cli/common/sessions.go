@@ -0,0 +1,165 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/app/ctxutils"
+	"asvsoft/internal/app/session"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// startSession создает сессию запуска в каталоге сессий и запускает удаление старых сессий
+// при нехватке места на диске.
+func startSession(
+	ctx context.Context,
+	cmd *cobra.Command,
+	cfgPath string,
+	cfg *config.ControllerConfig,
+) (*session.Session, error) {
+	log := logger.Wrap(logrus.StandardLogger(), "[session]")
+
+	m := session.Manifest{
+		Command:    cmd.Name(),
+		Config:     cfgPath,
+		StartStamp: proto.GetStartStamp(),
+		Effective:  cfg,
+	}
+
+	if info := ctxutils.GetAppInfo(cmd.Context()); info != nil {
+		m.Build = *info
+	}
+
+	for name, connCfg := range cfg.Modules {
+		if !connCfg.Enabled {
+			continue
+		}
+
+		m.Modules = append(m.Modules, session.ModuleInfo{
+			Name:    name,
+			Port:    connCfg.Listener.Port,
+			Modules: connCfg.Modules,
+		})
+	}
+
+	sess, err := session.Create(cfg.Sessions.Dir, m)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create session: %w", err)
+	}
+
+	log.Infof("session %s started in %s", sess.Manifest.ID, sess.Dir)
+
+	retention := session.NewRetention(cfg.Sessions.Dir, cfg.Sessions.MinFree).
+		WithCurrent(sess.Dir).
+		WithLogger(log)
+
+	go retention.Run(ctx, cfg.Sessions.RetentionInterval)
+
+	return sess, nil
+}
+
+type SessionsOptions struct {
+	// Config путь к конфигурации контроллера, из которой берется каталог сессий
+	Config string
+	// Dir каталог сессий, заменяет каталог из конфигурации
+	Dir string
+	// MinFree минимальное свободное место в байтах, заменяет значение из конфигурации
+	MinFree uint64
+}
+
+// sessionsConfig возвращает конфигурацию сессий с учетом флагов команды.
+func (o *SessionsOptions) sessionsConfig() (*config.SessionsConfig, error) {
+	cfg := &config.SessionsConfig{}
+
+	if o.Dir == "" || o.MinFree == 0 {
+		ctrlCfg, err := config.NewControllerConfig(o.Config)
+		if err != nil && o.Dir == "" {
+			return nil, fmt.Errorf("failed to get controller config: %w", err)
+		}
+
+		if err == nil && ctrlCfg.Sessions != nil {
+			cfg = ctrlCfg.Sessions
+		}
+	}
+
+	if o.Dir != "" {
+		cfg.Dir = o.Dir
+	}
+
+	if o.MinFree != 0 {
+		cfg.MinFree = o.MinFree
+	}
+
+	cfg.SetDefaults()
+
+	return cfg, nil
+}
+
+// SessionsListHandler выводит сессии каталога сессий от старых к новым.
+func SessionsListHandler(opts *SessionsOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := opts.sessionsConfig()
+		if err != nil {
+			return err
+		}
+
+		sessions, err := session.List(cfg.Dir)
+		if err != nil {
+			return fmt.Errorf("cannot list sessions: %w", err)
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCOMMAND\tSTARTED\tSTOPPED\tSIZE\tCOMMIT")
+
+		for _, s := range sessions {
+			if s.Err != nil {
+				fmt.Fprintf(w, "%s\t-\t-\t-\t%d\t%v\n", s.Dir, s.Size, s.Err)
+				continue
+			}
+
+			m := s.Manifest
+
+			stopped := "-"
+			if m.Stopped != nil {
+				stopped = m.Stopped.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(
+				w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+				m.ID, m.Command, m.Started.Format(time.RFC3339), stopped, s.Size, m.Build.BuildCommit,
+			)
+		}
+
+		return w.Flush()
+	}
+}
+
+// SessionsPruneHandler удаляет самые старые сессии, пока свободного места меньше минимального.
+func SessionsPruneHandler(opts *SessionsOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := opts.sessionsConfig()
+		if err != nil {
+			return err
+		}
+
+		if cfg.MinFree == 0 {
+			return fmt.Errorf("minimum free space is not configured")
+		}
+
+		deleted, err := session.NewRetention(cfg.Dir, cfg.MinFree).
+			WithLogger(logger.Wrap(logrus.StandardLogger(), "[session]")).
+			Prune()
+
+		for _, dir := range deleted {
+			fmt.Fprintln(cmd.OutOrStdout(), dir)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"asvsoft/internal/pkg/api"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"fmt"
+)
+
+// telemetryQueueSize размер очереди сообщений подписчика потока телеметрии
+const telemetryQueueSize = 256
+
+// telemetry реализует api.Telemetry поверх хранилища состояния: в поток попадают сообщения,
+// принятые в receiving, и переходы режимов в устаревшие.
+type telemetry struct {
+	store *state.Store
+}
+
+var _ api.Telemetry = telemetry{}
+
+func (t telemetry) Subscribe() (<-chan api.Message, func()) {
+	sub := t.store.Subscribe(telemetryQueueSize)
+	messages := make(chan api.Message, telemetryQueueSize)
+
+	go func() {
+		defer close(messages)
+
+		for e := range sub.C {
+			select {
+			case messages <- apiMessage(e):
+			default:
+			}
+		}
+	}()
+
+	return messages, func() { t.store.Unsubscribe(sub) }
+}
+
+// Image ищет изображение камеры в истории сообщений режима передачи изображений.
+func (t telemetry) Image(systemTime uint32) ([]byte, error) {
+	for _, s := range t.store.History(proto.CameraModuleID, proto.WritingModeB) {
+		data, ok := s.Payload.(*proto.CameraData)
+		if ok && s.SystemTime == systemTime {
+			return data.RawImagePart, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d", api.ErrImageNotFound, systemTime)
+}
+
+func apiMessage(e state.Entry) api.Message {
+	m := api.Message{
+		Module:     proto.ModuleName(e.Module),
+		Msg:        fmt.Sprintf("%#x", uint8(e.Msg)),
+		SystemTime: e.Latest.SystemTime,
+		RecvTime:   e.Latest.RecvTime,
+		Stale:      e.Stale,
+	}
+
+	if m.Stale {
+		return m
+	}
+
+	if data, ok := e.Latest.Payload.(*proto.CameraData); ok && e.Msg == proto.WritingModeB {
+		m.Image = &api.Image{
+			Ref:  api.ImageRef(e.Latest.SystemTime),
+			Size: len(data.RawImagePart),
+			Data: data.RawImagePart,
+		}
+
+		return m
+	}
+
+	m.Fields = apiFields(proto.Message{
+		ModuleID:   e.Module,
+		MsgID:      e.Msg,
+		SystemTime: e.Latest.SystemTime,
+		Payload:    e.Latest.Payload,
+	})
+	m.Data = e.Latest.Payload
+
+	return m
+}
@@ -0,0 +1,185 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"asvsoft/internal/pkg/watchdog"
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+// watchdogCheckInterval период проверки молчания модулей
+const watchdogCheckInterval = 100 * time.Millisecond
+
+// watchdogEvent событие контроля молчания с конфигурацией модуля на момент события
+type watchdogEvent struct {
+	watchdog.Event
+	cfg       *config.WatchdogConfig
+	moduleIDs []proto.ModuleID
+}
+
+// setWatchdog назначает модулю name контроль молчания по конфигурации connCfg. Контроль
+// с прежним таймаутом сохраняется, чтобы переоткрытие порта не сбрасывало молчание.
+// Вызывается с ms.ops и ms.mu.
+func (ms *modules) setWatchdog(name string, m *module, connCfg *config.ModuleConnectionConfig) {
+	if connCfg.Watchdog == nil {
+		delete(ms.watchdogs, name)
+		return
+	}
+
+	timeout := watchdog.Timeout(connCfg.Watchdog.Timeout, connCfg.Watchdog.ExpectedRate)
+
+	wd, ok := ms.watchdogs[name]
+	if !ok || wd.Timeout() != timeout {
+		wd = watchdog.New(name, timeout, time.Now())
+		ms.watchdogs[name] = wd
+	}
+
+	m.wd = wd
+}
+
+// watch проверяет молчание модулей до отмены контекста и выполняет действия, заданные
+// в конфигурации модулей.
+func (ms *modules) watch(ctx context.Context) {
+	ticker := time.NewTicker(watchdogCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, ev := range ms.checkWatchdogs(now) {
+				ms.onWatchdogEvent(ev)
+			}
+		}
+	}
+}
+
+func (ms *modules) checkWatchdogs(now time.Time) []watchdogEvent {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var events []watchdogEvent
+
+	for _, name := range slices.Sorted(maps.Keys(ms.watchdogs)) {
+		m, ok := ms.running[name]
+		if !ok {
+			continue
+		}
+
+		ev, ok := ms.watchdogs[name].Check(now)
+		if !ok {
+			continue
+		}
+
+		events = append(events, watchdogEvent{Event: ev, cfg: m.cfg.Watchdog, moduleIDs: m.moduleIDs})
+	}
+
+	return events
+}
+
+func (ms *modules) onWatchdogEvent(ev watchdogEvent) {
+	for _, action := range ev.cfg.Actions {
+		switch action {
+		case config.WatchdogLog:
+			if ev.Kind == watchdog.Silent {
+				ms.log.Warnf("module %s is silent: no messages for %v", ev.Module, ev.Silence.Round(time.Millisecond))
+			} else {
+				ms.log.Infof("module %s recovered after %v of silence", ev.Module, ev.Silence.Round(time.Millisecond))
+			}
+		case config.WatchdogAlarm:
+			ms.publishAlarm(ev)
+		case config.WatchdogFailsafe:
+			ms.failsafe(ev)
+		case config.WatchdogReopen:
+			if ev.Kind == watchdog.Silent {
+				ms.log.Warnf("module %s is silent, reopen port", ev.Module)
+
+				err := ms.Resync(ev.Module)
+				if err != nil {
+					ms.log.Errorf("cannot reopen port of module %s: %v", ev.Module, err)
+				}
+			}
+		}
+	}
+}
+
+// failsafe выдает аварийную уставку при молчании модуля. С регуляторами курса и скорости
+// уставка выдается вместо их уставок до восстановления модуля, иначе - однократно.
+func (ms *modules) failsafe(ev watchdogEvent) {
+	source := watchdogOverride(ev.Module)
+
+	if ev.Kind == watchdog.Recovered {
+		if ms.guide != nil {
+			ms.guide.Release(source)
+		}
+
+		return
+	}
+
+	ms.log.Warnf("module %s is silent, send failsafe setpoint %+v", ev.Module, ev.cfg.Failsafe)
+
+	if ms.guide != nil {
+		ms.guide.OverrideSetpoint(source, ev.cfg.Failsafe)
+		return
+	}
+
+	ms.publish(proto.WritingModeA, ev.cfg.Failsafe.ControlData(proto.ControlSourceFailsafe))
+}
+
+// watchdogOverride возвращает источник поведения при молчании модуля name
+// (см. guidanceRunner.Override).
+func watchdogOverride(name string) string {
+	return "watchdog " + name
+}
+
+// publishAlarm передает событие молчания или восстановления каждого модуля канала связи.
+func (ms *modules) publishAlarm(ev watchdogEvent) {
+	subjects := ev.moduleIDs
+
+	if len(subjects) == 0 {
+		if id, ok := proto.ModuleIDByName(ev.Module); ok {
+			subjects = []proto.ModuleID{id}
+		} else {
+			subjects = []proto.ModuleID{0}
+		}
+	}
+
+	var active uint8
+	if ev.Kind == watchdog.Silent {
+		active = 1
+	}
+
+	for _, id := range subjects {
+		ms.publish(proto.WritingModeB, &proto.ControlData{
+			Event:   proto.EventModuleSilent,
+			Subject: uint8(id),
+			Active:  active,
+			Value:   int32(ev.Silence.Milliseconds()),
+		})
+	}
+}
+
+// publish передает сообщение модуля управления в хранилище состояния, обработчикам и маршрутам.
+func (ms *modules) publish(msgID proto.MessageID, data *proto.ControlData) {
+	publishControl(ms.store, ms.msgBus, msgID, data)
+}
+
+// publishControl передает сообщение модуля управления в хранилище состояния store и на шину
+// msgBus: обработчикам, маршрутам и в запись.
+func publishControl(store *state.Store, msgBus *bus.Bus, msgID proto.MessageID, data *proto.ControlData) {
+	msg := proto.Message{
+		ModuleID:   proto.ControlModuleID,
+		MsgID:      msgID,
+		SystemTime: proto.SystemTime(),
+		Payload:    data,
+	}
+
+	store.Update(msg)
+	msgBus.Publish(msg)
+}
@@ -0,0 +1,58 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/watchdog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdogReopen(t *testing.T) {
+	t.Run("переоткрытие порта молчащего модуля", func(t *testing.T) {
+		ms := newTestModules(t)
+
+		cfg, master := ptyModuleWithMaster(t)
+		cfg.Watchdog = &config.WatchdogConfig{Timeout: 50 * time.Millisecond, Actions: []string{config.WatchdogReopen}}
+
+		require.NoError(t, ms.startAll(map[string]*config.ModuleConnectionConfig{"imu": cfg}))
+
+		old := ms.running["imu"]
+
+		time.Sleep(100 * time.Millisecond)
+
+		events := ms.checkWatchdogs(time.Now())
+		require.Len(t, events, 1)
+		require.Equal(t, watchdog.Silent, events[0].Kind)
+
+		handled := make(chan struct{})
+
+		go func() {
+			ms.onWatchdogEvent(events[0])
+			close(handled)
+		}()
+
+		select {
+		case <-handled:
+		case <-time.After(2 * time.Second):
+			t.Fatal("reopen action is still running")
+		}
+
+		requireClosed(t, old)
+		require.NotSame(t, old, ms.running["imu"])
+
+		// после переоткрытия порта сообщения принимаются и молчание снова контролируется
+		data, err := proto.NewMessage(proto.IMUModuleID, proto.WritingModeA, &proto.IMUData{}).Marshal()
+		require.NoError(t, err)
+
+		_, err = master.Write(data)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			events = ms.checkWatchdogs(time.Now())
+			return len(events) == 1 && events[0].Kind == watchdog.Recovered
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+}
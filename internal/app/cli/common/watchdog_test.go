@@ -0,0 +1,49 @@
+package common
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/bus"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/state"
+	"asvsoft/internal/pkg/watchdog"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdogFailsafe(t *testing.T) {
+	t.Run("аварийная уставка заменяет уставки регуляторов до восстановления", func(t *testing.T) {
+		store, msgBus := state.New(), bus.New()
+		ms := newModules(context.Background(), proto.ControlModuleID, msgBus, store, nil, logger.DummyLogger{})
+		ms.guide = newGuidanceRunner(&config.GuidanceConfig{Rate: 10}, nil, store, msgBus, logger.DummyLogger{})
+
+		ev := watchdogEvent{
+			Event: watchdog.Event{Module: "gnss", Kind: watchdog.Silent},
+			cfg: &config.WatchdogConfig{
+				Actions:  []string{config.WatchdogFailsafe},
+				Failsafe: config.SetpointConfig{Thrust: -20, Rudder: 5},
+			},
+		}
+
+		ms.onWatchdogEvent(ev)
+
+		// уставка повторяется на каждом шаге регуляторов
+		for range 3 {
+			ms.guide.step(time.Now(), 0.1)
+
+			entries := store.Module(proto.ControlModuleID)
+			require.Len(t, entries, 1)
+			require.Equal(t, &proto.ControlData{Thrust: -200, Rudder: 500, Source: proto.ControlSourceFailsafe},
+				entries[0].Latest.Payload)
+		}
+
+		ev.Kind = watchdog.Recovered
+		ms.onWatchdogEvent(ev)
+
+		_, ok := ms.guide.override()
+		require.False(t, ok)
+	})
+}
@@ -2,42 +2,623 @@
 package config
 
 import (
+	"asvsoft/internal/app/session"
+	"asvsoft/internal/pkg/ahrs"
+	"asvsoft/internal/pkg/api"
 	"asvsoft/internal/pkg/communication"
+	"asvsoft/internal/pkg/failsafe"
+	"asvsoft/internal/pkg/geofence"
+	"asvsoft/internal/pkg/guidance"
+	"asvsoft/internal/pkg/linkstats"
+	"asvsoft/internal/pkg/metrics"
+	"asvsoft/internal/pkg/mission"
+	"asvsoft/internal/pkg/navfilter"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
 	serialport "asvsoft/internal/pkg/serial-port"
+	"asvsoft/internal/pkg/transport"
+	"context"
 	"fmt"
+	"math"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// ModuleConfig конфигурация модуля. Загружается из флагов команды, переменных окружения
+// и yaml-файла (см. LoadModuleConfig).
 type ModuleConfig struct {
-	SensorSerialPort      *SerialPortConfig
-	ControllerSerialPort  *SerialPortConfig
-	RegistratorSerialPort *SerialPortConfig
-	NeoM8t                *NeoM8tConfig
-	SenseHAT              *SenseHATConfig
+	SensorSerialPort      *SerialPortConfig `yaml:"sensor,omitempty" mapstructure:"sensor"`
+	ControllerSerialPort  *SerialPortConfig `yaml:"controller,omitempty" mapstructure:"controller"`
+	RegistratorSerialPort *SerialPortConfig `yaml:"registrar,omitempty" mapstructure:"registrar"`
+	NeoM8t                *NeoM8tConfig     `yaml:"neo_m8t,omitempty" mapstructure:"neo_m8t"`
+	SenseHAT              *SenseHATConfig   `yaml:"sense_hat,omitempty" mapstructure:"sense_hat"`
+	// Metrics метрики модуля, пустой адрес - метрики отключены
+	Metrics *MetricsConfig `yaml:"metrics,omitempty" mapstructure:"metrics"`
 }
 
+const (
+	// DefaultRecordingPrefix префикс имен файлов записи по умолчанию
+	DefaultRecordingPrefix = "asvsoft"
+	// DefaultSessionsDir каталог сессий по умолчанию
+	DefaultSessionsDir = "/var/lib/asvsoft/sessions"
+)
+
 type ControllerConfig struct {
 	Modules map[string]*ModuleConnectionConfig `yaml:"modules" mapstructure:"modules"`
+	Routes  []*RouteConfig                     `yaml:"routes" mapstructure:"routes"`
+	// Handlers обработчики полученных сообщений; если не заданы, изображения камеры сохраняются
+	// в текущую директорию
+	Handlers []*HandlerConfig `yaml:"handlers" mapstructure:"handlers"`
+	// Recording запись принятых фреймов, nil - запись отключена
+	Recording *RecordingConfig `yaml:"recording" mapstructure:"recording"`
+	// Sessions каталог сессий: каждый запуск создает в нем каталог с манифестом, относительные
+	// пути записи и обработчиков отсчитываются от каталога сессии; nil - сессии не создаются
+	Sessions *SessionsConfig `yaml:"sessions" mapstructure:"sessions"`
+	// State хранилище последних значений сигналов, nil - значения по умолчанию
+	State *StateConfig `yaml:"state" mapstructure:"state"`
+	// API локальный HTTP API состояния и управления, nil - API отключен
+	API *APIConfig `yaml:"api" mapstructure:"api"`
+	// Metrics метрики в формате Prometheus, nil - метрики отключены
+	Metrics *MetricsConfig `yaml:"metrics" mapstructure:"metrics"`
+	// Mission миссия по точкам маршрута, nil - миссия не выполняется
+	Mission *MissionConfig `yaml:"mission" mapstructure:"mission"`
+	// Guidance регуляторы курса и скорости для движения по миссии, nil - уставки не выдаются
+	Guidance *GuidanceConfig `yaml:"guidance" mapstructure:"guidance"`
+	// Geofence зоны плавания, nil - зоны не контролируются
+	Geofence *GeofenceConfig `yaml:"geofence" mapstructure:"geofence"`
+	// Failsafe аварийные реакции на потерю связи, решения ГНСС, модулей и разряд батареи,
+	// nil - не настроены
+	Failsafe *FailsafeConfig `yaml:"failsafe" mapstructure:"failsafe"`
+	// Navigation фильтр ИНС и ГНСС, nil - навигационное решение не формируется
+	Navigation *NavigationConfig `yaml:"navigation" mapstructure:"navigation"`
+	// AHRS оценка ориентации по сообщениям IMU, nil - ориентация не оценивается
+	AHRS *AHRSConfig `yaml:"ahrs" mapstructure:"ahrs"`
+}
+
+// APIConfig конфигурация HTTP API (см. api.Server)
+type APIConfig struct {
+	// Listen адрес host:port, по умолчанию доступный только локально
+	Listen string `yaml:"listen" mapstructure:"listen"`
+}
+
+func (c *APIConfig) SetDefaults() {
+	if c.Listen == "" {
+		c.Listen = api.DefaultListen
+	}
+}
+
+// MetricsConfig конфигурация HTTP-сервера метрик (см. metrics.Server)
+type MetricsConfig struct {
+	// Listen адрес host:port
+	Listen string `yaml:"listen" mapstructure:"listen"`
+}
+
+func (c *MetricsConfig) SetDefaults() {
+	if c.Listen == "" {
+		c.Listen = metrics.DefaultListen
+	}
+}
+
+// MissionConfig конфигурация миссии по точкам маршрута (см. mission.Tracker)
+type MissionConfig struct {
+	// File файл миссии в формате yaml или GeoJSON
+	File string `yaml:"file" mapstructure:"file"`
+	// ProgressFile файл прогресса, по которому миссия продолжается после перезапуска
+	ProgressFile string `yaml:"progress_file" mapstructure:"progress_file"`
+}
+
+func (c *MissionConfig) SetDefaults() {
+	if c.ProgressFile == "" && c.File != "" {
+		c.ProgressFile = c.File + ".progress.json"
+	}
+}
+
+const (
+	// HeadingSourceGNSS курс относительно грунта из сообщений ГНСС
+	HeadingSourceGNSS = "gnss"
+	// HeadingSourceIMU магнитный курс из сообщений IMU
+	HeadingSourceIMU = "imu"
+	// HeadingSourceNavigation курс и скорость навигационного решения фильтра ИНС и ГНСС
+	HeadingSourceNavigation = "navigation"
+	// HeadingSourceAHRS курс с компенсацией наклона из сообщений ориентации модуля навигации
+	HeadingSourceAHRS = "ahrs"
+)
+
+// HeadingSources допустимые источники курса
+var HeadingSources = []string{HeadingSourceGNSS, HeadingSourceIMU, HeadingSourceNavigation, HeadingSourceAHRS}
+
+// GuidanceConfig конфигурация регуляторов курса и скорости (см. guidance.Controller)
+type GuidanceConfig struct {
+	// Rate частота выдачи уставок в Гц
+	Rate float64 `yaml:"rate" mapstructure:"rate"`
+	// HeadingSource источник курса: gnss, imu, navigation или ahrs
+	HeadingSource string `yaml:"heading_source" mapstructure:"heading_source"`
+	// MinCourseSpeed скорость в м/с, ниже которой курс ГНСС не используется
+	MinCourseSpeed float64 `yaml:"min_course_speed" mapstructure:"min_course_speed"`
+	// Timeout время, после которого измерение положения, курса или скорости устаревает
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// ReturnSpeed скорость в м/с при возвращении в точку удержания
+	ReturnSpeed float64 `yaml:"return_speed" mapstructure:"return_speed"`
+	// HoldRadius радиус удержания в точке в метрах
+	HoldRadius float64 `yaml:"hold_radius" mapstructure:"hold_radius"`
+	// Heading регулятор курса, выход - угол пера руля в градусах
+	Heading PIDConfig `yaml:"heading" mapstructure:"heading"`
+	// Speed регулятор скорости, выход - тяга в процентах
+	Speed PIDConfig `yaml:"speed" mapstructure:"speed"`
+}
+
+func (c *GuidanceConfig) SetDefaults() {
+	if c.Rate == 0 {
+		c.Rate = guidance.DefaultRate
+	}
+
+	if c.HeadingSource == "" {
+		c.HeadingSource = HeadingSourceGNSS
+	}
+
+	if c.MinCourseSpeed == 0 {
+		c.MinCourseSpeed = guidance.DefaultMinCourseSpeed
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = guidance.DefaultTimeout
+	}
+
+	if c.ReturnSpeed == 0 {
+		c.ReturnSpeed = mission.DefaultSpeed
+	}
+
+	if c.HoldRadius == 0 {
+		c.HoldRadius = mission.DefaultAcceptanceRadius
+	}
+
+	if c.Heading.Min == 0 && c.Heading.Max == 0 {
+		c.Heading.Min, c.Heading.Max = -guidance.DefaultMaxRudder, guidance.DefaultMaxRudder
+	}
+
+	if c.Speed.Min == 0 && c.Speed.Max == 0 {
+		c.Speed.Max = guidance.DefaultMaxThrust
+	}
+}
+
+// PIDConfig коэффициенты и пределы ПИД-регулятора (см. guidance.PID)
+type PIDConfig struct {
+	Kp float64 `yaml:"kp" mapstructure:"kp"`
+	Ki float64 `yaml:"ki" mapstructure:"ki"`
+	Kd float64 `yaml:"kd" mapstructure:"kd"`
+	// Min, Max пределы выхода
+	Min float64 `yaml:"min" mapstructure:"min"`
+	Max float64 `yaml:"max" mapstructure:"max"`
+	// RateLimit наибольшая скорость изменения выхода в единицах в секунду, 0 - без ограничения
+	RateLimit float64 `yaml:"rate_limit" mapstructure:"rate_limit"`
+}
+
+// PID возвращает регулятор с коэффициентами и пределами конфигурации.
+func (c PIDConfig) PID() *guidance.PID {
+	return &guidance.PID{
+		Kp:        c.Kp,
+		Ki:        c.Ki,
+		Kd:        c.Kd,
+		Min:       c.Min,
+		Max:       c.Max,
+		RateLimit: c.RateLimit,
+	}
+}
+
+// Реакции на нарушение зон плавания
+const (
+	// GeofenceAlarm только событие и запись в лог
+	GeofenceAlarm = "alarm"
+	// GeofenceStop остановка исполнительных механизмов
+	GeofenceStop = "stop"
+	// GeofenceReturn возвращение в точку запуска (нужны guidance и модуль ГНСС)
+	GeofenceReturn = "rtl"
+	// GeofenceHold удержание в точке нарушения (нужен guidance)
+	GeofenceHold = "hold"
+)
+
+// GeofenceActions допустимые реакции на нарушение зон плавания
+var GeofenceActions = []string{GeofenceAlarm, GeofenceStop, GeofenceReturn, GeofenceHold}
+
+// GeofenceConfig конфигурация контроля зон плавания (см. geofence.Monitor)
+type GeofenceConfig struct {
+	// File файл зон в формате yaml или GeoJSON
+	File string `yaml:"file" mapstructure:"file"`
+	// Margin запас до границы зоны в метрах
+	Margin float64 `yaml:"margin" mapstructure:"margin"`
+	// Hysteresis гистерезис в метрах: нарушение завершается при запасе margin+hysteresis
+	Hysteresis float64 `yaml:"hysteresis" mapstructure:"hysteresis"`
+	// Action реакция на нарушение
+	Action string `yaml:"action" mapstructure:"action"`
+}
+
+func (c *GeofenceConfig) SetDefaults() {
+	if c.Margin == 0 {
+		c.Margin = geofence.DefaultMargin
+	}
+
+	if c.Hysteresis == 0 {
+		c.Hysteresis = geofence.DefaultHysteresis
+	}
+
+	if c.Action == "" {
+		c.Action = GeofenceAlarm
+	}
+}
+
+// NavigationConfig конфигурация фильтра ИНС и ГНСС (см. navfilter.Filter)
+type NavigationConfig struct {
+	// Rate частота публикации решения в Гц
+	Rate float64 `yaml:"rate" mapstructure:"rate"`
+	// Timeout время без решения ГНСС, после которого положение считается счисленным, и без
+	// измерений, после которого решение не публикуется
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// MinCourseSpeed скорость в м/с, ниже которой курс ГНСС не используется
+	MinCourseSpeed float64 `yaml:"min_course_speed" mapstructure:"min_course_speed"`
+	// GyroNoise, AccNoise шумы гироскопа в град/с/√Гц и акселерометров в м/с^2/√Гц
+	GyroNoise float64 `yaml:"gyro_noise" mapstructure:"gyro_noise"`
+	AccNoise  float64 `yaml:"acc_noise" mapstructure:"acc_noise"`
+	// GyroBiasNoise, AccBiasNoise случайное блуждание смещений нуля в град/с/√с и м/с^2/√с
+	GyroBiasNoise float64 `yaml:"gyro_bias_noise" mapstructure:"gyro_bias_noise"`
+	AccBiasNoise  float64 `yaml:"acc_bias_noise" mapstructure:"acc_bias_noise"`
+	// CourseNoise СКО курса ГНСС в градусах
+	CourseNoise float64 `yaml:"course_noise" mapstructure:"course_noise"`
+}
+
+func (c *NavigationConfig) SetDefaults() {
+	if c.Rate == 0 {
+		c.Rate = navfilter.DefaultRate
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = navfilter.DefaultTimeout
+	}
+
+	if c.MinCourseSpeed == 0 {
+		c.MinCourseSpeed = navfilter.DefaultMinCourseSpeed
+	}
+
+	if c.GyroNoise == 0 {
+		c.GyroNoise = navfilter.DefaultGyroNoise
+	}
+
+	if c.AccNoise == 0 {
+		c.AccNoise = navfilter.DefaultAccNoise
+	}
+
+	if c.GyroBiasNoise == 0 {
+		c.GyroBiasNoise = navfilter.DefaultGyroBiasNoise
+	}
+
+	if c.AccBiasNoise == 0 {
+		c.AccBiasNoise = navfilter.DefaultAccBiasNoise
+	}
+
+	if c.CourseNoise == 0 {
+		c.CourseNoise = navfilter.DefaultCourseNoise
+	}
+}
+
+// Noise возвращает шумы модели фильтра.
+func (c *NavigationConfig) Noise() navfilter.Noise {
+	return navfilter.Noise{
+		Gyro:     c.GyroNoise,
+		Acc:      c.AccNoise,
+		GyroBias: c.GyroBiasNoise,
+		AccBias:  c.AccBiasNoise,
+		Course:   c.CourseNoise,
+	}
+}
+
+// AHRSConfig конфигурация оценки ориентации (см. ahrs.Madgwick)
+type AHRSConfig struct {
+	// Beta коэффициент коррекции фильтра Маджвика в рад/с
+	Beta float64 `yaml:"beta" mapstructure:"beta"`
+	// Declination магнитное склонение в градусах, восточное положительное
+	Declination float64 `yaml:"declination" mapstructure:"declination"`
+}
+
+func (c *AHRSConfig) SetDefaults() {
+	if c.Beta == 0 {
+		c.Beta = ahrs.DefaultBeta
+	}
+}
+
+// Madgwick возвращает фильтр ориентации с параметрами конфигурации.
+func (c AHRSConfig) Madgwick() *ahrs.Madgwick {
+	return ahrs.NewMadgwick(c.Beta, c.Declination)
+}
+
+// Признаки аварии
+const (
+	// FailsafeLinkSilence нет подтвержденной пересылки по маршруту связи с sync
+	FailsafeLinkSilence = "link_silence"
+	// FailsafeGNSSLost нет решения ГНСС
+	FailsafeGNSSLost = "gnss_lost"
+	// FailsafeModuleSilent нет сообщений модуля
+	FailsafeModuleSilent = "module_silent"
+	// FailsafeLowBattery напряжение или заряд батареи ниже порога
+	FailsafeLowBattery = "low_battery"
+)
+
+// FailsafeTriggers допустимые признаки аварии
+var FailsafeTriggers = []string{FailsafeLinkSilence, FailsafeGNSSLost, FailsafeModuleSilent, FailsafeLowBattery}
+
+// FailsafeConfig конфигурация аварийных реакций (см. failsafe.Policy)
+type FailsafeConfig struct {
+	// Triggers признаки аварии; при нескольких сработавших действует реакция первого
+	Triggers []*FailsafeTriggerConfig `yaml:"triggers" mapstructure:"triggers"`
+}
+
+func (c *FailsafeConfig) SetDefaults() {
+	for _, t := range c.Triggers {
+		if t != nil {
+			t.SetDefaults()
+		}
+	}
+}
+
+// FailsafeTriggerConfig признак аварии и реакция на него
+type FailsafeTriggerConfig struct {
+	// Type признак: link_silence, gnss_lost, module_silent или low_battery
+	Type string `yaml:"type" mapstructure:"type"`
+	// Route маршрут, по которому проверяется связь (link_silence)
+	Route string `yaml:"route" mapstructure:"route"`
+	// Module модуль, молчание которого проверяется (module_silent)
+	Module string `yaml:"module" mapstructure:"module"`
+	// MaxHAcc наибольшая горизонтальная погрешность решения в метрах, 0 - не проверяется (gnss_lost)
+	MaxHAcc float64 `yaml:"max_hacc" mapstructure:"max_hacc"`
+	// MinVoltage наименьшее напряжение в В, MinCharge наименьший заряд в процентах, 0 - не
+	// проверяется (low_battery)
+	MinVoltage float64 `yaml:"min_voltage" mapstructure:"min_voltage"`
+	MinCharge  float64 `yaml:"min_charge" mapstructure:"min_charge"`
+	// Timeout время без подтверждения нормального состояния, после которого признак срабатывает
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// Response реакция: hold, drift или rtl
+	Response string `yaml:"response" mapstructure:"response"`
+	// Latch сработавший признак не восстанавливается до перезапуска контроллера
+	Latch bool `yaml:"latch" mapstructure:"latch"`
+}
+
+func (c *FailsafeTriggerConfig) SetDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = failsafe.DefaultTimeout
+	}
+}
+
+// Name возвращает имя признака для журнала и событий.
+func (c *FailsafeTriggerConfig) Name() string {
+	switch {
+	case c.Route != "":
+		return c.Type + "/" + c.Route
+	case c.Module != "":
+		return c.Type + "/" + c.Module
+	default:
+		return c.Type
+	}
+}
+
+// StateConfig конфигурация хранилища состояния (см. state.Store)
+type StateConfig struct {
+	// HistorySize количество хранимых значений каждого сигнала, 0 - по умолчанию
+	HistorySize int `yaml:"history_size" mapstructure:"history_size"`
+	// StaleAfter время без сообщений, после которого сигнал устаревает, 0 - по умолчанию
+	StaleAfter time.Duration `yaml:"stale_after" mapstructure:"stale_after"`
+}
+
+// SessionsConfig конфигурация сессий записи (см. session)
+type SessionsConfig struct {
+	Dir string `yaml:"dir" mapstructure:"dir"`
+	// MinFree минимальное свободное место на диске в байтах, при нехватке удаляются самые
+	// старые сессии; 0 - сессии не удаляются
+	MinFree uint64 `yaml:"min_free" mapstructure:"min_free"`
+	// RetentionInterval период проверки свободного места
+	RetentionInterval time.Duration `yaml:"retention_interval" mapstructure:"retention_interval"`
+}
+
+func (c *SessionsConfig) SetDefaults() {
+	if c.Dir == "" {
+		c.Dir = DefaultSessionsDir
+	}
+
+	if c.RetentionInterval == 0 {
+		c.RetentionInterval = session.DefaultRetentionInterval
+	}
+}
+
+// RecordingConfig конфигурация записи принятых фреймов в файлы (см. recording.Writer)
+type RecordingConfig struct {
+	Dir    string `yaml:"dir" mapstructure:"dir"`
+	Prefix string `yaml:"prefix" mapstructure:"prefix"`
+	// MaxSize максимальный размер файла в байтах, 0 - без ограничения
+	MaxSize int64 `yaml:"max_size" mapstructure:"max_size"`
+	// MaxDuration максимальная длительность записи одного файла, 0 - без ограничения
+	MaxDuration   time.Duration `yaml:"max_duration" mapstructure:"max_duration"`
+	IndexInterval time.Duration `yaml:"index_interval" mapstructure:"index_interval"`
+	SyncInterval  time.Duration `yaml:"sync_interval" mapstructure:"sync_interval"`
+}
+
+func (c *RecordingConfig) SetDefaults() {
+	if c.Dir == "" {
+		c.Dir = "."
+	}
+
+	if c.Prefix == "" {
+		c.Prefix = DefaultRecordingPrefix
+	}
+
+	if c.IndexInterval == 0 {
+		c.IndexInterval = recording.DefaultIndexInterval
+	}
+
+	if c.SyncInterval == 0 {
+		c.SyncInterval = recording.DefaultSyncInterval
+	}
+}
+
+// HandlerConfig конфигурация обработчика полученных сообщений
+type HandlerConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	// Type тип обработчика: log, save-image, alarm
+	Type string `yaml:"type" mapstructure:"type"`
+	// Modules имена модулей, сообщения которых обрабатываются; пустой список - все модули
+	Modules []string `yaml:"modules" mapstructure:"modules"`
+	// Messages идентификаторы обрабатываемых сообщений; пустой список - все сообщения
+	Messages  []uint8 `yaml:"messages" mapstructure:"messages"`
+	QueueSize int     `yaml:"queue_size" mapstructure:"queue_size"`
+	// Params параметры, зависящие от типа обработчика
+	Params map[string]any `yaml:"params" mapstructure:"params"`
+}
+
+// ModuleIDs возвращает идентификаторы модулей обработчика.
+func (c HandlerConfig) ModuleIDs() ([]proto.ModuleID, error) {
+	return ModuleIDs(c.Modules)
+}
+
+// RouteConfig конфигурация маршрута пересылки полученных сообщений
+type RouteConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	// Modules имена модулей, сообщения которых пересылаются; пустой список - все модули
+	Modules []string `yaml:"modules" mapstructure:"modules"`
+	// Messages идентификаторы пересылаемых сообщений; пустой список - все сообщения с данными
+	Messages []uint8 `yaml:"messages" mapstructure:"messages"`
+	// Rate максимальная частота пересылки сообщений каждого модуля в секунду, 0 - без ограничения
+	Rate float64 `yaml:"rate" mapstructure:"rate"`
+	// Reencode идентификатор сообщения, в режиме которого перекодируются пересылаемые сообщения
+	Reencode    uint8             `yaml:"reencode" mapstructure:"reencode"`
+	QueueSize   int               `yaml:"queue_size" mapstructure:"queue_size"`
+	Destination *SerialPortConfig `yaml:"destination" mapstructure:"destination"`
+}
+
+// ModuleIDs возвращает идентификаторы модулей маршрута.
+func (c RouteConfig) ModuleIDs() ([]proto.ModuleID, error) {
+	return ModuleIDs(c.Modules)
 }
 
 type ModuleConnectionConfig struct {
 	Listener *SerialPortConfig `yaml:"listener" mapstructure:"listener"`
 	Enabled  bool              `yaml:"enabled" mapstructure:"enabled"`
+	// Modules имена модулей, разделяющих канал связи listener (см. proto.ModuleIDByName).
+	// Пустой список означает канал связи одного модуля без проверки ModuleID.
+	Modules []string `yaml:"modules" mapstructure:"modules"`
+	// Watchdog контроль молчания модуля, nil - не контролируется
+	Watchdog *WatchdogConfig `yaml:"watchdog" mapstructure:"watchdog"`
+}
+
+// Действия при молчании и восстановлении модуля
+const (
+	// WatchdogLog запись в лог
+	WatchdogLog = "log"
+	// WatchdogAlarm сообщение о событии контроллера (proto.EventModuleSilent)
+	WatchdogAlarm = "alarm"
+	// WatchdogFailsafe уставка аварийного режима исполнительным механизмам при молчании
+	WatchdogFailsafe = "failsafe"
+	// WatchdogReopen переоткрытие порта модуля при молчании
+	WatchdogReopen = "reopen"
+)
+
+// WatchdogActions допустимые действия контроля молчания
+var WatchdogActions = []string{WatchdogLog, WatchdogAlarm, WatchdogFailsafe, WatchdogReopen}
+
+// WatchdogConfig конфигурация контроля молчания модуля (см. watchdog.Watchdog)
+type WatchdogConfig struct {
+	// ExpectedRate ожидаемая частота сообщений в секунду
+	ExpectedRate float64 `yaml:"expected_rate" mapstructure:"expected_rate"`
+	// Timeout время без сообщений, после которого модуль считается молчащим; 0 - три периода
+	// ожидаемой частоты
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// Actions действия при молчании и восстановлении, по умолчанию только запись в лог
+	Actions []string `yaml:"actions" mapstructure:"actions"`
+	// Failsafe уставка действия failsafe, по умолчанию остановка с прямым рулем
+	Failsafe SetpointConfig `yaml:"failsafe" mapstructure:"failsafe"`
+}
+
+func (c *WatchdogConfig) SetDefaults() {
+	if len(c.Actions) == 0 {
+		c.Actions = []string{WatchdogLog}
+	}
+}
+
+// SetpointConfig уставка исполнительных механизмов
+type SetpointConfig struct {
+	// Thrust тяга в процентах от максимальной, отрицательная - задний ход
+	Thrust float64 `yaml:"thrust" mapstructure:"thrust"`
+	// Rudder угол пера руля в градусах, положительный - вправо
+	Rudder float64 `yaml:"rudder" mapstructure:"rudder"`
+}
+
+// ControlData возвращает уставку в единицах протокола от источника source.
+func (c SetpointConfig) ControlData(source uint8) *proto.ControlData {
+	return &proto.ControlData{
+		Thrust: int16(math.Round(c.Thrust * 10)),
+		Rudder: int16(math.Round(c.Rudder * 100)),
+		Source: source,
+	}
+}
+
+// ModuleIDs возвращает идентификаторы модулей, разделяющих канал связи.
+func (c ModuleConnectionConfig) ModuleIDs() ([]proto.ModuleID, error) {
+	return ModuleIDs(c.Modules)
+}
+
+// ModuleIDs возвращает идентификаторы модулей по их именам (см. proto.ModuleIDByName).
+func ModuleIDs(names []string) ([]proto.ModuleID, error) {
+	ids := make([]proto.ModuleID, 0, len(names))
+
+	for _, name := range names {
+		id, ok := proto.ModuleIDByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown module %q", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// MuxConfig конфигурация мультиплексора: общий канал связи с контроллером и входы модулей
+type MuxConfig struct {
+	Uplink *SerialPortConfig          `yaml:"uplink" mapstructure:"uplink"`
+	Inputs map[string]*MuxInputConfig `yaml:"inputs" mapstructure:"inputs"`
 }
 
+type MuxInputConfig struct {
+	// Listener канал связи с модулем, Sync включает отправку подтверждений модулю
+	Listener *SerialPortConfig `yaml:"listener" mapstructure:"listener"`
+	// Priority приоритет входа, фреймы входов с большим приоритетом передаются раньше
+	Priority int `yaml:"priority" mapstructure:"priority"`
+	// Weight доля пропускной способности среди входов с одинаковым приоритетом
+	Weight    int `yaml:"weight" mapstructure:"weight"`
+	QueueSize int `yaml:"queue_size" mapstructure:"queue_size"`
+}
+
+// SerialPortConfig конфигурация канала связи. Поле Port содержит либо путь до последовательного
+// порта, либо адрес канала в виде URL (см. transport.Endpoint).
 type SerialPortConfig struct {
-	serialport.Config
+	serialport.Config `yaml:",inline" mapstructure:",squash"`
 	// Sync флаг включения функционала гарантированной доставки сообщений. В случае конфига
 	// сервера - будут отправляться ok-сообщения, в случае конфига клиента - будет ожидание
 	// ok-сообщения от сервера.
-	Sync                 bool `yaml:"sync" mapstructure:"sync"`
-	ChunkSize            int  `yaml:"chunk_size" mapstructure:"chunk_size"`
-	RetriesLimit         int  `yaml:"retries_limit" mapstructure:"retries_limit"`
-	TransmittingDisabled bool
-	Sleep                time.Duration
+	Sync         bool `yaml:"sync" mapstructure:"sync"`
+	ChunkSize    int  `yaml:"chunk_size" mapstructure:"chunk_size"`
+	RetriesLimit int  `yaml:"retries_limit" mapstructure:"retries_limit"`
+	// Budget бюджет линии в байт/с: 0 - вычисляется по скорости последовательного порта,
+	// отрицательное значение отключает ограничение (см. communication.LinkBudget)
+	Budget int `yaml:"budget" mapstructure:"budget"`
+	// StatsInterval период вывода статистики канала связи в лог, отрицательное значение
+	// отключает вывод
+	StatsInterval time.Duration `yaml:"stats_interval" mapstructure:"stats_interval"`
+	// StatsReport флаг отправки статистики канала связи получателю
+	StatsReport          bool          `yaml:"stats_report" mapstructure:"stats_report"`
+	TransmittingDisabled bool          `yaml:"transmitting_disabled" mapstructure:"transmitting_disabled"`
+	Sleep                time.Duration `yaml:"sleep" mapstructure:"sleep"`
 }
 
 func (c *SerialPortConfig) SetDefaults() {
@@ -48,6 +629,10 @@ func (c *SerialPortConfig) SetDefaults() {
 	if c.RetriesLimit == 0 {
 		c.RetriesLimit = communication.DefaultRetriesLimit
 	}
+
+	if c.StatsInterval == 0 {
+		c.StatsInterval = linkstats.DefaultLogInterval
+	}
 }
 
 func (c SerialPortConfig) String() string {
@@ -57,62 +642,262 @@ func (c SerialPortConfig) String() string {
 	)
 }
 
-func (c SerialPortConfig) Short() serialport.Config {
-	return serialport.Config{
-		Port:     c.Port,
+// LinkBudget возвращает бюджет линии в байт/с, 0 означает отсутствие ограничения.
+func (c SerialPortConfig) LinkBudget() (budget, baudRate int, err error) {
+	ep, err := c.Endpoint()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if ep.Scheme == transport.SchemeSerial {
+		baudRate = ep.BaudRate
+	}
+
+	return communication.LinkBudget(baudRate, c.Budget), baudRate, nil
+}
+
+// Endpoint возвращает адрес канала связи. Скорость и таймаут, не указанные в адресе,
+// берутся из BaudRate и Timeout.
+func (c SerialPortConfig) Endpoint() (transport.Endpoint, error) {
+	return transport.ParseEndpoint(c.Port, transport.Endpoint{
 		BaudRate: c.BaudRate,
 		Timeout:  c.Timeout,
-	}
+	})
 }
 
 type NeoM8tConfig struct {
 	// Rate период получения навигационного решения в секундах
-	Rate int
+	Rate int `yaml:"rate" mapstructure:"rate"`
 }
 
 type SenseHATConfig struct {
-	Period time.Duration
-	Mode   string
-	Acc    SenseHATSensorConfig
-	Gyr    SenseHATSensorConfig
-	Mag    SenseHATSensorConfig
+	Period time.Duration        `yaml:"period" mapstructure:"period"`
+	Mode   string               `yaml:"mode" mapstructure:"mode"`
+	Acc    SenseHATSensorConfig `yaml:"acc" mapstructure:"acc"`
+	Gyr    SenseHATSensorConfig `yaml:"gyr" mapstructure:"gyr"`
+	Mag    SenseHATSensorConfig `yaml:"mag" mapstructure:"mag"`
+	// AHRS оценка ориентации в режиме ahrs
+	AHRS AHRSConfig `yaml:"ahrs" mapstructure:"ahrs"`
 }
 
 type SenseHATSensorConfig struct {
-	Order        float32
-	Range        int
-	RemoveOffset bool
+	Order        float32 `yaml:"order" mapstructure:"order"`
+	Range        int     `yaml:"range" mapstructure:"range"`
+	RemoveOffset bool    `yaml:"remove_offset" mapstructure:"remove_offset"`
 }
 
 func NewControllerConfig(cfgPath string) (*ControllerConfig, error) {
+	cfg, err := ReadControllerConfig(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Validate().Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ReadControllerConfig читает конфигурацию контроллера и устанавливает значения по умолчанию
+// без проверки правил (см. ControllerConfig.Validate). Ключи, отсутствующие в ControllerConfig,
+// считаются ошибкой.
+func ReadControllerConfig(cfgPath string) (*ControllerConfig, error) {
+	var cfg ControllerConfig
+
+	err := readYAML(cfgPath, &cfg, viper.DecoderConfigOption(func(c *mapstructure.DecoderConfig) {
+		c.ErrorUnused = true
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cfg.Modules {
+		if c == nil || c.Listener == nil {
+			continue
+		}
+
+		c.Listener.SetDefaults()
+
+		if c.Watchdog != nil {
+			c.Watchdog.SetDefaults()
+		}
+	}
+
+	for _, c := range cfg.Routes {
+		if c == nil || c.Destination == nil {
+			continue
+		}
+
+		c.Destination.SetDefaults()
+	}
+
+	if cfg.Recording != nil {
+		cfg.Recording.SetDefaults()
+	}
+
+	if cfg.Sessions != nil {
+		cfg.Sessions.SetDefaults()
+	}
+
+	if cfg.API != nil {
+		cfg.API.SetDefaults()
+	}
+
+	if cfg.Metrics != nil {
+		cfg.Metrics.SetDefaults()
+	}
+
+	if cfg.Mission != nil {
+		cfg.Mission.SetDefaults()
+	}
+
+	if cfg.Guidance != nil {
+		cfg.Guidance.SetDefaults()
+	}
+
+	if cfg.Geofence != nil {
+		cfg.Geofence.SetDefaults()
+	}
+
+	if cfg.Failsafe != nil {
+		cfg.Failsafe.SetDefaults()
+	}
+
+	if cfg.Navigation != nil {
+		cfg.Navigation.SetDefaults()
+	}
+
+	if cfg.AHRS != nil {
+		cfg.AHRS.SetDefaults()
+	}
+
+	return &cfg, nil
+}
+
+// EnvPrefix префикс переменных окружения конфигурации модулей: ключ controller.retries_limit
+// задается переменной ASVSOFT_CONTROLLER_RETRIES_LIMIT
+const EnvPrefix = "ASVSOFT"
+
+// LoadModuleConfig загружает конфигурацию модуля в cfg. Значение каждого ключа берется
+// по приоритету: измененный флаг из flags, переменная окружения, yaml-файл cfgPath (если
+// задан), значение флага по умолчанию. Флаги связываются с ключами через keys (имя флага -
+// ключ вида controller.port). Неизвестные ключи файла считаются ошибкой.
+func LoadModuleConfig(cfgPath string, flags *pflag.FlagSet, keys map[string]string, cfg *ModuleConfig) error {
 	v := viper.New()
 
-	if cfgPath == "" {
-		return nil, fmt.Errorf("config path is empty")
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for name, key := range keys {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("flag %q of config key %q is not defined", name, key)
+		}
+
+		err := v.BindPFlag(key, flag)
+		if err != nil {
+			return err
+		}
 	}
 
-	if !strings.HasSuffix(cfgPath, ".yaml") {
-		return nil, fmt.Errorf("config file type must be yaml")
+	if cfgPath != "" {
+		if !strings.HasSuffix(cfgPath, ".yaml") {
+			return fmt.Errorf("config file type must be yaml")
+		}
+
+		v.SetConfigType("yaml")
+		v.SetConfigFile(cfgPath)
+
+		err := v.ReadInConfig()
+		if err != nil {
+			return fmt.Errorf("failed to read in config: %w", err)
+		}
 	}
 
-	v.SetConfigType("yaml")
-	v.SetConfigFile(cfgPath)
+	err := v.Unmarshal(cfg, func(c *mapstructure.DecoderConfig) {
+		c.ErrorUnused = true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
 
-	err := v.ReadInConfig()
+	return nil
+}
+
+// WatchControllerConfig отслеживает изменения файла конфигурации контроллера cfgPath и вызывает
+// onChange с новой конфигурацией или ошибкой ее чтения. Отслеживание прекращается при отмене
+// контекста или удалении файла.
+func WatchControllerConfig(ctx context.Context, cfgPath string, onChange func(cfg *ControllerConfig, err error)) error {
+	absPath, err := filepath.Abs(cfgPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read in config: %w", err)
+		return err
 	}
 
-	var cfg ControllerConfig
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
 
-	err = v.Unmarshal(&cfg)
+	// редакторы заменяют файл новым, поэтому отслеживается каталог файла
+	err = watcher.Add(filepath.Dir(absPath))
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+		_ = watcher.Close()
+		return err
 	}
 
-	for _, c := range cfg.Modules {
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if filepath.Clean(event.Name) != absPath {
+					continue
+				}
+
+				switch {
+				case event.Has(fsnotify.Write) || event.Has(fsnotify.Create):
+					onChange(NewControllerConfig(cfgPath))
+				case event.Has(fsnotify.Remove):
+					return
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func NewMuxConfig(cfgPath string) (*MuxConfig, error) {
+	var cfg MuxConfig
+
+	err := readYAML(cfgPath, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Uplink == nil {
+		return nil, fmt.Errorf("uplink is not configured")
+	}
+
+	cfg.Uplink.SetDefaults()
+
+	for name, c := range cfg.Inputs {
 		if c.Listener == nil {
-			continue
+			return nil, fmt.Errorf("listener of input %q is not configured", name)
 		}
 
 		c.Listener.SetDefaults()
@@ -120,3 +905,31 @@ func NewControllerConfig(cfgPath string) (*ControllerConfig, error) {
 
 	return &cfg, nil
 }
+
+// readYAML читает yaml-файл конфигурации cfgPath в cfg с параметрами декодирования opts.
+func readYAML(cfgPath string, cfg any, opts ...viper.DecoderConfigOption) error {
+	v := viper.New()
+
+	if cfgPath == "" {
+		return fmt.Errorf("config path is empty")
+	}
+
+	if !strings.HasSuffix(cfgPath, ".yaml") {
+		return fmt.Errorf("config file type must be yaml")
+	}
+
+	v.SetConfigType("yaml")
+	v.SetConfigFile(cfgPath)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read in config: %w", err)
+	}
+
+	err = v.Unmarshal(cfg, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"maps"
+	"slices"
+)
+
+// Equal сообщает, совпадают ли настройки канала связи модуля.
+func (c *ModuleConnectionConfig) Equal(other *ModuleConnectionConfig) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+
+	return c.Enabled == other.Enabled &&
+		equalPtr(c.Listener, other.Listener) &&
+		slices.Equal(c.Modules, other.Modules) &&
+		c.Watchdog.Equal(other.Watchdog)
+}
+
+// Equal сообщает, совпадают ли настройки контроля молчания.
+func (c *WatchdogConfig) Equal(other *WatchdogConfig) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+
+	return c.ExpectedRate == other.ExpectedRate &&
+		c.Timeout == other.Timeout &&
+		slices.Equal(c.Actions, other.Actions) &&
+		c.Failsafe == other.Failsafe
+}
+
+// Equal сообщает, совпадают ли настройки маршрута.
+func (c *RouteConfig) Equal(other *RouteConfig) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+
+	return c.Name == other.Name &&
+		slices.Equal(c.Modules, other.Modules) &&
+		slices.Equal(c.Messages, other.Messages) &&
+		c.Rate == other.Rate &&
+		c.Reencode == other.Reencode &&
+		c.QueueSize == other.QueueSize &&
+		equalPtr(c.Destination, other.Destination)
+}
+
+// Equal сообщает, совпадают ли настройки обработчика, включая параметры.
+func (c *HandlerConfig) Equal(other *HandlerConfig) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+
+	return c.Name == other.Name &&
+		c.Type == other.Type &&
+		slices.Equal(c.Modules, other.Modules) &&
+		slices.Equal(c.Messages, other.Messages) &&
+		c.QueueSize == other.QueueSize &&
+		equalParams(c.Params, other.Params)
+}
+
+// Equal сообщает, совпадают ли признаки аварийных реакций.
+func (c *FailsafeConfig) Equal(other *FailsafeConfig) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+
+	return slices.EqualFunc(c.Triggers, other.Triggers, equalPtr[FailsafeTriggerConfig])
+}
+
+// EqualExceptModules сообщает, совпадают ли конфигурации без учета модулей.
+func (c *ControllerConfig) EqualExceptModules(other *ControllerConfig) bool {
+	return slices.EqualFunc(c.Routes, other.Routes, (*RouteConfig).Equal) &&
+		slices.EqualFunc(c.Handlers, other.Handlers, (*HandlerConfig).Equal) &&
+		equalPtr(c.Recording, other.Recording) &&
+		equalPtr(c.Sessions, other.Sessions) &&
+		equalPtr(c.State, other.State) &&
+		equalPtr(c.API, other.API) &&
+		equalPtr(c.Metrics, other.Metrics) &&
+		equalPtr(c.Mission, other.Mission) &&
+		equalPtr(c.Guidance, other.Guidance) &&
+		equalPtr(c.Geofence, other.Geofence) &&
+		c.Failsafe.Equal(other.Failsafe) &&
+		equalPtr(c.Navigation, other.Navigation) &&
+		equalPtr(c.AHRS, other.AHRS)
+}
+
+// equalPtr сравнивает значения секций конфигурации, состоящих только из сравнимых полей.
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// equalParams сравнивает параметры обработчиков, прочитанные из yaml.
+func equalParams(a, b map[string]any) bool {
+	return maps.EqualFunc(a, b, equalParam)
+}
+
+func equalParam(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		return ok && equalParams(a, b)
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equalParam)
+	default:
+		return a == b
+	}
+}
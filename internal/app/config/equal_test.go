@@ -0,0 +1,80 @@
+package config
+
+import (
+	serialport "asvsoft/internal/pkg/serial-port"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleConnectionConfigEqual(t *testing.T) {
+	newConfig := func() *ModuleConnectionConfig {
+		return &ModuleConnectionConfig{
+			Enabled:  true,
+			Listener: &SerialPortConfig{Config: serialport.Config{Port: "/dev/ttyUSB0", BaudRate: 9600}},
+			Modules:  []string{"gnss", "imu"},
+			Watchdog: &WatchdogConfig{Timeout: time.Second, Actions: []string{WatchdogLog}},
+		}
+	}
+
+	t.Run("одинаковые настройки", func(t *testing.T) {
+		require.True(t, newConfig().Equal(newConfig()))
+	})
+
+	t.Run("пустой и отсутствующий список модулей", func(t *testing.T) {
+		a, b := newConfig(), newConfig()
+		a.Modules, b.Modules = nil, []string{}
+
+		require.True(t, a.Equal(b))
+	})
+
+	for name, change := range map[string]func(c *ModuleConnectionConfig){
+		"скорости порта":     func(c *ModuleConnectionConfig) { c.Listener.BaudRate = 19200 },
+		"модулей канала":     func(c *ModuleConnectionConfig) { c.Modules = []string{"gnss"} },
+		"действий":           func(c *ModuleConnectionConfig) { c.Watchdog.Actions = []string{WatchdogAlarm} },
+		"контроля молчания":  func(c *ModuleConnectionConfig) { c.Watchdog = nil },
+		"признака включения": func(c *ModuleConnectionConfig) { c.Enabled = false },
+	} {
+		t.Run("изменение "+name, func(t *testing.T) {
+			changed := newConfig()
+			change(changed)
+
+			require.False(t, newConfig().Equal(changed))
+		})
+	}
+}
+
+func TestControllerConfigEqualExceptModules(t *testing.T) {
+	newConfig := func() *ControllerConfig {
+		return &ControllerConfig{
+			Modules: map[string]*ModuleConnectionConfig{"gnss": {Enabled: true}},
+			Handlers: []*HandlerConfig{{
+				Type:   "alarm",
+				Params: map[string]any{"field": "Distance", "min": 150, "levels": []any{1, 2}},
+			}},
+			API: &APIConfig{Listen: "localhost:8080"},
+		}
+	}
+
+	t.Run("изменены только модули", func(t *testing.T) {
+		changed := newConfig()
+		changed.Modules = nil
+
+		require.True(t, newConfig().EqualExceptModules(changed))
+	})
+
+	t.Run("изменены параметры обработчика", func(t *testing.T) {
+		changed := newConfig()
+		changed.Handlers[0].Params["levels"] = []any{1, 3}
+
+		require.False(t, newConfig().EqualExceptModules(changed))
+	})
+
+	t.Run("изменен адрес api", func(t *testing.T) {
+		changed := newConfig()
+		changed.API = nil
+
+		require.False(t, newConfig().EqualExceptModules(changed))
+	})
+}
@@ -0,0 +1,520 @@
+package config
+
+import (
+	"asvsoft/internal/pkg/failsafe"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/transport"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BaudRates допустимые скорости последовательного порта в бит/с
+var BaudRates = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+// Problem нарушение правила конфигурации в поле Path (например, modules.gnss.listener.timeout)
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// Validation результат проверки конфигурации. Ошибки делают конфигурацию непригодной к работе,
+// предупреждения указывают на вероятно ошибочные настройки.
+type Validation struct {
+	Errors   []Problem
+	Warnings []Problem
+}
+
+func (v *Validation) errorf(path, format string, args ...any) {
+	v.Errors = append(v.Errors, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *Validation) warnf(path, format string, args ...any) {
+	v.Warnings = append(v.Warnings, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err возвращает ошибку со списком всех нарушений или nil, если ошибок нет.
+func (v *Validation) Err() error {
+	if len(v.Errors) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(v.Errors))
+	for _, p := range v.Errors {
+		lines = append(lines, p.String())
+	}
+
+	return fmt.Errorf("invalid config:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// Validate проверяет конфигурацию контроллера после установки значений по умолчанию.
+// Неизвестные ключи обнаруживаются при чтении файла (см. ReadControllerConfig). Файлы миссии
+// и зон плавания не читаются, их содержимое проверяется при загрузке.
+func (c *ControllerConfig) Validate() *Validation {
+	v := &Validation{}
+
+	// ports занятые каналы связи: адрес - путь к полю, в котором он указан
+	ports := make(map[string]string)
+
+	for _, name := range slices.Sorted(maps.Keys(c.Modules)) {
+		m := c.Modules[name]
+		path := "modules." + name
+
+		if m == nil || m.Listener == nil {
+			v.errorf(path+".listener", "listener is not configured")
+			continue
+		}
+
+		validateModuleNames(v, path+".modules", m.Modules)
+
+		if m.Watchdog != nil {
+			validateWatchdog(v, path+".watchdog", m.Watchdog)
+		}
+
+		if m.Enabled {
+			validatePort(v, path+".listener", m.Listener, ports)
+		} else {
+			validatePort(v, path+".listener", m.Listener, nil)
+		}
+	}
+
+	for i, r := range c.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+
+		if r == nil {
+			v.errorf(path, "route is empty")
+			continue
+		}
+
+		validateModuleNames(v, path+".modules", r.Modules)
+
+		if r.Destination == nil {
+			v.errorf(path+".destination", "destination is not configured")
+		} else {
+			validatePort(v, path+".destination", r.Destination, ports)
+		}
+
+		if r.Rate < 0 {
+			v.errorf(path+".rate", "must not be negative, got %v", r.Rate)
+		}
+
+		if r.QueueSize < 0 {
+			v.errorf(path+".queue_size", "must not be negative, got %d", r.QueueSize)
+		}
+	}
+
+	for i, h := range c.Handlers {
+		path := fmt.Sprintf("handlers[%d]", i)
+
+		if h == nil {
+			v.errorf(path, "handler is empty")
+			continue
+		}
+
+		if h.Type == "" {
+			v.errorf(path+".type", "type of handler %q is not configured", h.Name)
+		}
+
+		validateModuleNames(v, path+".modules", h.Modules)
+
+		if h.QueueSize < 0 {
+			v.errorf(path+".queue_size", "must not be negative, got %d", h.QueueSize)
+		}
+	}
+
+	if r := c.Recording; r != nil {
+		if r.MaxSize < 0 {
+			v.errorf("recording.max_size", "must not be negative, got %d", r.MaxSize)
+		}
+
+		validateDuration(v, "recording.max_duration", r.MaxDuration)
+		validateDuration(v, "recording.index_interval", r.IndexInterval)
+		validateDuration(v, "recording.sync_interval", r.SyncInterval)
+	}
+
+	if s := c.Sessions; s != nil {
+		validateDuration(v, "sessions.retention_interval", s.RetentionInterval)
+
+		if s.MinFree == 0 {
+			v.warnf("sessions.min_free", "not set, old sessions are never deleted")
+		}
+	}
+
+	if st := c.State; st != nil {
+		if st.HistorySize < 0 {
+			v.errorf("state.history_size", "must not be negative, got %d", st.HistorySize)
+		}
+
+		validateDuration(v, "state.stale_after", st.StaleAfter)
+	}
+
+	if a := c.API; a != nil {
+		host, _, err := net.SplitHostPort(a.Listen)
+		switch {
+		case err != nil:
+			v.errorf("api.listen", "%v", err)
+		case !isLoopback(host):
+			v.warnf("api.listen", "%s is reachable from other hosts, api has no authentication", a.Listen)
+		}
+	}
+
+	if m := c.Metrics; m != nil {
+		_, _, err := net.SplitHostPort(m.Listen)
+		switch {
+		case err != nil:
+			v.errorf("metrics.listen", "%v", err)
+		case c.API != nil && c.API.Listen == m.Listen:
+			v.errorf("metrics.listen", "%s is already used by api", m.Listen)
+		}
+	}
+
+	if m := c.Mission; m != nil {
+		if m.File == "" {
+			v.errorf("mission.file", "mission file is required")
+		}
+	}
+
+	if g := c.Guidance; g != nil {
+		if c.Mission == nil {
+			v.errorf("guidance", "mission is required")
+		}
+
+		validateGuidance(v, "guidance", g)
+	}
+
+	if g := c.Geofence; g != nil {
+		validateGeofence(v, "geofence", g)
+
+		if (g.Action == GeofenceReturn || g.Action == GeofenceHold) && c.Guidance == nil {
+			v.errorf("geofence.action", "%s requires guidance", g.Action)
+		}
+
+		if g.Action == GeofenceReturn {
+			c.validateLaunch(v, "geofence.action", g.Action)
+		}
+	}
+
+	if f := c.Failsafe; f != nil {
+		c.validateFailsafe(v, "failsafe", f)
+	}
+
+	if n := c.Navigation; n != nil {
+		validateNavigation(v, "navigation", n)
+	}
+
+	if a := c.AHRS; a != nil {
+		validateAHRS(v, "ahrs", a)
+	}
+
+	if g := c.Guidance; g != nil {
+		switch {
+		case g.HeadingSource == HeadingSourceNavigation && c.Navigation == nil:
+			v.errorf("guidance.heading_source", "%s requires navigation", HeadingSourceNavigation)
+		case g.HeadingSource == HeadingSourceAHRS && c.AHRS == nil && c.Modules["navigation"] == nil:
+			v.errorf("guidance.heading_source", "%s requires ahrs or navigation module", HeadingSourceAHRS)
+		}
+	}
+
+	return v
+}
+
+func validateWatchdog(v *Validation, path string, w *WatchdogConfig) {
+	if w.ExpectedRate < 0 {
+		v.errorf(path+".expected_rate", "must not be negative, got %v", w.ExpectedRate)
+	}
+
+	if w.Timeout < 0 {
+		v.errorf(path+".timeout", "must not be negative, got %v", w.Timeout)
+	}
+
+	if w.Timeout == 0 && w.ExpectedRate == 0 {
+		v.errorf(path, "timeout or expected_rate is required")
+	}
+
+	for i, a := range w.Actions {
+		if !slices.Contains(WatchdogActions, a) {
+			v.errorf(fmt.Sprintf("%s.actions[%d]", path, i), "unknown action %q, expected one of %v", a, WatchdogActions)
+		}
+	}
+
+	validateSetpoint(v, path+".failsafe", w.Failsafe)
+}
+
+func validateGuidance(v *Validation, path string, g *GuidanceConfig) {
+	if g.Rate <= 0 {
+		v.errorf(path+".rate", "must be positive, got %v", g.Rate)
+	}
+
+	if !slices.Contains(HeadingSources, g.HeadingSource) {
+		v.errorf(path+".heading_source", "unknown source %q, expected one of %v", g.HeadingSource, HeadingSources)
+	}
+
+	if g.MinCourseSpeed < 0 {
+		v.errorf(path+".min_course_speed", "must not be negative, got %v", g.MinCourseSpeed)
+	}
+
+	validateDuration(v, path+".timeout", g.Timeout)
+
+	if g.ReturnSpeed < 0 {
+		v.errorf(path+".return_speed", "must not be negative, got %v", g.ReturnSpeed)
+	}
+
+	if g.HoldRadius < 0 {
+		v.errorf(path+".hold_radius", "must not be negative, got %v", g.HoldRadius)
+	}
+
+	validatePID(v, path+".heading", g.Heading)
+	validatePID(v, path+".speed", g.Speed)
+
+	if math.Abs(g.Heading.Min) > 90 || math.Abs(g.Heading.Max) > 90 {
+		v.errorf(path+".heading", "limits must be within [-90, 90] deg, got [%v, %v]", g.Heading.Min, g.Heading.Max)
+	}
+
+	if math.Abs(g.Speed.Min) > 100 || math.Abs(g.Speed.Max) > 100 {
+		v.errorf(path+".speed", "limits must be within [-100, 100] %%, got [%v, %v]", g.Speed.Min, g.Speed.Max)
+	}
+}
+
+func validateGeofence(v *Validation, path string, g *GeofenceConfig) {
+	if g.File == "" {
+		v.errorf(path+".file", "geofence file is required")
+	}
+
+	if g.Margin < 0 {
+		v.errorf(path+".margin", "must not be negative, got %v", g.Margin)
+	}
+
+	if g.Hysteresis < 0 {
+		v.errorf(path+".hysteresis", "must not be negative, got %v", g.Hysteresis)
+	}
+
+	if !slices.Contains(GeofenceActions, g.Action) {
+		v.errorf(path+".action", "unknown action %q, expected one of %v", g.Action, GeofenceActions)
+	}
+}
+
+func validateAHRS(v *Validation, path string, a *AHRSConfig) {
+	if a.Beta <= 0 {
+		v.errorf(path+".beta", "must be positive, got %v", a.Beta)
+	}
+
+	if math.Abs(a.Declination) > 180 {
+		v.errorf(path+".declination", "must be within [-180, 180] deg, got %v", a.Declination)
+	}
+}
+
+func validateNavigation(v *Validation, path string, n *NavigationConfig) {
+	if n.Rate <= 0 {
+		v.errorf(path+".rate", "must be positive, got %v", n.Rate)
+	}
+
+	validateDuration(v, path+".timeout", n.Timeout)
+
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"min_course_speed", n.MinCourseSpeed},
+		{"gyro_noise", n.GyroNoise},
+		{"acc_noise", n.AccNoise},
+		{"gyro_bias_noise", n.GyroBiasNoise},
+		{"acc_bias_noise", n.AccBiasNoise},
+		{"course_noise", n.CourseNoise},
+	} {
+		if f.value < 0 {
+			v.errorf(path+"."+f.name, "must not be negative, got %v", f.value)
+		}
+	}
+}
+
+func (c *ControllerConfig) validateFailsafe(v *Validation, path string, f *FailsafeConfig) {
+	if len(f.Triggers) == 0 {
+		v.warnf(path+".triggers", "no triggers, failsafe is never engaged")
+	}
+
+	for i, t := range f.Triggers {
+		path := fmt.Sprintf("%s.triggers[%d]", path, i)
+
+		if t == nil {
+			v.errorf(path, "trigger is empty")
+			continue
+		}
+
+		switch t.Type {
+		case FailsafeLinkSilence:
+			i := slices.IndexFunc(c.Routes, func(r *RouteConfig) bool { return r != nil && r.Name == t.Route })
+
+			switch {
+			case i < 0:
+				v.errorf(path+".route", "route %q is not configured", t.Route)
+			case c.Routes[i].Destination != nil && !c.Routes[i].Destination.Sync:
+				// без подтверждений запись в порт успешна и при недоступной второй стороне
+				v.errorf(path+".route", "route %q has no sync, link silence is never detected", t.Route)
+			}
+		case FailsafeModuleSilent:
+			if _, ok := proto.ModuleIDByName(t.Module); !ok {
+				v.errorf(path+".module", "unknown module %q, expected one of %v", t.Module, proto.ModuleNames())
+			}
+		case FailsafeLowBattery:
+			if t.MinVoltage <= 0 && t.MinCharge <= 0 {
+				v.errorf(path, "min_voltage or min_charge is required")
+			}
+		case FailsafeGNSSLost:
+		default:
+			v.errorf(path+".type", "unknown trigger %q, expected one of %v", t.Type, FailsafeTriggers)
+		}
+
+		if t.MaxHAcc < 0 || t.MinVoltage < 0 || t.MinCharge < 0 {
+			v.errorf(path, "thresholds must not be negative")
+		}
+
+		validateDuration(v, path+".timeout", t.Timeout)
+
+		response := failsafe.Response(t.Response)
+
+		switch {
+		case !slices.Contains(failsafe.Responses, response):
+			v.errorf(path+".response", "unknown response %q, expected one of %v", t.Response, failsafe.Responses)
+		case response != failsafe.Drift && c.Guidance == nil:
+			v.errorf(path+".response", "%s requires guidance", t.Response)
+		case response == failsafe.Return:
+			c.validateLaunch(v, path+".response", t.Response)
+		}
+	}
+}
+
+// validateLaunch проверяет, что для возвращения в точку запуска есть ее источник: модуль ГНСС,
+// первое положение которого становится точкой запуска, если миссия еще не начата.
+func (c *ControllerConfig) validateLaunch(v *Validation, path, action string) {
+	gnss := c.Modules["gnss"]
+
+	switch {
+	case gnss == nil:
+		v.errorf(path, "%s requires gnss module for the launch point", action)
+	case !gnss.Enabled:
+		v.warnf(path, "%s: gnss module is disabled, the launch point is unknown until it is enabled", action)
+	}
+}
+
+func validatePID(v *Validation, path string, p PIDConfig) {
+	if p.Kp <= 0 {
+		v.errorf(path+".kp", "must be positive, got %v", p.Kp)
+	}
+
+	if p.Ki < 0 || p.Kd < 0 {
+		v.errorf(path, "ki and kd must not be negative, got %v and %v", p.Ki, p.Kd)
+	}
+
+	if p.Min >= p.Max {
+		v.errorf(path, "min must be less than max, got %v and %v", p.Min, p.Max)
+	}
+
+	if p.RateLimit < 0 {
+		v.errorf(path+".rate_limit", "must not be negative, got %v", p.RateLimit)
+	}
+}
+
+// validateSetpoint проверяет, что уставка укладывается в пределы исполнительных механизмов.
+func validateSetpoint(v *Validation, path string, s SetpointConfig) {
+	if math.Abs(s.Thrust) > 100 {
+		v.errorf(path+".thrust", "must be within [-100, 100] %%, got %v", s.Thrust)
+	}
+
+	if math.Abs(s.Rudder) > 90 {
+		v.errorf(path+".rudder", "must be within [-90, 90] deg, got %v", s.Rudder)
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// validatePort проверяет настройки канала связи. Если ports не nil, адрес канала не должен
+// совпадать с уже занятыми.
+func validatePort(v *Validation, path string, cfg *SerialPortConfig, ports map[string]string) {
+	if cfg.Port == "" {
+		v.errorf(path+".port", "port is not configured")
+		return
+	}
+
+	ep, err := cfg.Endpoint()
+	if err != nil {
+		v.errorf(path+".port", "%v", err)
+		return
+	}
+
+	if ports != nil {
+		key := ep.Scheme + "://" + ep.Address
+		if other, ok := ports[key]; ok {
+			v.errorf(path+".port", "port %s is already used by %s", ep.Address, other)
+		} else {
+			ports[key] = path
+		}
+	}
+
+	switch {
+	case ep.Scheme != transport.SchemeSerial:
+		if cfg.BaudRate != 0 {
+			v.warnf(path+".baudrate", "ignored for %s port", ep.Scheme)
+		}
+	case ep.BaudRate != 0 && !slices.Contains(BaudRates, ep.BaudRate):
+		v.errorf(path+".baudrate", "invalid baud rate %d, want one of %v", ep.BaudRate, BaudRates)
+	}
+
+	switch {
+	case ep.Timeout < 0:
+		v.errorf(path+".timeout", "must not be negative, got %v", ep.Timeout)
+	case ep.Timeout == 0:
+		v.errorf(path+".timeout", "not set, reading would block forever")
+	}
+
+	if cfg.ChunkSize < 0 {
+		v.errorf(path+".chunk_size", "must not be negative, got %d", cfg.ChunkSize)
+	}
+
+	if cfg.RetriesLimit < 0 {
+		v.errorf(path+".retries_limit", "must not be negative, got %d", cfg.RetriesLimit)
+	}
+
+	if cfg.Sleep < 0 {
+		v.errorf(path+".sleep", "must not be negative, got %v", cfg.Sleep)
+	}
+}
+
+// validateModuleNames проверяет имена модулей (см. proto.ModuleIDByName).
+func validateModuleNames(v *Validation, path string, names []string) {
+	seen := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		if _, ok := proto.ModuleIDByName(name); !ok {
+			v.errorf(path, "unknown module %q, want one of %v", name, proto.ModuleNames())
+			continue
+		}
+
+		if seen[name] {
+			v.errorf(path, "module %q is listed twice", name)
+		}
+
+		seen[name] = true
+	}
+}
+
+func validateDuration(v *Validation, path string, d time.Duration) {
+	if d < 0 {
+		v.errorf(path, "must not be negative, got %v", d)
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig записывает yaml-конфигурацию во временный файл и возвращает путь к нему.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0o600))
+
+	return cfgPath
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+		// path поле с ошибкой, пустая строка - ошибок нет
+		path string
+	}{
+		{
+			name: "корректная конфигурация",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: 1s}
+`,
+		},
+		{
+			name: "повторяющиеся порты",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: 1s}
+  imu:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: 1s}
+`,
+			path: "modules.imu.listener.port",
+		},
+		{
+			name: "недопустимая скорость порта",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 1000, timeout: 1s}
+`,
+			path: "modules.gnss.listener.baudrate",
+		},
+		{
+			name: "отсутствует listener",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+`,
+			path: "modules.gnss.listener",
+		},
+		{
+			name: "неизвестный модуль",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+    modules: [gnss, sonar]
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: 1s}
+`,
+			path: "modules.gnss.modules",
+		},
+		{
+			name: "отрицательный таймаут",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: -1s}
+`,
+			path: "modules.gnss.listener.timeout",
+		},
+		{
+			name: "таймаут не задан",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600}
+`,
+			path: "modules.gnss.listener.timeout",
+		},
+		{
+			name: "возвращение с модулем ГНСС",
+			cfg: `
+modules:
+  gnss:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: 1s}
+mission: {file: mission.yaml}
+guidance: {heading: {kp: 1, min: -30, max: 30}, speed: {kp: 10, max: 100}}
+failsafe:
+  triggers:
+    - {type: gnss_lost, response: rtl}
+`,
+		},
+		{
+			name: "возвращение без модуля ГНСС",
+			cfg: `
+modules:
+  imu:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: 1s}
+mission: {file: mission.yaml}
+guidance: {heading: {kp: 1, min: -30, max: 30}, speed: {kp: 10, max: 100}}
+failsafe:
+  triggers:
+    - {type: gnss_lost, response: rtl}
+`,
+			path: "failsafe.triggers[0].response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ReadControllerConfig(writeConfig(t, tt.cfg))
+			require.NoError(t, err)
+
+			v := cfg.Validate()
+			if tt.path == "" {
+				require.Empty(t, v.Errors)
+				require.NoError(t, v.Err())
+
+				return
+			}
+
+			require.Len(t, v.Errors, 1)
+			require.Equal(t, tt.path, v.Errors[0].Path)
+			require.ErrorContains(t, v.Err(), tt.path)
+		})
+	}
+
+	t.Run("неизвестные ключи", func(t *testing.T) {
+		_, err := ReadControllerConfig(writeConfig(t, `
+modules:
+  gnss:
+    enabled: true
+    listener: {port: /dev/ttyUSB0, baudrate: 9600, timeout: 1s, parity: even}
+`))
+		require.ErrorContains(t, err, "parity")
+	})
+}
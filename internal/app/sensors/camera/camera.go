@@ -46,7 +46,12 @@ func (c *Camera) WithLogger(log logger.Logger) *Camera {
 }
 
 func (c *Camera) Close() error {
-	errConnClose := c.conn.Close()
+	var errConnClose error
+
+	if c.conn != nil {
+		errConnClose = c.conn.Close()
+	}
+
 	errListenerClose := c.listener.Close()
 	errSocketRemove := os.Remove(defaultSocketPath)
 
@@ -2,9 +2,10 @@
 package camera
 
 import (
+	"asvsoft/internal/pkg/communication"
 	"asvsoft/internal/pkg/proto"
 	"context"
-	"errors"
+	"fmt"
 	"time"
 )
 
@@ -14,7 +15,7 @@ type MockCamera struct {
 }
 
 // ErrNoMoreData ошибка отсутсвия данных
-var ErrNoMoreData = errors.New("no more data")
+var ErrNoMoreData = fmt.Errorf("no more data: %w", communication.ErrEndOfData)
 
 func NewMockCamera(data [][3]int16) (*MockCamera, error) {
 	return &MockCamera{data: data}, nil
@@ -0,0 +1,64 @@
+package sensehat
+
+import (
+	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/ahrs"
+	"asvsoft/internal/pkg/imu"
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"errors"
+	"time"
+)
+
+// AHRS измеритель ориентации: оценивает крен, дифферент и магнитный курс по каждому
+// измерению Sense HAT и возвращает их сообщением модуля навигации (режим WritingModeB).
+type AHRS struct {
+	hat    *SenseHAT
+	filter *ahrs.Madgwick
+	// last время предыдущего измерения, нулевое - измерений не было
+	last time.Time
+}
+
+// NewAHRS инициализирует Sense HAT в режиме ahrs.
+func NewAHRS(cfg *config.SenseHATConfig) (*AHRS, error) {
+	hat, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AHRS{
+		hat:    hat,
+		filter: cfg.AHRS.Madgwick(),
+	}, nil
+}
+
+func (a *AHRS) Measure(_ context.Context) (proto.Packer, error) {
+	time.Sleep(a.hat.config.Period)
+
+	m, err := a.hat.measure()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var dt float64
+	if !a.last.IsZero() {
+		dt = now.Sub(a.last).Seconds()
+	}
+
+	a.last = now
+
+	s, ok := imu.FromProto(m, proto.WritingModeB)
+	if !ok {
+		return nil, errors.New("no sensitivity factors")
+	}
+
+	a.filter.Update(s, dt)
+
+	return a.filter.Attitude().NavigationData(), nil
+}
+
+func (a *AHRS) Close() error {
+	return a.hat.Close()
+}
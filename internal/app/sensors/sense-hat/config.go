@@ -9,6 +9,8 @@ import (
 const (
 	IntertialMode = "inertial"
 	FullMode      = "full"
+	// AHRSMode чтение всех датчиков и передача ориентации (см. AHRS)
+	AHRSMode = "ahrs"
 )
 
 type internalConfig struct {
@@ -35,6 +37,7 @@ func getInternalConfig(cmnCfg config.SenseHATConfig) internalConfig {
 
 	cfg.Period = cmnCfg.Period
 	cfg.Mode = cmnCfg.Mode
+	cfg.AHRS = cmnCfg.AHRS
 
 	cfg.Acc = sensorConfig{
 		SenseHATSensorConfig: cmnCfg.Acc,
@@ -107,7 +110,7 @@ func (c *internalConfig) validate() (err error) {
 	case IntertialMode:
 		c.Acc.enable = true
 		c.Gyr.enable = true
-	case FullMode:
+	case FullMode, AHRSMode:
 		c.Acc.enable = true
 		c.Gyr.enable = true
 		c.Mag.enable = true
@@ -115,6 +118,10 @@ func (c *internalConfig) validate() (err error) {
 		return fmt.Errorf("unknown mode: '%s'", c.Mode)
 	}
 
+	if c.Mode == AHRSMode && c.AHRS.Beta <= 0 {
+		return fmt.Errorf("ahrs beta must be positive, got %v", c.AHRS.Beta)
+	}
+
 	if !(c.Acc.enable || c.Gyr.enable || c.Mag.enable) {
 		return fmt.Errorf("acc, gyro and magn disable, nothing to do")
 	}
@@ -2,6 +2,7 @@ package sensehat
 
 import (
 	"asvsoft/internal/app/config"
+	"asvsoft/internal/pkg/communication"
 	"asvsoft/internal/pkg/encoder"
 	"asvsoft/internal/pkg/proto"
 	"bytes"
@@ -42,13 +43,13 @@ func New(cmnCfg *config.SenseHATConfig) (*SenseHAT, error) {
 
 	err := cfg.validate()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: bad config: %w", communication.ErrFatal, err)
 	}
 
 	s := &SenseHAT{config: cfg}
 
 	switch cfg.Mode {
-	case FullMode:
+	case FullMode, AHRSMode:
 		s.buf = make([]byte, 24)
 	case IntertialMode:
 		s.buf = make([]byte, 16)
@@ -119,7 +120,7 @@ func (s *SenseHAT) measure() (*proto.IMUData, error) {
 	decoder := encoder.NewDecoder(io.NopCloser(bytes.NewBuffer(b)))
 
 	switch s.config.Mode {
-	case FullMode:
+	case FullMode, AHRSMode:
 		m.AccFactor = int16(s.config.Acc.rangeSensitivity())
 		m.GyrFactor = int16(s.config.Gyr.rangeSensitivity())
 		err = decoder.Decode(
@@ -162,7 +163,7 @@ func (s *SenseHAT) RawMeasure() ([]byte, error) {
 	var err error
 
 	switch s.config.Mode {
-	case FullMode:
+	case FullMode, AHRSMode:
 		b, _, err := s.inertialBus.ReadRegBytes(QMI8658RegisterAxL, 12)
 		if err != nil {
 			return nil, err
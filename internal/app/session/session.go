@@ -0,0 +1,317 @@
+// Package session предоставляет сессии записи: каталог запуска контроллера или регистратора
+// с манифестом, описывающим сборку, конфигурацию и время работы
+package session
+
+import (
+	"asvsoft/internal/app/ds"
+	"asvsoft/internal/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ManifestFile имя файла манифеста в каталоге сессии
+	ManifestFile = "manifest.yaml"
+	// DefaultRetentionInterval период проверки свободного места по умолчанию
+	DefaultRetentionInterval = time.Minute
+
+	dirTimeFormat = "20060102T150405"
+)
+
+// Manifest описание сессии
+type Manifest struct {
+	ID      string       `yaml:"id"`
+	Command string       `yaml:"command"`
+	Build   ds.AppInfo   `yaml:"build"`
+	Config  string       `yaml:"config_path"`
+	Modules []ModuleInfo `yaml:"modules"`
+	// StartStamp начало отсчета системного времени модулей, мс (см. proto.GetStartStamp)
+	StartStamp uint32     `yaml:"start_stamp"`
+	Started    time.Time  `yaml:"started"`
+	Stopped    *time.Time `yaml:"stopped,omitempty"`
+	// Effective действующая конфигурация контроллера
+	Effective any `yaml:"effective_config"`
+}
+
+// ModuleInfo канал связи с модулями сессии
+type ModuleInfo struct {
+	Name    string   `yaml:"name"`
+	Port    string   `yaml:"port"`
+	Modules []string `yaml:"modules,omitempty"`
+}
+
+// Session сессия записи
+type Session struct {
+	Dir      string
+	Manifest Manifest
+}
+
+// Create создает каталог сессии в root и записывает манифест m.
+func Create(root string, m Manifest) (*Session, error) {
+	if m.Started.IsZero() {
+		m.Started = time.Now()
+	}
+
+	id := m.Started.Format(dirTimeFormat)
+
+	dir := filepath.Join(root, id)
+	for i := 1; ; i++ {
+		err := os.MkdirAll(root, 0755) // nolint:gosec
+		if err != nil {
+			return nil, fmt.Errorf("cannot create sessions directory: %w", err)
+		}
+
+		err = os.Mkdir(dir, 0755) // nolint:gosec
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("cannot create session directory: %w", err)
+		}
+
+		dir = filepath.Join(root, fmt.Sprintf("%s-%d", id, i))
+	}
+
+	m.ID = filepath.Base(dir)
+
+	s := &Session{Dir: dir, Manifest: m}
+
+	err := s.writeManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close записывает в манифест время завершения сессии.
+func (s *Session) Close() error {
+	now := time.Now()
+	s.Manifest.Stopped = &now
+
+	return s.writeManifest()
+}
+
+// Path возвращает путь path относительно каталога сессии; абсолютные пути не изменяются.
+func (s *Session) Path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(s.Dir, path)
+}
+
+func (s *Session) writeManifest() error {
+	data, err := yaml.Marshal(s.Manifest)
+	if err != nil {
+		return fmt.Errorf("cannot marshal manifest: %w", err)
+	}
+
+	// манифест заменяется целиком, чтобы при аварийном завершении не остался обрезанный файл
+	tmp := filepath.Join(s.Dir, ManifestFile+".tmp")
+
+	err = os.WriteFile(tmp, data, 0644) // nolint:gosec
+	if err != nil {
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+
+	return os.Rename(tmp, filepath.Join(s.Dir, ManifestFile))
+}
+
+// Info сессия, найденная в каталоге сессий
+type Info struct {
+	Dir      string
+	Manifest *Manifest
+	// Size суммарный размер файлов сессии
+	Size int64
+	// Err ошибка чтения манифеста
+	Err error
+}
+
+// List возвращает сессии каталога root от старых к новым.
+func List(root string) ([]Info, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Info, 0, len(entries))
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(root, e.Name())
+
+		if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err != nil {
+			// каталог не является сессией
+			continue
+		}
+
+		info := Info{Dir: dir}
+		info.Manifest, info.Err = readManifest(dir)
+		info.Size = dirSize(dir)
+
+		sessions = append(sessions, info)
+	}
+
+	// имена каталогов начинаются со времени создания
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Dir < sessions[j].Dir })
+
+	return sessions, nil
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile)) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+
+	err = yaml.Unmarshal(data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("bad manifest: %w", err)
+	}
+
+	return &m, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size
+}
+
+// FreeSpace возвращает свободное место в байтах на файловой системе каталога path.
+func FreeSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+
+	err := syscall.Statfs(path, &st)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(st.Bavail) * uint64(st.Bsize), nil // nolint:gosec,unconvert
+}
+
+// Retention удаляет самые старые сессии, пока свободное место на диске меньше minFree байт.
+// Текущая сессия не удаляется.
+type Retention struct {
+	root      string
+	minFree   uint64
+	current   string
+	log       logger.Logger
+	freeSpace func(path string) (uint64, error)
+}
+
+func NewRetention(root string, minFree uint64) *Retention {
+	return &Retention{
+		root:      root,
+		minFree:   minFree,
+		log:       logger.DummyLogger{},
+		freeSpace: FreeSpace,
+	}
+}
+
+// WithCurrent исключает каталог текущей сессии из удаления.
+func (r *Retention) WithCurrent(dir string) *Retention {
+	r.current = dir
+	return r
+}
+
+func (r *Retention) WithLogger(log logger.Logger) *Retention {
+	r.log = log
+	return r
+}
+
+// Prune удаляет самые старые сессии до освобождения места и возвращает удаленные каталоги.
+func (r *Retention) Prune() ([]string, error) {
+	if r.minFree == 0 {
+		return nil, nil
+	}
+
+	sessions, err := List(r.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+
+	for _, s := range sessions {
+		free, err := r.freeSpace(r.root)
+		if err != nil {
+			return deleted, fmt.Errorf("cannot get free space: %w", err)
+		}
+
+		if free >= r.minFree {
+			return deleted, nil
+		}
+
+		if s.Dir == r.current {
+			continue
+		}
+
+		err = os.RemoveAll(s.Dir)
+		if err != nil {
+			return deleted, fmt.Errorf("cannot remove session %s: %w", s.Dir, err)
+		}
+
+		r.log.Warnf("free space %d bytes is below %d, removed session %s (%d bytes)", free, r.minFree, s.Dir, s.Size)
+
+		deleted = append(deleted, s.Dir)
+	}
+
+	free, err := r.freeSpace(r.root)
+	if err == nil && free < r.minFree {
+		r.log.Warnf("free space %d bytes is below %d, no sessions left to remove", free, r.minFree)
+	}
+
+	return deleted, nil
+}
+
+// Run проверяет свободное место с периодом interval до отмены контекста.
+func (r *Retention) Run(ctx context.Context, interval time.Duration) {
+	if r.minFree == 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := r.Prune()
+		if err != nil {
+			r.log.Errorf("retention failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	root := t.TempDir()
+	started := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	s, err := Create(root, Manifest{Command: "registrar", Started: started, StartStamp: 42})
+	require.NoError(t, err)
+	require.Equal(t, "20261019T100000", s.Manifest.ID)
+
+	// сессия, начатая в ту же секунду
+	s2, err := Create(root, Manifest{Command: "registrar", Started: started})
+	require.NoError(t, err)
+	require.Equal(t, "20261019T100000-1", s2.Manifest.ID)
+
+	require.NoError(t, os.WriteFile(s.Path("data.bin"), make([]byte, 100), 0600))
+	require.Equal(t, "/abs", s.Path("/abs"))
+	require.NoError(t, s.Close())
+
+	sessions, err := List(root)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.NoError(t, sessions[0].Err)
+	require.Equal(t, "registrar", sessions[0].Manifest.Command)
+	require.Equal(t, uint32(42), sessions[0].Manifest.StartStamp)
+	require.NotNil(t, sessions[0].Manifest.Stopped)
+	require.Nil(t, sessions[1].Manifest.Stopped)
+	require.Greater(t, sessions[0].Size, int64(100))
+}
+
+func TestRetention(t *testing.T) {
+	root := t.TempDir()
+
+	var dirs []string
+
+	for i := range 3 {
+		s, err := Create(root, Manifest{Started: time.Date(2026, 10, 19, 10, i, 0, 0, time.UTC)})
+		require.NoError(t, err)
+
+		dirs = append(dirs, s.Dir)
+	}
+
+	// каждая удаленная сессия освобождает 10 байт
+	r := NewRetention(root, 15).WithCurrent(dirs[0])
+	r.freeSpace = func(string) (uint64, error) {
+		sessions, err := List(root)
+		if err != nil {
+			return 0, err
+		}
+
+		return uint64(10 * (4 - len(sessions))), nil // nolint:gosec
+	}
+
+	deleted, err := r.Prune()
+	require.NoError(t, err)
+	require.Equal(t, []string{dirs[1]}, deleted)
+
+	_, err = os.Stat(filepath.Join(dirs[0], ManifestFile))
+	require.NoError(t, err)
+
+	_, err = os.Stat(dirs[2])
+	require.NoError(t, err)
+}
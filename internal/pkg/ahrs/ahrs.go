@@ -0,0 +1,64 @@
+// Package ahrs предоставляет оценку ориентации по измерениям ИНС фильтром Маджвика: крен,
+// дифферент и магнитный курс с компенсацией наклона
+package ahrs
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/imu"
+	"asvsoft/internal/pkg/proto"
+	"math"
+)
+
+// DefaultBeta коэффициент коррекции фильтра Маджвика по умолчанию, рад/с
+const DefaultBeta = 0.1
+
+// maxStep наибольший шаг интегрирования в секундах; более долгий перерыв в измерениях
+// заново задает ориентацию по акселерометрам и магнитометру
+const maxStep = 1.0
+
+// Attitude ориентация в градусах
+type Attitude struct {
+	// Roll крен, положительный - на правый борт; Pitch дифферент, положительный - на корму
+	Roll, Pitch float64
+	// Heading курс с учетом магнитного склонения, [0, 360)
+	Heading float64
+	// HeadingValid курс определен по магнитометру, иначе он отсчитывается от начального
+	// положения по гироскопам
+	HeadingValid bool
+}
+
+// NavigationData возвращает сообщение модуля навигации с ориентацией (режим WritingModeB).
+func (a Attitude) NavigationData() *proto.NavigationData {
+	var status uint8
+	if a.HeadingValid {
+		status = proto.NavigationHeadingValid
+	}
+
+	return &proto.NavigationData{
+		Roll:    int16(math.Round(a.Roll * 100)),
+		Pitch:   int16(math.Round(a.Pitch * 100)),
+		Heading: uint16(math.Round(a.Heading*100)) % 36000,
+		Status:  status,
+	}
+}
+
+// TiltCompensatedHeading возвращает магнитный курс в градусах по магнитному полю mx, my, mz
+// при крене roll и дифференте pitch в градусах.
+func TiltCompensatedHeading(roll, pitch, mx, my, mz float64) float64 {
+	sinR, cosR := math.Sincos(geo.Rad(roll))
+	sinP, cosP := math.Sincos(geo.Rad(pitch))
+
+	// проекции поля на горизонтальную плоскость
+	xh := mx*cosP + my*sinR*sinP + mz*cosR*sinP
+	yh := my*cosR - mz*sinR
+
+	return geo.NormalizeBearing(geo.Deg(math.Atan2(-yh, xh)))
+}
+
+// tilt возвращает крен и дифферент в градусах по ускорениям в покое.
+func tilt(s imu.Sample) (roll, pitch float64) {
+	// направление силы тяжести противоположно измеренному ускорению
+	gx, gy, gz := -s.Ax, -s.Ay, -s.Az
+
+	return geo.Deg(math.Atan2(gy, gz)), geo.Deg(math.Atan2(-gx, math.Hypot(gy, gz)))
+}
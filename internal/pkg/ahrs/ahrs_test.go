@@ -0,0 +1,135 @@
+package ahrs
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/imu"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// field магнитное поле в системе север-восток-вниз, мкТл
+var field = [3]float64{15, 0, 50}
+
+// measure возвращает измерения в покое при ориентации roll, pitch, yaw в градусах без
+// угловых скоростей.
+func measure(roll, pitch, yaw float64) imu.Sample {
+	sinR, cosR := math.Sincos(geo.Rad(roll))
+	sinP, cosP := math.Sincos(geo.Rad(pitch))
+	sinY, cosY := math.Sincos(geo.Rad(yaw))
+
+	// матрица поворота из связанной системы в систему север-восток-вниз
+	r := [3][3]float64{
+		{cosP * cosY, sinR*sinP*cosY - cosR*sinY, cosR*sinP*cosY + sinR*sinY},
+		{cosP * sinY, sinR*sinP*sinY + cosR*cosY, cosR*sinP*sinY - sinR*cosY},
+		{-sinP, sinR * cosP, cosR * cosP},
+	}
+
+	// в связанной системе: транспонированная матрица
+	body := func(v [3]float64) [3]float64 {
+		return [3]float64{
+			r[0][0]*v[0] + r[1][0]*v[1] + r[2][0]*v[2],
+			r[0][1]*v[0] + r[1][1]*v[1] + r[2][1]*v[2],
+			r[0][2]*v[0] + r[1][2]*v[1] + r[2][2]*v[2],
+		}
+	}
+
+	a := body([3]float64{0, 0, -imu.Gravity})
+	m := body(field)
+
+	return imu.Sample{Ax: a[0], Ay: a[1], Az: a[2], Mx: m[0], My: m[1], Mz: m[2]}
+}
+
+func TestTiltCompensatedHeading(t *testing.T) {
+	for _, c := range [][3]float64{{0, 0, 0}, {10, -5, 45}, {-20, 15, 200}, {5, 25, 315}} {
+		s := measure(c[0], c[1], c[2])
+
+		roll, pitch := tilt(s)
+		require.InDelta(t, c[0], roll, 1e-9)
+		require.InDelta(t, c[1], pitch, 1e-9)
+		require.InDelta(t, c[2], TiltCompensatedHeading(roll, pitch, s.Mx, s.My, s.Mz), 1e-9)
+	}
+}
+
+func TestMadgwick(t *testing.T) {
+	const (
+		dt = 0.01
+		// rate угловая скорость циркуляции, град/с
+		rate     = 10.0
+		roll     = 8.0
+		pitch    = -4.0
+		gyroBias = 0.5
+	)
+
+	// run моделирует циркуляцию с креном и дифферентом длительностью d секунд от курса yaw
+	// и возвращает конечный курс; без магнитометра поле не измеряется.
+	run := func(f *Madgwick, yaw, d float64, magnetic bool) float64 {
+		sinR, cosR := math.Sincos(geo.Rad(roll))
+		sinP, cosP := math.Sincos(geo.Rad(pitch))
+
+		for i := 0; i < int(d/dt); i++ {
+			yaw += rate * dt
+			s := measure(roll, pitch, yaw)
+
+			// угловые скорости в связанной системе при постоянных крене и дифференте
+			s.Gx = geo.Rad(-sinP*rate + gyroBias)
+			s.Gy = geo.Rad(sinR * cosP * rate)
+			s.Gz = geo.Rad(cosR*cosP*rate + gyroBias)
+
+			if !magnetic {
+				s.Mx, s.My, s.Mz = 0, 0, 0
+			}
+
+			f.Update(s, dt)
+		}
+
+		return geo.NormalizeBearing(yaw)
+	}
+
+	t.Run("ориентация по первому измерению", func(t *testing.T) {
+		f := NewMadgwick(DefaultBeta, 0)
+		f.Update(measure(roll, pitch, 100), 0)
+
+		a := f.Attitude()
+		require.InDelta(t, roll, a.Roll, 1e-6)
+		require.InDelta(t, pitch, a.Pitch, 1e-6)
+		require.InDelta(t, 100, a.Heading, 1e-6)
+		require.True(t, a.HeadingValid)
+	})
+
+	t.Run("магнитометр компенсирует смещение гироскопа", func(t *testing.T) {
+		f := NewMadgwick(DefaultBeta, 0)
+		f.Update(measure(roll, pitch, 0), 0)
+
+		yaw := run(f, 0, 60, true)
+
+		a := f.Attitude()
+		require.InDelta(t, roll, a.Roll, 1)
+		require.InDelta(t, pitch, a.Pitch, 1)
+		require.InDelta(t, 0, geo.NormalizeAngle(a.Heading-yaw), 2)
+		require.True(t, a.HeadingValid)
+	})
+
+	t.Run("без магнитометра курс интегрируется", func(t *testing.T) {
+		f := NewMadgwick(DefaultBeta, 0)
+		f.Update(measure(roll, pitch, 0), 0)
+
+		yaw := run(f, 0, 60, false)
+
+		a := f.Attitude()
+		require.InDelta(t, roll, a.Roll, 1)
+		require.InDelta(t, pitch, a.Pitch, 1)
+		require.False(t, a.HeadingValid)
+		// смещение гироскопа накапливается в курсе
+		require.Greater(t, math.Abs(geo.NormalizeAngle(a.Heading-yaw)), 10.0)
+	})
+
+	t.Run("магнитное склонение", func(t *testing.T) {
+		f := NewMadgwick(DefaultBeta, -12)
+		f.Update(measure(0, 0, 5), 0)
+
+		require.InDelta(t, 353, f.Attitude().Heading, 1e-6)
+		require.Equal(t, uint16(35300), f.Attitude().NavigationData().Heading)
+	})
+}
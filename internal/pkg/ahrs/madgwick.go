@@ -0,0 +1,157 @@
+package ahrs
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/imu"
+	"math"
+)
+
+// Madgwick фильтр ориентации Маджвика: интегрирует угловые скорости и корректирует
+// ориентацию градиентным спуском к направлениям силы тяжести и магнитного поля. Без
+// магнитометра курс только интегрируется. Не безопасен для одновременного использования.
+type Madgwick struct {
+	// beta коэффициент коррекции в рад/с: больше - быстрее сходимость и сильнее влияние
+	// ускорений от движения
+	beta float64
+	// declination магнитное склонение в градусах, восточное положительное
+	declination float64
+
+	// q кватернион поворота из связанной системы координат в систему север-восток-вниз
+	q           [4]float64
+	initialized bool
+	magnetic    bool
+}
+
+func NewMadgwick(beta, declination float64) *Madgwick {
+	return &Madgwick{beta: beta, declination: declination}
+}
+
+// Update учитывает измерение s через dt секунд после предыдущего. Первое измерение и
+// измерение после перерыва дольше секунды задают ориентацию по акселерометрам и магнитометру.
+func (f *Madgwick) Update(s imu.Sample, dt float64) {
+	if !f.initialized || dt <= 0 || dt > maxStep {
+		f.reset(s)
+		return
+	}
+
+	q0, q1, q2, q3 := f.q[0], f.q[1], f.q[2], f.q[3]
+
+	// производная кватерниона по угловым скоростям
+	qDot0 := 0.5 * (-q1*s.Gx - q2*s.Gy - q3*s.Gz)
+	qDot1 := 0.5 * (q0*s.Gx + q2*s.Gz - q3*s.Gy)
+	qDot2 := 0.5 * (q0*s.Gy - q1*s.Gz + q3*s.Gx)
+	qDot3 := 0.5 * (q0*s.Gz + q1*s.Gy - q2*s.Gx)
+
+	step, ok := f.gradient(s)
+	if ok {
+		qDot0 -= f.beta * step[0]
+		qDot1 -= f.beta * step[1]
+		qDot2 -= f.beta * step[2]
+		qDot3 -= f.beta * step[3]
+	}
+
+	f.q = normalize([4]float64{q0 + qDot0*dt, q1 + qDot1*dt, q2 + qDot2*dt, q3 + qDot3*dt})
+	f.magnetic = s.Magnetic()
+}
+
+// gradient возвращает нормированный градиент целевой функции; false - ускорения нулевые.
+func (f *Madgwick) gradient(s imu.Sample) ([4]float64, bool) {
+	// направление силы тяжести противоположно измеренному ускорению
+	ax, ay, az := -s.Ax, -s.Ay, -s.Az
+
+	n := math.Sqrt(ax*ax + ay*ay + az*az)
+	if n == 0 {
+		return [4]float64{}, false
+	}
+
+	ax, ay, az = ax/n, ay/n, az/n
+
+	q0, q1, q2, q3 := f.q[0], f.q[1], f.q[2], f.q[3]
+
+	// невязки направления силы тяжести
+	fg0 := 2*(q1*q3-q0*q2) - ax
+	fg1 := 2*(q0*q1+q2*q3) - ay
+	fg2 := 2*(0.5-q1*q1-q2*q2) - az
+
+	grad := [4]float64{
+		-2*q2*fg0 + 2*q1*fg1,
+		2*q3*fg0 + 2*q0*fg1 - 4*q1*fg2,
+		-2*q0*fg0 + 2*q3*fg1 - 4*q2*fg2,
+		2*q1*fg0 + 2*q2*fg1,
+	}
+
+	if s.Magnetic() {
+		mx, my, mz := s.Mx, s.My, s.Mz
+		n = math.Sqrt(mx*mx + my*my + mz*mz)
+		mx, my, mz = mx/n, my/n, mz/n
+
+		// поле в системе север-восток-вниз, его горизонтальная составляющая направлена на
+		// север
+		hx := mx*(q0*q0+q1*q1-q2*q2-q3*q3) + 2*my*(q1*q2-q0*q3) + 2*mz*(q0*q2+q1*q3)
+		hy := 2*mx*(q0*q3+q1*q2) + my*(q0*q0-q1*q1+q2*q2-q3*q3) + 2*mz*(q2*q3-q0*q1)
+		bx := math.Hypot(hx, hy)
+		bz := 2*mx*(q1*q3-q0*q2) + 2*my*(q0*q1+q2*q3) + mz*(q0*q0-q1*q1-q2*q2+q3*q3)
+
+		// невязки направления магнитного поля
+		fm0 := bx*(0.5-q2*q2-q3*q3) + bz*(q1*q3-q0*q2) - mx/2
+		fm1 := bx*(q1*q2-q0*q3) + bz*(q0*q1+q2*q3) - my/2
+		fm2 := bx*(q0*q2+q1*q3) + bz*(0.5-q1*q1-q2*q2) - mz/2
+
+		grad[0] += 4 * (-bz*q2*fm0 + (-bx*q3+bz*q1)*fm1 + bx*q2*fm2)
+		grad[1] += 4 * (bz*q3*fm0 + (bx*q2+bz*q0)*fm1 + (bx*q3-2*bz*q1)*fm2)
+		grad[2] += 4 * ((-2*bx*q2-bz*q0)*fm0 + (bx*q1+bz*q3)*fm1 + (bx*q0-2*bz*q2)*fm2)
+		grad[3] += 4 * ((-2*bx*q3+bz*q1)*fm0 + (-bx*q0+bz*q2)*fm1 + bx*q1*fm2)
+	}
+
+	n = math.Sqrt(grad[0]*grad[0] + grad[1]*grad[1] + grad[2]*grad[2] + grad[3]*grad[3])
+	if n == 0 {
+		return [4]float64{}, false
+	}
+
+	return [4]float64{grad[0] / n, grad[1] / n, grad[2] / n, grad[3] / n}, true
+}
+
+// reset задает ориентацию по измерению s в предположении покоя.
+func (f *Madgwick) reset(s imu.Sample) {
+	roll, pitch := tilt(s)
+
+	var yaw float64
+	if s.Magnetic() {
+		yaw = TiltCompensatedHeading(roll, pitch, s.Mx, s.My, s.Mz)
+	}
+
+	sinR, cosR := math.Sincos(geo.Rad(roll) / 2)
+	sinP, cosP := math.Sincos(geo.Rad(pitch) / 2)
+	sinY, cosY := math.Sincos(geo.Rad(yaw) / 2)
+
+	f.q = [4]float64{
+		cosR*cosP*cosY + sinR*sinP*sinY,
+		sinR*cosP*cosY - cosR*sinP*sinY,
+		cosR*sinP*cosY + sinR*cosP*sinY,
+		cosR*cosP*sinY - sinR*sinP*cosY,
+	}
+	f.initialized = true
+	f.magnetic = s.Magnetic()
+}
+
+// Attitude возвращает текущую ориентацию.
+func (f *Madgwick) Attitude() Attitude {
+	q0, q1, q2, q3 := f.q[0], f.q[1], f.q[2], f.q[3]
+
+	roll := math.Atan2(2*(q0*q1+q2*q3), 1-2*(q1*q1+q2*q2))
+	pitch := math.Asin(math.Max(-1, math.Min(1, 2*(q0*q2-q1*q3))))
+	yaw := math.Atan2(2*(q0*q3+q1*q2), 1-2*(q2*q2+q3*q3))
+
+	return Attitude{
+		Roll:         geo.Deg(roll),
+		Pitch:        geo.Deg(pitch),
+		Heading:      geo.NormalizeBearing(geo.Deg(yaw) + f.declination),
+		HeadingValid: f.initialized && f.magnetic,
+	}
+}
+
+func normalize(q [4]float64) [4]float64 {
+	n := math.Sqrt(q[0]*q[0] + q[1]*q[1] + q[2]*q[2] + q[3]*q[3])
+
+	return [4]float64{q[0] / n, q[1] / n, q[2] / n, q[3] / n}
+}
@@ -0,0 +1,233 @@
+// Package api предоставляет локальный HTTP/JSON API состояния и управления контроллером:
+// список модулей с настройками портов, последними сообщениями и статистикой канала связи,
+// включение и выключение модулей, пересинхронизацию, изменение уровня логирования и поток
+// телеметрии
+package api
+
+import (
+	"asvsoft/internal/pkg/linkstats"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultListen адрес API по умолчанию, доступный только локально
+const DefaultListen = "127.0.0.1:8642"
+
+// ErrNotFound модуль с указанным именем не настроен
+var ErrNotFound = errors.New("module not found")
+
+// Controller состояние и управление модулями контроллера
+type Controller interface {
+	// Modules возвращает настроенные модули, упорядоченные по имени.
+	Modules() []Module
+	// Module возвращает модуль name или ErrNotFound.
+	Module(name string) (Module, error)
+	// SetEnabled запускает или останавливает прием сообщений модуля name.
+	SetEnabled(name string, enabled bool) error
+	// Resync переоткрывает порт модуля name и сбрасывает синхронизацию фреймов.
+	Resync(name string) error
+}
+
+// Module состояние канала связи модуля
+type Module struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Running прием сообщений запущен
+	Running bool     `json:"running"`
+	Port    Port     `json:"port"`
+	Modules []string `json:"modules,omitempty"`
+	// Link статистика канала связи, nil - прием не запущен
+	Link    *linkstats.Snapshot `json:"link,omitempty"`
+	Sync    SyncState           `json:"sync"`
+	Signals []Signal            `json:"signals"`
+}
+
+// Port настройки порта модуля
+type Port struct {
+	Address  string `json:"address"`
+	BaudRate int    `json:"baudrate,omitempty"`
+	Timeout  string `json:"timeout"`
+	Sync     bool   `json:"sync"`
+}
+
+// SyncState синхронизация системного времени модуля с контроллером
+type SyncState struct {
+	// StartStamp начало отсчета системного времени контроллера в секундах unix-времени
+	StartStamp uint32 `json:"start_stamp"`
+	// Requests количество запросов синхронизации модуля
+	Requests    uint64     `json:"requests"`
+	LastRequest *time.Time `json:"last_request,omitempty"`
+}
+
+// Signal последнее сообщение режима модуля
+type Signal struct {
+	Module     string    `json:"module"`
+	Msg        string    `json:"msg"`
+	SystemTime uint32    `json:"system_time"`
+	RecvTime   time.Time `json:"recv_time"`
+	Count      uint64    `json:"count"`
+	// Rate частота сообщений в секунду
+	Rate  float64 `json:"rate"`
+	AgeMs int64   `json:"age_ms"`
+	Stale bool    `json:"stale"`
+	// Fields значения полей сообщения в единицах СИ, null - значение не определено
+	Fields map[string]*float64 `json:"fields,omitempty"`
+}
+
+// LogLevel уровень логирования
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server HTTP-сервер API
+type Server struct {
+	ctrl      Controller
+	telemetry Telemetry
+	log       logger.Logger
+	mux       *http.ServeMux
+}
+
+func NewServer(ctrl Controller) *Server {
+	s := &Server{
+		ctrl: ctrl,
+		log:  logger.DummyLogger{},
+		mux:  http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /api/v1/modules", s.listModules)
+	s.mux.HandleFunc("GET /api/v1/modules/{name}", s.getModule)
+	s.mux.HandleFunc("POST /api/v1/modules/{name}/enable", s.setEnabled(true))
+	s.mux.HandleFunc("POST /api/v1/modules/{name}/disable", s.setEnabled(false))
+	s.mux.HandleFunc("POST /api/v1/modules/{name}/resync", s.resync)
+	s.mux.HandleFunc("GET /api/v1/loglevel", s.getLogLevel)
+	s.mux.HandleFunc("PUT /api/v1/loglevel", s.setLogLevel)
+	s.mux.HandleFunc("GET /api/v1/stream", s.stream)
+	s.mux.HandleFunc("GET /api/v1/images/{time}", s.getImage)
+
+	return s
+}
+
+func (s *Server) WithLogger(log logger.Logger) *Server {
+	s.log = log
+	return s
+}
+
+// WithTelemetry задает источник потока телеметрии, без него поток недоступен.
+func (s *Server) WithTelemetry(t Telemetry) *Server {
+	s.telemetry = t
+	return s
+}
+
+// Handler возвращает обработчик запросов API.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Serve обслуживает запросы, принимаемые l, до отмены контекста. Отмена контекста завершает
+// и потоки телеметрии.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	s.log.Infof("serving api on http://%s", l.Addr())
+
+	return utils.ServeHTTP(ctx, l, s.mux)
+}
+
+func (s *Server) listModules(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.ctrl.Modules())
+}
+
+func (s *Server) getModule(w http.ResponseWriter, r *http.Request) {
+	m, err := s.ctrl.Module(r.PathValue("name"))
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, m)
+}
+
+func (s *Server) setEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+
+		err := s.ctrl.SetEnabled(name, enabled)
+		if err != nil {
+			s.writeError(w, err)
+			return
+		}
+
+		s.log.Infof("module %s enabled: %v", name, enabled)
+
+		s.getModule(w, r)
+	}
+}
+
+func (s *Server) resync(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	err := s.ctrl.Resync(name)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.log.Infof("module %s resynced", name)
+
+	s.getModule(w, r)
+}
+
+func (s *Server) getLogLevel(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, LogLevel{Level: logrus.GetLevel().String()})
+}
+
+func (s *Server) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevel
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("bad request: %v", err)})
+		return
+	}
+
+	lvl, err := logrus.ParseLevel(req.Level)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	logrus.SetLevel(lvl)
+	s.log.Infof("log level set to %s", lvl)
+
+	s.writeJSON(w, http.StatusOK, LogLevel{Level: lvl.String()})
+}
+
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrImageNotFound) {
+		status = http.StatusNotFound
+	}
+
+	s.writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		s.log.Errorf("cannot write response: %v", err)
+	}
+}
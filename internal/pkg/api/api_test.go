@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeController struct {
+	modules map[string]*Module
+	resyncs int
+}
+
+func (c *fakeController) Modules() []Module {
+	return []Module{*c.modules["gnss"]}
+}
+
+func (c *fakeController) Module(name string) (Module, error) {
+	m, ok := c.modules[name]
+	if !ok {
+		return Module{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	return *m, nil
+}
+
+func (c *fakeController) SetEnabled(name string, enabled bool) error {
+	m, ok := c.modules[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	m.Enabled, m.Running = enabled, enabled
+
+	return nil
+}
+
+func (c *fakeController) Resync(name string) error {
+	if _, ok := c.modules[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	c.resyncs++
+
+	return nil
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, body string, v any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	if v != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	ctrl := &fakeController{modules: map[string]*Module{
+		"gnss": {Name: "gnss", Enabled: true, Running: true, Port: Port{Address: "/dev/ttyAMA0", BaudRate: 9600}},
+	}}
+
+	srv := httptest.NewServer(NewServer(ctrl).Handler())
+	defer srv.Close()
+
+	t.Run("список модулей", func(t *testing.T) {
+		var modules []Module
+		require.Equal(t, http.StatusOK, do(t, srv, http.MethodGet, "/api/v1/modules", "", &modules))
+		require.Len(t, modules, 1)
+		require.Equal(t, "/dev/ttyAMA0", modules[0].Port.Address)
+	})
+
+	t.Run("неизвестный модуль", func(t *testing.T) {
+		var resp errorResponse
+		require.Equal(t, http.StatusNotFound, do(t, srv, http.MethodGet, "/api/v1/modules/lidar", "", &resp))
+		require.Contains(t, resp.Error, "module not found")
+
+		require.Equal(t, http.StatusNotFound, do(t, srv, http.MethodPost, "/api/v1/modules/lidar/resync", "", nil))
+	})
+
+	t.Run("выключение и включение модуля", func(t *testing.T) {
+		var m Module
+		require.Equal(t, http.StatusOK, do(t, srv, http.MethodPost, "/api/v1/modules/gnss/disable", "", &m))
+		require.False(t, m.Enabled)
+		require.False(t, m.Running)
+
+		require.Equal(t, http.StatusOK, do(t, srv, http.MethodPost, "/api/v1/modules/gnss/enable", "", &m))
+		require.True(t, m.Running)
+	})
+
+	t.Run("пересинхронизация", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, do(t, srv, http.MethodPost, "/api/v1/modules/gnss/resync", "", nil))
+		require.Equal(t, 1, ctrl.resyncs)
+	})
+
+	t.Run("уровень логирования", func(t *testing.T) {
+		defer logrus.SetLevel(logrus.GetLevel())
+
+		var lvl LogLevel
+		require.Equal(t, http.StatusOK, do(t, srv, http.MethodPut, "/api/v1/loglevel", `{"level":"debug"}`, &lvl))
+		require.Equal(t, "debug", lvl.Level)
+		require.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+		require.Equal(t, http.StatusOK, do(t, srv, http.MethodGet, "/api/v1/loglevel", "", &lvl))
+		require.Equal(t, "debug", lvl.Level)
+
+		require.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodPut, "/api/v1/loglevel", `{"level":"loud"}`, nil))
+		require.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodPut, "/api/v1/loglevel", `level`, nil))
+	})
+}
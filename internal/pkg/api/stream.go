@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultStreamRate частота сообщений каждого модуля и режима в потоке телеметрии по умолчанию
+	DefaultStreamRate = 10.0
+	// keepAliveInterval период комментариев, поддерживающих соединение потока без сообщений
+	keepAliveInterval = 15 * time.Second
+)
+
+// ErrImageNotFound изображение отсутствует в истории сообщений
+var ErrImageNotFound = errors.New("image not found")
+
+// Telemetry сообщения, полученные контроллером
+type Telemetry interface {
+	// Subscribe подписывает на полученные сообщения. cancel отменяет подписку и закрывает канал.
+	// При заполнении очереди подписчика сообщения отбрасываются.
+	Subscribe() (messages <-chan Message, cancel func())
+	// Image возвращает изображение камеры с системным временем systemTime или ErrImageNotFound.
+	Image(systemTime uint32) ([]byte, error)
+}
+
+// Message сообщение потока телеметрии
+type Message struct {
+	Module     string    `json:"module"`
+	Msg        string    `json:"msg"`
+	SystemTime uint32    `json:"system_time"`
+	RecvTime   time.Time `json:"recv_time"`
+	// Stale сообщений режима нет дольше таймаута устаревания, Data и Image - последнее сообщение
+	Stale bool `json:"stale,omitempty"`
+	// Fields значения полей сообщения в единицах СИ, null - значение не определено
+	Fields map[string]*float64 `json:"fields,omitempty"`
+	// Data декодированные данные сообщения, кроме изображений
+	Data  any    `json:"data,omitempty"`
+	Image *Image `json:"image,omitempty"`
+}
+
+// Image изображение камеры
+type Image struct {
+	// Ref путь API, по которому изображение доступно, пока хранится в истории сообщений
+	Ref  string `json:"ref"`
+	Size int    `json:"size"`
+	// Data изображение в base64, передается только при запросе images=base64
+	Data []byte `json:"data,omitempty"`
+}
+
+// ImageRef возвращает путь API изображения с системным временем systemTime.
+func ImageRef(systemTime uint32) string {
+	return fmt.Sprintf("/api/v1/images/%d", systemTime)
+}
+
+type imagesMode string
+
+const (
+	imagesRef    imagesMode = "ref"
+	imagesBase64 imagesMode = "base64"
+	imagesNone   imagesMode = "none"
+)
+
+// streamFilter параметры потока телеметрии: фильтры по модулям и режимам, частота сообщений
+// каждого модуля и режима (0 - без прореживания) и способ передачи изображений.
+type streamFilter struct {
+	modules  []string
+	messages []string
+	interval time.Duration
+	images   imagesMode
+	last     map[string]time.Time
+}
+
+// parseStreamFilter разбирает параметры запроса module, msg, rate и images. Значения module
+// и msg задаются списком через запятую или повторением параметра.
+func parseStreamFilter(q url.Values) (*streamFilter, error) {
+	f := &streamFilter{
+		modules: splitValues(q["module"]),
+		images:  imagesRef,
+		last:    make(map[string]time.Time),
+	}
+
+	for _, v := range splitValues(q["msg"]) {
+		id, err := strconv.ParseUint(v, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("bad msg %q: %w", v, err)
+		}
+
+		f.messages = append(f.messages, fmt.Sprintf("%#x", id))
+	}
+
+	rate := DefaultStreamRate
+
+	if v := q.Get("rate"); v != "" {
+		var err error
+
+		rate, err = strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("bad rate %q: must be a non-negative number", v)
+		}
+	}
+
+	if rate > 0 {
+		f.interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	if v := q.Get("images"); v != "" {
+		f.images = imagesMode(v)
+
+		if !slices.Contains([]imagesMode{imagesRef, imagesBase64, imagesNone}, f.images) {
+			return nil, fmt.Errorf("bad images %q: must be ref, base64 or none", v)
+		}
+	}
+
+	return f, nil
+}
+
+func splitValues(values []string) []string {
+	var result []string
+
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+
+	return result
+}
+
+// pass сообщает, передается ли сообщение m в момент now. Уведомления об устаревании
+// не прореживаются.
+func (f *streamFilter) pass(m Message, now time.Time) bool {
+	if len(f.modules) > 0 && !slices.Contains(f.modules, m.Module) {
+		return false
+	}
+
+	if len(f.messages) > 0 && !slices.Contains(f.messages, m.Msg) {
+		return false
+	}
+
+	if f.interval == 0 || m.Stale {
+		return true
+	}
+
+	key := m.Module + "/" + m.Msg
+
+	if last, ok := f.last[key]; ok && now.Sub(last) < f.interval {
+		return false
+	}
+
+	f.last[key] = now
+
+	return true
+}
+
+// prepare применяет к сообщению способ передачи изображений.
+func (f *streamFilter) prepare(m Message) Message {
+	if m.Image == nil {
+		return m
+	}
+
+	switch f.images {
+	case imagesNone:
+		m.Image = nil
+	case imagesRef:
+		img := *m.Image
+		img.Data = nil
+		m.Image = &img
+	}
+
+	return m
+}
+
+// stream передает сообщения телеметрии как Server-Sent Events: событие message на каждое
+// сообщение и stale на устаревание режима.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	if s.telemetry == nil {
+		s.writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "telemetry is not available"})
+		return
+	}
+
+	filter, err := parseStreamFilter(r.URL.Query())
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming is not supported"})
+		return
+	}
+
+	messages, cancel := s.telemetry.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	s.log.Infof("telemetry stream to %s started", r.RemoteAddr)
+	defer s.log.Infof("telemetry stream to %s finished", r.RemoteAddr)
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			if !filter.pass(m, time.Now()) {
+				continue
+			}
+
+			err = writeEvent(w, m.event(), filter.prepare(m))
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func (m Message) event() string {
+	if m.Stale {
+		return "stale"
+	}
+
+	return "message"
+}
+
+func writeEvent(w http.ResponseWriter, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+
+	return err
+}
+
+func (s *Server) getImage(w http.ResponseWriter, r *http.Request) {
+	if s.telemetry == nil {
+		s.writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "telemetry is not available"})
+		return
+	}
+
+	systemTime, err := strconv.ParseUint(r.PathValue("time"), 10, 32)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("bad system time: %v", err)})
+		return
+	}
+
+	img, err := s.telemetry.Image(uint32(systemTime))
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+
+	_, err = w.Write(img)
+	if err != nil {
+		s.log.Errorf("cannot write image: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTelemetry struct {
+	messages chan Message
+	images   map[uint32][]byte
+}
+
+func (t *fakeTelemetry) Subscribe() (<-chan Message, func()) {
+	return t.messages, func() {}
+}
+
+func (t *fakeTelemetry) Image(systemTime uint32) ([]byte, error) {
+	img, ok := t.images[systemTime]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrImageNotFound, systemTime)
+	}
+
+	return img, nil
+}
+
+func TestStreamFilter(t *testing.T) {
+	t.Run("фильтр по модулям и режимам", func(t *testing.T) {
+		f, err := parseStreamFilter(url.Values{"module": {"gnss,imu"}, "msg": {"21"}, "rate": {"0"}})
+		require.NoError(t, err)
+
+		now := time.Now()
+		require.True(t, f.pass(Message{Module: "gnss", Msg: "0x15"}, now))
+		require.True(t, f.pass(Message{Module: "gnss", Msg: "0x15"}, now))
+		require.False(t, f.pass(Message{Module: "gnss", Msg: "0x14"}, now))
+		require.False(t, f.pass(Message{Module: "lidar", Msg: "0x15"}, now))
+	})
+
+	t.Run("прореживание по модулю и режиму", func(t *testing.T) {
+		f, err := parseStreamFilter(url.Values{"rate": {"2"}})
+		require.NoError(t, err)
+
+		start := time.Now()
+		require.True(t, f.pass(Message{Module: "imu", Msg: "0x14"}, start))
+		require.False(t, f.pass(Message{Module: "imu", Msg: "0x14"}, start.Add(100*time.Millisecond)))
+		require.True(t, f.pass(Message{Module: "gnss", Msg: "0x14"}, start.Add(100*time.Millisecond)))
+		require.True(t, f.pass(Message{Module: "imu", Msg: "0x14", Stale: true}, start.Add(200*time.Millisecond)))
+		require.True(t, f.pass(Message{Module: "imu", Msg: "0x14"}, start.Add(500*time.Millisecond)))
+	})
+
+	t.Run("изображения", func(t *testing.T) {
+		m := Message{Image: &Image{Ref: ImageRef(7), Size: 3, Data: []byte{1, 2, 3}}}
+
+		f, err := parseStreamFilter(url.Values{})
+		require.NoError(t, err)
+		require.Nil(t, f.prepare(m).Image.Data)
+		require.Equal(t, []byte{1, 2, 3}, m.Image.Data)
+
+		f, err = parseStreamFilter(url.Values{"images": {"base64"}})
+		require.NoError(t, err)
+		require.Equal(t, []byte{1, 2, 3}, f.prepare(m).Image.Data)
+
+		f, err = parseStreamFilter(url.Values{"images": {"none"}})
+		require.NoError(t, err)
+		require.Nil(t, f.prepare(m).Image)
+	})
+
+	t.Run("неверные параметры", func(t *testing.T) {
+		for _, q := range []url.Values{{"msg": {"0x100"}}, {"rate": {"-1"}}, {"rate": {"fast"}}, {"images": {"png"}}} {
+			_, err := parseStreamFilter(q)
+			require.Error(t, err, q)
+		}
+	})
+}
+
+func TestStream(t *testing.T) {
+	tlm := &fakeTelemetry{
+		messages: make(chan Message, 10),
+		images:   map[uint32][]byte{7: {0xff, 0xd8}},
+	}
+
+	srv := httptest.NewServer(NewServer(&fakeController{}).WithTelemetry(tlm).Handler())
+	defer srv.Close()
+
+	t.Run("поток сообщений", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/stream?module=gnss&images=base64", nil)
+		require.NoError(t, err)
+
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		tlm.messages <- Message{Module: "lidar", Msg: "0x14"}
+		tlm.messages <- Message{Module: "gnss", Msg: "0x15", SystemTime: 1000}
+		tlm.messages <- Message{Module: "gnss", Msg: "0x15", Stale: true}
+
+		r := bufio.NewReader(resp.Body)
+
+		readEvent := func() (string, Message) {
+			event, err := r.ReadString('\n')
+			require.NoError(t, err)
+
+			data, err := r.ReadString('\n')
+			require.NoError(t, err)
+
+			_, err = r.ReadString('\n')
+			require.NoError(t, err)
+
+			var m Message
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &m))
+
+			return strings.TrimSpace(strings.TrimPrefix(event, "event: ")), m
+		}
+
+		event, m := readEvent()
+		require.Equal(t, "message", event)
+		require.Equal(t, uint32(1000), m.SystemTime)
+
+		event, m = readEvent()
+		require.Equal(t, "stale", event)
+		require.True(t, m.Stale)
+	})
+
+	t.Run("неверные параметры потока", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodGet, "/api/v1/stream?rate=-1", "", nil))
+	})
+
+	t.Run("изображение по ссылке", func(t *testing.T) {
+		resp, err := srv.Client().Get(srv.URL + ImageRef(7))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+
+		require.Equal(t, http.StatusNotFound, do(t, srv, http.MethodGet, ImageRef(8), "", nil))
+	})
+}
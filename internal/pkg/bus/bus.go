@@ -0,0 +1,213 @@
+// Package bus предоставляет шину сообщений: приемники публикуют полученные сообщения,
+// обработчики подписываются на них по фильтру модулей и идентификаторов сообщений
+package bus
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DefaultQueueSize размер очереди подписчика по умолчанию
+const DefaultQueueSize = 16
+
+// Handler обработчик сообщений шины
+type Handler interface {
+	Handle(msg proto.Message) error
+}
+
+// HandlerFunc позволяет использовать функцию как обработчик
+type HandlerFunc func(msg proto.Message) error
+
+func (f HandlerFunc) Handle(msg proto.Message) error {
+	return f(msg)
+}
+
+// Filter фильтр сообщений подписчика. Пустой список не ограничивает соответствующее поле.
+type Filter struct {
+	Modules  []proto.ModuleID
+	Messages []proto.MessageID
+}
+
+// Match проверяет, подходит ли сообщение msg под фильтр.
+func (f Filter) Match(msg proto.Message) bool {
+	if len(f.Modules) > 0 && !slices.Contains(f.Modules, msg.ModuleID) {
+		return false
+	}
+
+	if len(f.Messages) > 0 && !slices.Contains(f.Messages, msg.MsgID) {
+		return false
+	}
+
+	return true
+}
+
+// SubscriptionStats счетчики сообщений подписчика
+type SubscriptionStats struct {
+	Handled int
+	Failed  int
+	Dropped int
+}
+
+// Subscription подписка обработчика на сообщения шины. Каждая подписка имеет свою очередь и
+// горутину, поэтому медленный обработчик не задерживает прием и других подписчиков. При
+// заполнении очереди новые сообщения отбрасываются.
+type Subscription struct {
+	name    string
+	filter  Filter
+	handler Handler
+	log     logger.Logger
+	queue   chan proto.Message
+
+	mu    sync.Mutex
+	stats SubscriptionStats
+}
+
+func (s *Subscription) WithQueueSize(size int) *Subscription {
+	if size > 0 {
+		s.queue = make(chan proto.Message, size)
+	}
+
+	return s
+}
+
+func (s *Subscription) WithLogger(log logger.Logger) *Subscription {
+	s.log = log
+	return s
+}
+
+func (s *Subscription) Name() string {
+	return s.name
+}
+
+func (s *Subscription) Stats() SubscriptionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
+
+// QueueLen возвращает количество сообщений в очереди подписчика.
+func (s *Subscription) QueueLen() int {
+	return len(s.queue)
+}
+
+func (s *Subscription) offer(msg proto.Message) {
+	if !s.filter.Match(msg) {
+		return
+	}
+
+	select {
+	case s.queue <- msg:
+	default:
+		s.mu.Lock()
+		s.stats.Dropped++
+		dropped := s.stats.Dropped
+		s.mu.Unlock()
+
+		s.log.Warnf("queue is full, drop message %s (dropped: %d)", msg, dropped)
+	}
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-s.queue:
+			err := s.handle(msg)
+
+			s.mu.Lock()
+			if err != nil {
+				s.stats.Failed++
+			} else {
+				s.stats.Handled++
+			}
+			s.mu.Unlock()
+
+			if err != nil {
+				s.log.Errorf("cannot handle message %s: %v", msg, err)
+			}
+		}
+	}
+}
+
+func (s *Subscription) handle(msg proto.Message) (err error) {
+	// паника обработчика не должна останавливать подписку
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panic: %v", p)
+		}
+	}()
+
+	return s.handler.Handle(msg)
+}
+
+// Bus шина сообщений
+type Bus struct {
+	mu   sync.RWMutex
+	subs []*Subscription
+}
+
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe подписывает обработчик h на сообщения, подходящие под фильтр. Подписка должна быть
+// создана до запуска шины.
+func (b *Bus) Subscribe(name string, filter Filter, h Handler) *Subscription {
+	sub := &Subscription{
+		name:    name,
+		filter:  filter,
+		handler: h,
+		log:     logger.DummyLogger{},
+		queue:   make(chan proto.Message, DefaultQueueSize),
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Subscriptions возвращает подписки шины.
+func (b *Bus) Subscriptions() []*Subscription {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return slices.Clone(b.subs)
+}
+
+// Publish ставит сообщение в очереди подходящих подписчиков. Не блокируется.
+func (b *Bus) Publish(msg proto.Message) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs {
+		sub.offer(msg)
+	}
+}
+
+// Run запускает обработку сообщений всех подписчиков до отмены контекста.
+func (b *Bus) Run(ctx context.Context) {
+	b.mu.RLock()
+	subs := slices.Clone(b.subs)
+	b.mu.RUnlock()
+
+	var wg sync.WaitGroup
+
+	for _, sub := range subs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			sub.run(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
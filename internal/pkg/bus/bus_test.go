@@ -0,0 +1,98 @@
+package bus
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func message(id proto.ModuleID, msgID proto.MessageID) proto.Message {
+	payload, _ := proto.NewPayload(id)
+	return proto.Message{ModuleID: id, MsgID: msgID, Payload: payload}
+}
+
+func TestFilter(t *testing.T) {
+	f := Filter{Modules: []proto.ModuleID{proto.CameraModuleID}, Messages: []proto.MessageID{proto.WritingModeB}}
+
+	require.True(t, f.Match(message(proto.CameraModuleID, proto.WritingModeB)))
+	require.False(t, f.Match(message(proto.CameraModuleID, proto.WritingModeA)))
+	require.False(t, f.Match(message(proto.GNSSModuleID, proto.WritingModeB)))
+	require.True(t, Filter{}.Match(message(proto.GNSSModuleID, proto.WritingModeA)))
+}
+
+func TestBus(t *testing.T) {
+	b := New()
+
+	var gnss, all atomic.Int32
+
+	b.Subscribe("gnss", Filter{Modules: []proto.ModuleID{proto.GNSSModuleID}}, HandlerFunc(func(proto.Message) error {
+		gnss.Add(1)
+		return nil
+	}))
+
+	b.Subscribe("all", Filter{}, HandlerFunc(func(proto.Message) error {
+		all.Add(1)
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go b.Run(ctx)
+
+	b.Publish(message(proto.GNSSModuleID, proto.WritingModeA))
+	b.Publish(message(proto.IMUModuleID, proto.WritingModeA))
+
+	require.Eventually(t, func() bool {
+		return gnss.Load() == 1 && all.Load() == 2
+	}, time.Second, time.Millisecond)
+
+	t.Run("медленный обработчик не блокирует публикацию", func(t *testing.T) {
+		b := New()
+
+		release := make(chan struct{})
+		sub := b.Subscribe("slow", Filter{}, HandlerFunc(func(proto.Message) error {
+			<-release
+			return nil
+		})).WithQueueSize(1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go b.Run(ctx)
+
+		for range 5 {
+			b.Publish(message(proto.GNSSModuleID, proto.WritingModeA))
+		}
+
+		close(release)
+
+		require.Eventually(t, func() bool {
+			stats := sub.Stats()
+			return stats.Handled+stats.Dropped == 5
+		}, time.Second, time.Millisecond)
+		require.Positive(t, sub.Stats().Dropped)
+	})
+
+	t.Run("паника обработчика", func(t *testing.T) {
+		b := New()
+
+		sub := b.Subscribe("panic", Filter{}, HandlerFunc(func(proto.Message) error {
+			panic("boom")
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go b.Run(ctx)
+
+		b.Publish(message(proto.GNSSModuleID, proto.WritingModeA))
+		b.Publish(message(proto.GNSSModuleID, proto.WritingModeA))
+
+		require.Eventually(t, func() bool { return sub.Stats().Failed == 2 }, time.Second, time.Millisecond)
+	})
+}
@@ -0,0 +1,205 @@
+package communication
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// bitsPerByte количество бит на байт при передаче по UART в формате 8N1
+	bitsPerByte = 10
+	// DefaultLinkUtilization доля пропускной способности линии, отводимая под бюджет по умолчанию
+	DefaultLinkUtilization = 0.8
+	// DefaultBudgetBurst интервал, за который может быть накоплен запас бюджета
+	DefaultBudgetBurst = 250 * time.Millisecond
+	// DefaultBudgetReportInterval период предупреждений о прореживании измерений
+	DefaultBudgetReportInterval = 10 * time.Second
+
+	// cameraChunkHeaderSize номер части и количество частей изображения в полезной нагрузке
+	cameraChunkHeaderSize = 2
+)
+
+// FrameAirtime возвращает время передачи size байт по последовательной линии со скоростью baudRate.
+func FrameAirtime(size, baudRate int) time.Duration {
+	if baudRate <= 0 {
+		return 0
+	}
+
+	return time.Duration(size*bitsPerByte) * time.Second / time.Duration(baudRate)
+}
+
+// LinkBudget возвращает бюджет линии в байт/с. Положительное значение configured задает бюджет
+// явно, отрицательное отключает ограничение. При нулевом configured бюджет вычисляется как
+// DefaultLinkUtilization от пропускной способности последовательной линии, для линий без
+// скорости (сокеты) ограничение отключено. Возвращаемый 0 означает отсутствие ограничения.
+func LinkBudget(baudRate, configured int) int {
+	switch {
+	case configured > 0:
+		return configured
+	case configured < 0, baudRate <= 0:
+		return 0
+	default:
+		return int(float64(baudRate/bitsPerByte) * DefaultLinkUtilization)
+	}
+}
+
+// TokenBucket ограничитель скорости по алгоритму маркерной корзины. Корзина допускает уход
+// в долг: кадр пропускается, если запас неотрицателен, даже когда его стоимость больше запаса.
+// Это позволяет передавать кадры крупнее запаса корзины, сохраняя среднюю скорость.
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewTokenBucket создает корзину со скоростью rate единиц в секунду и запасом burst.
+func NewTokenBucket(rate, burst float64) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		now:    time.Now,
+	}
+}
+
+// Allow списывает n единиц, если запас корзины неотрицателен.
+func (b *TokenBucket) Allow(n int) bool {
+	now := b.now()
+
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+
+	b.last = now
+
+	if b.tokens < 0 {
+		return false
+	}
+
+	b.tokens -= float64(n)
+
+	return true
+}
+
+// budget ограничивает объем передаваемых отправителем данных и прореживает измерения,
+// не укладывающиеся в бюджет линии.
+type budget struct {
+	bucket    *TokenBucket
+	limit     int
+	baudRate  int
+	log       logger.Logger
+	measures  int
+	decimated int
+	bytes     int
+	since     time.Time
+}
+
+func newBudget(limit, baudRate int, log logger.Logger) *budget {
+	burst := max(float64(limit)*DefaultBudgetBurst.Seconds(), 1)
+
+	if capacity := baudRate / bitsPerByte; baudRate > 0 && limit > capacity {
+		log.Warnf("link budget %d B/s exceeds link capacity %d B/s at %d baud", limit, capacity, baudRate)
+	}
+
+	return &budget{
+		bucket:   NewTokenBucket(float64(limit), burst),
+		limit:    limit,
+		baudRate: baudRate,
+		log:      log,
+		since:    time.Now(),
+	}
+}
+
+// allow решает, передавать ли измерение стоимостью cost байт.
+func (b *budget) allow(cost int) bool {
+	b.measures++
+	b.bytes += cost
+
+	ok := b.bucket.Allow(cost)
+	if !ok {
+		b.decimated++
+	}
+
+	b.report()
+
+	return ok
+}
+
+func (b *budget) report() {
+	elapsed := time.Since(b.since)
+	if elapsed < DefaultBudgetReportInterval {
+		return
+	}
+
+	if b.decimated > 0 {
+		b.log.Warnf(
+			"measures demand %.0f B/s exceeds link budget %d B/s: %d of %d measures decimated in last %v",
+			float64(b.bytes)/elapsed.Seconds(), b.limit, b.decimated, b.measures, elapsed.Truncate(time.Second),
+		)
+	}
+
+	b.measures, b.decimated, b.bytes = 0, 0, 0
+	b.since = time.Now()
+}
+
+// frameCost возвращает количество байт, передаваемых по линии для доставки payload:
+// фрейм сообщения и, при гарантированной доставке, ответный фрейм подтверждения.
+func frameCost(payloadSize int, sync bool) int {
+	cost := proto.FrameSize(payloadSize)
+	if sync {
+		cost += proto.FrameSize(0)
+	}
+
+	return cost
+}
+
+// CheckRate предупреждает, если измерения data с периодом period не укладываются в бюджет линии.
+func (s *Sender) CheckRate(data proto.Packer, period time.Duration) error {
+	if s.budget == nil || period <= 0 {
+		return nil
+	}
+
+	cost, err := s.cost(data)
+	if err != nil {
+		return err
+	}
+
+	demand := float64(cost) / period.Seconds()
+	if demand <= float64(s.budget.limit) {
+		return nil
+	}
+
+	s.budget.log.Warnf(
+		"measure period %v needs %.0f B/s (%d B per measure, airtime %v), link budget is %d B/s: about 1 of %.0f measures will be sent",
+		period, demand, cost, FrameAirtime(cost, s.budget.baudRate), s.budget.limit, math.Ceil(demand/float64(s.budget.limit)),
+	)
+
+	return nil
+}
+
+// cost возвращает количество байт, передаваемых по линии для доставки измерения data.
+func (s *Sender) cost(data proto.Packer) (int, error) {
+	return s.costOf(s.addr, s.mode, data)
+}
+
+// costOf возвращает количество байт, передаваемых по линии для доставки сообщения модуля addr.
+func (s *Sender) costOf(addr proto.ModuleID, mode proto.MessageID, data proto.Packer) (int, error) {
+	if cameraData, ok := data.(*proto.CameraData); ok && chunkedRequestModules[addr] {
+		size := len(cameraData.RawImagePart)
+		chunks := max((size+s.chunkSize-1)/s.chunkSize, 1)
+
+		return size + chunks*frameCost(cameraChunkHeaderSize, s.sync), nil
+	}
+
+	payload, err := data.Pack(mode)
+	if err != nil {
+		return 0, fmt.Errorf("cannot pack measure: %w", err)
+	}
+
+	return frameCost(len(payload), s.sync), nil
+}
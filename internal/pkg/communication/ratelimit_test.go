@@ -0,0 +1,93 @@
+package communication
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameAirtime(t *testing.T) {
+	require.Equal(t, time.Second, FrameAirtime(960, 9600))
+	require.Equal(t, 100*time.Millisecond, FrameAirtime(480, 48000))
+	require.Zero(t, FrameAirtime(10, 0))
+}
+
+func TestLinkBudget(t *testing.T) {
+	t.Run("вычисление по скорости порта", func(t *testing.T) {
+		require.Equal(t, 384, LinkBudget(4800, 0))
+	})
+
+	t.Run("явно заданный бюджет", func(t *testing.T) {
+		require.Equal(t, 100, LinkBudget(4800, 100))
+	})
+
+	t.Run("отключение ограничения", func(t *testing.T) {
+		require.Zero(t, LinkBudget(4800, -1))
+		require.Zero(t, LinkBudget(0, 0))
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Run("равномерное прореживание", func(t *testing.T) {
+		now := time.Unix(0, 0)
+
+		// 100 байт/с, измерения по 10 байт каждые 10 мс: проходит каждое десятое
+		b := NewTokenBucket(100, 0)
+		b.now = func() time.Time { return now }
+
+		allowed := 0
+
+		for range 1000 {
+			if b.Allow(10) {
+				allowed++
+			}
+
+			now = now.Add(10 * time.Millisecond)
+		}
+
+		require.InDelta(t, 100, allowed, 1)
+	})
+
+	t.Run("кадр крупнее запаса корзины", func(t *testing.T) {
+		now := time.Unix(0, 0)
+
+		b := NewTokenBucket(100, 10)
+		b.now = func() time.Time { return now }
+
+		require.True(t, b.Allow(500))
+		require.False(t, b.Allow(1))
+
+		now = now.Add(5 * time.Second)
+		require.True(t, b.Allow(1))
+	})
+}
+
+func TestSenderCost(t *testing.T) {
+	t.Run("одиночный фрейм с подтверждением", func(t *testing.T) {
+		s := NewSender(nil, proto.CheckModuleID, proto.WritingModeA).WithSync(true)
+
+		cost, err := s.cost(&proto.CheckData{})
+		require.NoError(t, err)
+		require.Equal(t, proto.FrameSize(4)+proto.FrameSize(0), cost)
+	})
+
+	t.Run("изображение из нескольких частей", func(t *testing.T) {
+		s := NewSender(nil, proto.CameraModuleID, proto.WritingModeA).WithChunkSize(100)
+
+		cost, err := s.cost(&proto.CameraData{RawImagePart: make([]byte, 250)})
+		require.NoError(t, err)
+		require.Equal(t, 250+3*proto.FrameSize(cameraChunkHeaderSize), cost)
+	})
+
+	t.Run("учет прореженных измерений", func(t *testing.T) {
+		s := NewSender(nil, proto.CheckModuleID, proto.WritingModeA)
+		s.budget = newBudget(1, 0, logger.DummyLogger{})
+
+		require.True(t, s.budget.allow(15))
+		require.False(t, s.budget.allow(15))
+		require.Equal(t, 1, s.budget.decimated)
+	})
+}
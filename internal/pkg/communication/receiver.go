@@ -1,9 +1,11 @@
 package communication
 
 import (
+	"asvsoft/internal/pkg/linkstats"
 	"asvsoft/internal/pkg/logger"
 	"asvsoft/internal/pkg/proto"
 	"asvsoft/internal/pkg/utils"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -15,8 +17,16 @@ type Receiver struct {
 	chunkSize    int
 	retriesLimit int
 	log          logger.Logger
+	stats        *linkstats.Stats
+	counter      *countingReader
+	images       map[proto.ModuleID]*partialImage
+	frameHook    FrameHook
 }
 
+// FrameHook вызывается для каждого прочитанного фрейма с результатом его распаковки, в том числе
+// для фреймов с неверной контрольной суммой. Фрейм не должен изменяться.
+type FrameHook func(frame []byte, err error)
+
 func NewReceiver(rwc io.ReadWriteCloser, moduleID proto.ModuleID) *Receiver {
 	return &Receiver{
 		rwc:          rwc,
@@ -24,7 +34,22 @@ func NewReceiver(rwc io.ReadWriteCloser, moduleID proto.ModuleID) *Receiver {
 		log:          logger.DummyLogger{},
 		chunkSize:    DefaultChunkSize,
 		retriesLimit: DefaultRetriesLimit,
+		stats:        linkstats.New(),
+		counter:      &countingReader{r: rwc},
+		images:       make(map[proto.ModuleID]*partialImage),
+	}
+}
+
+// Stats возвращает статистику канала связи получателя. Количество переоткрытий берется
+// из порта-источника, если он ведет собственную статистику.
+func (r *Receiver) Stats() linkstats.Snapshot {
+	snapshot := r.stats.Snapshot()
+
+	if p, ok := r.rwc.(linkstats.Provider); ok {
+		snapshot.Reopens += p.Stats().Reopens
 	}
+
+	return snapshot
 }
 
 func (r *Receiver) WithChunkSize(chunkSize int) *Receiver {
@@ -42,50 +67,87 @@ func (r *Receiver) WithSync(sync bool) *Receiver {
 	return r
 }
 
+func (r *Receiver) WithFrameHook(hook FrameHook) *Receiver {
+	r.frameHook = hook
+	return r
+}
+
 func (r *Receiver) WithLogger(log logger.Logger) *Receiver {
 	r.log = log
 	return r
 }
 
-// Receive читает данный из r.rc и распаковывает пакет в сообщение.
+// Receive читает данный из r.rc и распаковывает пакет в сообщение. Части изображения камеры
+// собираются отдельно для каждого модуля, поэтому между ними могут приходить сообщения
+// других модулей, разделяющих канал связи.
 func (r *Receiver) Receive() (proto.Message, error) {
-	msg, err := r.receive()
-	if err != nil {
-		return msg, err
-	}
+	for {
+		msg, err := r.receive()
+		if err != nil {
+			return msg, err
+		}
 
-	// TODO: унести в отдельный метод
-	if msg.ModuleID == proto.CameraModuleID && msg.MsgID == proto.WritingModeB {
-		payload, ok := msg.Payload.(*proto.CameraData)
-		if !ok {
-			return msg, fmt.Errorf("failed to handle chunked message: unexpected type")
+		if msg.ModuleID != proto.CameraModuleID || msg.MsgID != proto.WritingModeB {
+			return msg, nil
 		}
 
-		rawImage := make([]byte, 0, int(payload.TotalChunckes)*r.chunkSize)
-		rawImage = append(rawImage, payload.RawImagePart...)
+		msg, complete, err := r.assemble(msg)
+		if err != nil || complete {
+			return msg, err
+		}
+	}
+}
 
-		for payload.CurrentChunck < payload.TotalChunckes {
-			msg, err := r.receive()
-			if err != nil {
-				return msg, err
-			}
+// partialImage изображение, собираемое из частей
+type partialImage struct {
+	data  []byte
+	next  uint8
+	total uint8
+}
 
-			r.log.Debugf("received chunked message: %s", msg)
+// assemble добавляет часть изображения к собираемому изображению модуля. Возвращает true
+// и сообщение с изображением целиком после получения последней части.
+func (r *Receiver) assemble(msg proto.Message) (proto.Message, bool, error) {
+	payload, ok := msg.Payload.(*proto.CameraData)
+	if !ok {
+		return msg, false, fmt.Errorf("failed to handle chunked message: unexpected type")
+	}
 
-			payload, ok = msg.Payload.(*proto.CameraData)
-			if !ok {
-				return msg, fmt.Errorf("failed to handle chunked message: unexpected type")
-			}
+	r.log.Debugf("received chunked message: %s", msg)
 
-			rawImage = append(rawImage, payload.RawImagePart...)
+	image, ok := r.images[msg.ModuleID]
+	if payload.CurrentChunck <= 1 || !ok {
+		image = &partialImage{
+			data:  make([]byte, 0, int(payload.TotalChunckes)*r.chunkSize),
+			next:  1,
+			total: payload.TotalChunckes,
 		}
+		r.images[msg.ModuleID] = image
+	}
 
-		msg.Payload = &proto.CameraData{RawImagePart: rawImage}
-		msg.CheckSum = 0
-		msg.PayloadSize = 0
+	if payload.CurrentChunck != image.next || payload.TotalChunckes != image.total {
+		delete(r.images, msg.ModuleID)
+
+		return msg, false, fmt.Errorf(
+			"failed to handle chunked message: got chunk %d/%d, want %d/%d",
+			payload.CurrentChunck, payload.TotalChunckes, image.next, image.total,
+		)
 	}
 
-	return msg, err
+	image.data = append(image.data, payload.RawImagePart...)
+	image.next++
+
+	if payload.CurrentChunck < payload.TotalChunckes {
+		return msg, false, nil
+	}
+
+	delete(r.images, msg.ModuleID)
+
+	msg.Payload = &proto.CameraData{RawImagePart: image.data}
+	msg.CheckSum = 0
+	msg.PayloadSize = 0
+
+	return msg, true, nil
 }
 
 func (r *Receiver) receive() (proto.Message, error) {
@@ -94,16 +156,43 @@ func (r *Receiver) receive() (proto.Message, error) {
 		err error
 	)
 
+	attempt := 0
+
 	err = utils.RunWithRetries(func() error {
-		rawData, err := proto.Read(r.rwc)
+		if attempt++; attempt > 1 {
+			r.stats.Retries.Inc()
+		}
+
+		r.counter.n = 0
+
+		rawData, err := proto.Read(r.counter)
+		r.stats.BytesReceived.Add(r.counter.n)
+
 		if err != nil {
 			return fmt.Errorf("read msg failed: %v", err)
 		}
 
+		r.stats.FramesReceived.Inc()
+
+		// байты перед заголовком фрейма были отброшены при поиске синхронизации
+		if skipped := r.counter.n - len(rawData); skipped > 0 {
+			r.stats.Resyncs.Inc()
+			r.log.Debugf("resync: %d bytes skipped before frame", skipped)
+		}
+
 		r.log.Debugf("raw received msg: %+v", rawData)
 
 		err = msg.Unmarshal(rawData)
+
+		if r.frameHook != nil {
+			r.frameHook(rawData, err)
+		}
+
 		if err != nil {
+			if errors.Is(err, proto.ErrChecksumMismatch) {
+				r.stats.ChecksumFailures.Inc()
+			}
+
 			_ = r.sendMsg(proto.ResponseFail)
 
 			return fmt.Errorf("unmarshal msg failed: %v", err)
@@ -132,7 +221,7 @@ func (r *Receiver) sendMsg(msgID proto.MessageID) error {
 		return err
 	}
 
-	_, err = r.rwc.Write(rawResp)
+	n, err := r.rwc.Write(rawResp)
 	if err != nil {
 		err = fmt.Errorf("failed to write response: %w", err)
 		r.log.Errorf("%v", err)
@@ -140,6 +229,9 @@ func (r *Receiver) sendMsg(msgID proto.MessageID) error {
 		return err
 	}
 
+	r.stats.FramesSent.Inc()
+	r.stats.BytesSent.Add(n)
+
 	r.log.Debugf("successfully sent %d msg", msgID)
 
 	return nil
@@ -148,3 +240,16 @@ func (r *Receiver) sendMsg(msgID proto.MessageID) error {
 func (r *Receiver) Close() error {
 	return r.rwc.Close()
 }
+
+// countingReader подсчитывает количество прочитанных байт.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+
+	return n, err
+}
@@ -0,0 +1,94 @@
+package communication
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bufferConn struct {
+	*bytes.Reader
+	written bytes.Buffer
+}
+
+func (b *bufferConn) Write(p []byte) (int, error) {
+	return b.written.Write(p)
+}
+
+func (b *bufferConn) Close() error {
+	return nil
+}
+
+func TestReceiverStats(t *testing.T) {
+	frame, err := proto.NewMessage(proto.CheckModuleID, proto.WritingModeA, &proto.CheckData{Value: 7}).Marshal()
+	require.NoError(t, err)
+
+	corrupted := bytes.Clone(frame)
+	corrupted[len(corrupted)-1]++
+
+	// мусор перед фреймом, затем фрейм с неверной контрольной суммой и корректный фрейм
+	var stream []byte
+	stream = append(stream, 0x01, 0x02, 0x03)
+	stream = append(stream, corrupted...)
+	stream = append(stream, frame...)
+
+	conn := &bufferConn{Reader: bytes.NewReader(stream)}
+
+	rcvr := NewReceiver(conn, proto.ControlModuleID).WithSync(true).WithRetriesLimit(2)
+
+	msg, err := rcvr.Receive()
+	require.NoError(t, err)
+	require.Equal(t, &proto.CheckData{Value: 7}, msg.Payload)
+
+	stats := rcvr.Stats()
+	require.Equal(t, uint64(2), stats.FramesReceived)
+	require.Equal(t, uint64(len(stream)), stats.BytesReceived)
+	require.Equal(t, uint64(1), stats.Resyncs)
+	require.Equal(t, uint64(1), stats.ChecksumFailures)
+	require.Equal(t, uint64(1), stats.Retries)
+	// ответ об ошибке на поврежденный фрейм и подтверждение корректного
+	require.Equal(t, uint64(2), stats.FramesSent)
+	require.Equal(t, uint64(conn.written.Len()), stats.BytesSent)
+}
+
+func TestReceiverInterleavedChunks(t *testing.T) {
+	marshal := func(msg *proto.Message) []byte {
+		raw, err := msg.Marshal()
+		require.NoError(t, err)
+
+		return raw
+	}
+
+	chunk := func(current, total uint8, data ...byte) []byte {
+		return marshal(proto.NewMessage(proto.CameraModuleID, proto.WritingModeB, &proto.CameraData{
+			RawImagePart:  data,
+			CurrentChunck: current,
+			TotalChunckes: total,
+		}))
+	}
+
+	var stream []byte
+	stream = append(stream, chunk(1, 2, 0xAA, 0xBB)...)
+	stream = append(stream, marshal(proto.NewMessage(proto.CheckModuleID, proto.WritingModeA, &proto.CheckData{Value: 1}))...)
+	stream = append(stream, chunk(2, 2, 0xCC)...)
+
+	rcvr := NewReceiver(&bufferConn{Reader: bytes.NewReader(stream)}, proto.ControlModuleID)
+
+	msg, err := rcvr.Receive()
+	require.NoError(t, err)
+	require.Equal(t, proto.CheckModuleID, msg.ModuleID)
+
+	msg, err = rcvr.Receive()
+	require.NoError(t, err)
+	require.Equal(t, proto.CameraModuleID, msg.ModuleID)
+	require.Equal(t, &proto.CameraData{RawImagePart: []byte{0xAA, 0xBB, 0xCC}}, msg.Payload)
+
+	t.Run("пропущенная часть изображения", func(t *testing.T) {
+		rcvr := NewReceiver(&bufferConn{Reader: bytes.NewReader(append(chunk(1, 3, 0x01), chunk(3, 3, 0x03)...))}, proto.ControlModuleID)
+
+		_, err := rcvr.Receive()
+		require.Error(t, err)
+	})
+}
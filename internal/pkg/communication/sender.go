@@ -2,9 +2,11 @@
 package communication
 
 import (
+	"asvsoft/internal/pkg/linkstats"
 	"asvsoft/internal/pkg/proto"
 	"asvsoft/internal/pkg/utils"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -22,6 +24,7 @@ func NewSender(m MeasureCloser, addr proto.ModuleID, mode proto.MessageID) *Send
 		mode:         mode,
 		chunkSize:    DefaultChunkSize,
 		retriesLimit: DefaultRetriesLimit,
+		stats:        linkstats.New(),
 	}
 }
 
@@ -49,6 +52,11 @@ type Sender struct {
 	chunkSize    int
 	retriesLimit int
 	sync         bool
+	budget       *budget
+
+	stats         *linkstats.Stats
+	statsInterval time.Duration
+	statsReport   bool
 }
 
 func (s *Sender) WithReadWriteCloser(rw io.ReadWriteCloser) *Sender {
@@ -61,7 +69,51 @@ func (s *Sender) WithSync(sync bool) *Sender {
 	return s
 }
 
-// Start асинхронно получает измерения от измерителя s.m и отправляет их в s.wc.
+// WithStatsInterval задает период вывода статистики канала связи в лог, 0 отключает вывод.
+func (s *Sender) WithStatsInterval(interval time.Duration) *Sender {
+	s.statsInterval = interval
+	return s
+}
+
+// WithStatsReport включает отправку статистики канала связи получателю с периодом вывода в лог.
+func (s *Sender) WithStatsReport(report bool) *Sender {
+	s.statsReport = report
+	return s
+}
+
+// Stats возвращает статистику канала связи отправителя. Количество переоткрытий берется
+// из порта-назначения, если он ведет собственную статистику.
+func (s *Sender) Stats() linkstats.Snapshot {
+	snapshot := s.stats.Snapshot()
+
+	if p, ok := s.rwc.(linkstats.Provider); ok {
+		snapshot.Reopens += p.Stats().Reopens
+	}
+
+	return snapshot
+}
+
+// Measurer возвращает измеритель отправителя.
+func (s *Sender) Measurer() MeasureCloser {
+	return s.m
+}
+
+// WithBudget ограничивает объем передаваемых данных бюджетом bytesPerSecond байт/с для линии
+// со скоростью baudRate (0 для линий без скорости). Измерения, не укладывающиеся в бюджет,
+// прореживаются. Нулевой бюджет отключает ограничение.
+func (s *Sender) WithBudget(bytesPerSecond, baudRate int) *Sender {
+	if bytesPerSecond <= 0 {
+		s.budget = nil
+		return s
+	}
+
+	s.budget = newBudget(bytesPerSecond, baudRate, log.StandardLogger())
+
+	return s
+}
+
+// Start асинхронно получает измерения от измерителя s.m и отправляет их в s.wc. Завершается
+// по сигналу, при исчерпании данных измерителя (ErrEndOfData) или неустранимой ошибке (ErrFatal).
 func (s *Sender) Start(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -69,31 +121,57 @@ func (s *Sender) Start(ctx context.Context) error {
 	quit := make(chan os.Signal, 2)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	measureChan := make(chan proto.Packer)
+	type timedMeasure struct {
+		data proto.Packer
+		at   time.Time
+	}
+
+	measureChan := make(chan timedMeasure)
+
+	var statsTick <-chan time.Time
+
+	if s.statsInterval > 0 {
+		ticker := time.NewTicker(s.statsInterval)
+		defer ticker.Stop()
+
+		statsTick = ticker.C
+	}
+
+	var fatalErr error
 
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				err := s.m.Close()
-				if err != nil {
-					log.Errorf("failed to close measurer: %v", err)
-				}
+		defer close(measureChan)
 
-				close(measureChan)
+		defer func() {
+			err := s.m.Close()
+			if err != nil {
+				log.Errorf("failed to close measurer: %v", err)
+			}
+		}()
 
-				return
-			default:
-				measure, err := s.m.Measure(ctx)
-				if err != nil {
-					log.Errorf("cannot read measure: %v", err)
+		for {
+			measure, err := s.m.Measure(ctx)
 
-					continue
-				}
+			switch {
+			case ctx.Err() != nil:
+				return
+			case errors.Is(err, ErrEndOfData):
+				log.Infof("measurer has no more data, stop sending")
+				return
+			case errors.Is(err, ErrFatal):
+				fatalErr = err
+				return
+			case err != nil:
+				log.Errorf("cannot read measure: %v", err)
+				continue
+			}
 
-				log.Infof("read measure: %s", measure)
+			log.Infof("read measure: %s", measure)
 
-				measureChan <- measure
+			select {
+			case measureChan <- timedMeasure{data: measure, at: time.Now()}:
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
@@ -104,15 +182,32 @@ LOOP:
 		case <-quit:
 			log.Infoln("signal called, cancel operations")
 			cancel()
+
 			break LOOP
-		case measure := <-measureChan:
-			err := s.Send(measure)
+		case measure, ok := <-measureChan:
+			if !ok {
+				break LOOP
+			}
+
+			s.stats.QueueLatency.Observe(time.Since(measure.at))
+
+			err := s.Send(measure.data)
 			if err != nil {
 				log.Errorf("cannot transmit measure: %v", err)
 			}
+		case <-statsTick:
+			s.logStats()
 		}
 	}
 
+	// ожидание закрытия измерителя: канал закрывается после записи fatalErr
+	for range measureChan {
+	}
+
+	if fatalErr != nil {
+		return fmt.Errorf("measurer failed: %w", fatalErr)
+	}
+
 	return nil
 }
 
@@ -124,6 +219,21 @@ var (
 
 // Send упаковывает измерения согласно унифицированному протоколу и отправляет пакет в s.rw.
 func (s *Sender) Send(data proto.Packer) error {
+	if s.budget != nil {
+		cost, err := s.cost(data)
+		if err != nil {
+			return err
+		}
+
+		if !s.budget.allow(cost) {
+			s.stats.Decimated.Inc()
+			log.Debugf("link budget exhausted, decimate measure: %s", data)
+			return nil
+		}
+
+		log.Debugf("measure cost: %d B, airtime: %v", cost, FrameAirtime(cost, s.budget.baudRate))
+	}
+
 	if chunkedRequestModules[s.addr] {
 		return s.chunkedSend(data)
 	}
@@ -132,13 +242,60 @@ func (s *Sender) Send(data proto.Packer) error {
 }
 
 func (s *Sender) send(data proto.Packer) error {
-	msg := proto.NewMessage(s.addr, s.mode, data)
+	return s.sendMessage(proto.NewMessage(s.addr, s.mode, data))
+}
 
+func (s *Sender) sendMessage(msg *proto.Message) error {
 	b, err := msg.Marshal()
 	if err != nil {
 		return fmt.Errorf("cannot marshal msg: %w", err)
 	}
 
+	return s.sendFrame(msg, b)
+}
+
+// Forward пересылает полученное сообщение msg, сохраняя его системное время. Изображения
+// камеры пересылаются частями размером s.chunkSize.
+func (s *Sender) Forward(msg proto.Message) error {
+	if s.budget != nil {
+		cost, err := s.costOf(msg.ModuleID, msg.MsgID, msg.Payload)
+		if err != nil {
+			return err
+		}
+
+		if !s.budget.allow(cost) {
+			s.stats.Decimated.Inc()
+			log.Debugf("link budget exhausted, decimate forwarded msg: %s", msg)
+
+			return nil
+		}
+	}
+
+	if chunkedRequestModules[msg.ModuleID] && msg.MsgID == proto.WritingModeB {
+		return s.sendChunks(msg.Payload, func(chunk proto.Packer) error {
+			return s.forward(proto.Message{
+				ModuleID:   msg.ModuleID,
+				MsgID:      msg.MsgID,
+				SystemTime: msg.SystemTime,
+				Payload:    chunk,
+			})
+		})
+	}
+
+	return s.forward(msg)
+}
+
+func (s *Sender) forward(msg proto.Message) error {
+	b, err := msg.MarshalKeepTime()
+	if err != nil {
+		return fmt.Errorf("cannot marshal msg: %w", err)
+	}
+
+	return s.sendFrame(&msg, b)
+}
+
+// sendFrame отправляет фрейм b сообщения msg и ожидает подтверждения.
+func (s *Sender) sendFrame(msg *proto.Message, b []byte) error {
 	if s.rwc == nil {
 		log.Debugf("s.wc == nil: mock sending msg: %+v", msg)
 		return nil
@@ -150,17 +307,32 @@ func (s *Sender) send(data proto.Packer) error {
 		log.Infof("sending msg: %s", msg)
 	}
 
-	err = utils.RunWithRetries(func() error {
-		_, err := s.rwc.Write(b)
+	attempt := 0
+
+	err := utils.RunWithRetries(func() error {
+		if attempt++; attempt > 1 {
+			s.stats.Retries.Inc()
+		}
+
+		start := time.Now()
+
+		n, err := s.rwc.Write(b)
 		if err != nil {
 			return fmt.Errorf("cannot write measures: %w", err)
 		}
 
+		s.stats.FramesSent.Inc()
+		s.stats.BytesSent.Add(n)
+
 		err = s.waitOK()
 		if err != nil {
 			return fmt.Errorf("failed to wait ok message: %w", err)
 		}
 
+		if s.sync {
+			s.stats.AckRTT.Observe(time.Since(start))
+		}
+
 		return nil
 	}, log.StandardLogger(), s.retriesLimit, 0)
 
@@ -174,6 +346,11 @@ func (s *Sender) send(data proto.Packer) error {
 }
 
 func (s *Sender) chunkedSend(data any) error {
+	return s.sendChunks(data, s.send)
+}
+
+// sendChunks разбивает изображение камеры на части и отправляет их функцией send.
+func (s *Sender) sendChunks(data any, send func(chunk proto.Packer) error) error {
 	cameraData, ok := data.(*proto.CameraData)
 	if !ok {
 		return fmt.Errorf("unexpected data")
@@ -198,7 +375,7 @@ func (s *Sender) chunkedSend(data any) error {
 
 		log.Debugf("sending msg %s", msg)
 
-		err := s.send(msg)
+		err := send(msg)
 		if err != nil {
 			return fmt.Errorf("failed to send #%d chunk, drop package: %w", i, err)
 		}
@@ -216,17 +393,26 @@ func (s *Sender) waitOK() error {
 
 	rawResp, err := proto.Read(s.rwc)
 	if err != nil {
+		s.stats.AckTimeouts.Inc()
 		return fmt.Errorf("failed to read ok message: %w", err)
 	}
 
+	s.stats.FramesReceived.Inc()
+	s.stats.BytesReceived.Add(len(rawResp))
+
 	var msg proto.Message
 
 	err = msg.Unmarshal(rawResp)
 	if err != nil {
+		if errors.Is(err, proto.ErrChecksumMismatch) {
+			s.stats.ChecksumFailures.Inc()
+		}
+
 		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
 	if msg.MsgID != proto.ResponseOK {
+		s.stats.AckFailures.Inc()
 		return fmt.Errorf("response is not ok: %d", msg.MsgID)
 	}
 
@@ -235,6 +421,45 @@ func (s *Sender) waitOK() error {
 	return nil
 }
 
+// SendStats отправляет получателю статистику канала связи.
+func (s *Sender) SendStats() error {
+	return s.sendMessage(proto.NewMessage(s.addr, proto.StatsReport, statsData(s.Stats())))
+}
+
+func (s *Sender) logStats() {
+	log.Infof("link stats: %s", s.Stats())
+
+	if !s.statsReport {
+		return
+	}
+
+	err := s.SendStats()
+	if err != nil {
+		log.Errorf("cannot send link stats: %v", err)
+	}
+}
+
+// statsData упаковывает статистику канала связи в сообщение протокола.
+func statsData(snapshot linkstats.Snapshot) *proto.StatsData {
+	return &proto.StatsData{
+		FramesSent:       uint32(snapshot.FramesSent),
+		BytesSent:        uint32(snapshot.BytesSent),
+		FramesReceived:   uint32(snapshot.FramesReceived),
+		BytesReceived:    uint32(snapshot.BytesReceived),
+		Retries:          uint32(snapshot.Retries),
+		AckTimeouts:      uint32(snapshot.AckTimeouts),
+		AckFailures:      uint32(snapshot.AckFailures),
+		ChecksumFailures: uint32(snapshot.ChecksumFailures),
+		Resyncs:          uint32(snapshot.Resyncs),
+		Reopens:          uint32(snapshot.Reopens),
+		Decimated:        uint32(snapshot.Decimated),
+		AckRTTMean:       uint32(snapshot.AckRTT.Mean().Microseconds()),
+		AckRTTMax:        uint32(snapshot.AckRTT.Max.Microseconds()),
+		QueueLatencyMean: uint32(snapshot.QueueLatency.Mean().Microseconds()),
+		QueueLatencyMax:  uint32(snapshot.QueueLatency.Max.Microseconds()),
+	}
+}
+
 func (s *Sender) Close() error {
 	if s.rwc == nil {
 		return nil
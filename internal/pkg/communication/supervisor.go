@@ -0,0 +1,270 @@
+package communication
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSupervisorMinBackoff = 100 * time.Millisecond
+	DefaultSupervisorMaxBackoff = 10 * time.Second
+	DefaultSupervisorReopenAt   = 10
+)
+
+var (
+	// ErrEndOfData измеритель исчерпал данные, дальнейшие измерения невозможны
+	ErrEndOfData = errors.New("end of data")
+	// ErrFatal неустранимая ошибка измерителя, повторные попытки бессмысленны
+	ErrFatal = errors.New("fatal error")
+)
+
+// SupervisorState состояние измерителя под наблюдением супервизора
+type SupervisorState int
+
+const (
+	StateOpening SupervisorState = iota
+	StateRunning
+	StateBackoff
+	StateStopped
+	StateFailed
+)
+
+// SupervisorStates все состояния супервизора
+var SupervisorStates = []SupervisorState{StateOpening, StateRunning, StateBackoff, StateStopped, StateFailed}
+
+func (s SupervisorState) String() string {
+	switch s {
+	case StateOpening:
+		return "opening"
+	case StateRunning:
+		return "running"
+	case StateBackoff:
+		return "backoff"
+	case StateStopped:
+		return "stopped"
+	case StateFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// SupervisorStatus снимок состояния супервизора
+type SupervisorStatus struct {
+	State SupervisorState
+	// Failures количество ошибок подряд
+	Failures int
+	// Reopens количество переоткрытий измерителя
+	Reopens int
+	// LastError последняя ошибка измерителя
+	LastError error
+}
+
+// Opener открывает измеритель: открывает порт-источник и выполняет инициализацию датчика.
+type Opener func() (MeasureCloser, error)
+
+// Supervisor оборачивает измеритель и изолирует отправителя от его сбоев. Ошибки измерения
+// классифицируются: ErrEndOfData останавливает измерения, ErrFatal приводит к аварийному
+// завершению, остальные считаются временными и повторяются с экспоненциальной задержкой.
+// После reopenAt временных ошибок подряд измеритель закрывается и открывается заново.
+type Supervisor struct {
+	open       Opener
+	m          MeasureCloser
+	log        logger.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	reopenAt   int
+
+	mu     sync.Mutex
+	status SupervisorStatus
+	opened bool
+}
+
+func NewSupervisor(open Opener) *Supervisor {
+	return &Supervisor{
+		open:       open,
+		log:        logger.DummyLogger{},
+		minBackoff: DefaultSupervisorMinBackoff,
+		maxBackoff: DefaultSupervisorMaxBackoff,
+		reopenAt:   DefaultSupervisorReopenAt,
+	}
+}
+
+func (s *Supervisor) WithLogger(log logger.Logger) *Supervisor {
+	s.log = log
+	return s
+}
+
+func (s *Supervisor) WithBackoff(minBackoff, maxBackoff time.Duration) *Supervisor {
+	s.minBackoff = minBackoff
+	s.maxBackoff = maxBackoff
+
+	return s
+}
+
+func (s *Supervisor) WithReopenAt(reopenAt int) *Supervisor {
+	s.reopenAt = reopenAt
+	return s
+}
+
+// Status возвращает текущее состояние супервизора.
+func (s *Supervisor) Status() SupervisorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.status
+}
+
+// Measure возвращает очередное измерение. Временные ошибки обрабатываются внутри,
+// наружу возвращаются только ошибки контекста, ErrEndOfData и ErrFatal.
+func (s *Supervisor) Measure(ctx context.Context) (proto.Packer, error) {
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		status := s.Status()
+
+		switch status.State {
+		case StateStopped, StateFailed:
+			return nil, status.LastError
+		}
+
+		if s.m == nil {
+			err = s.reopen()
+		} else {
+			var measure proto.Packer
+
+			measure, err = s.m.Measure(ctx)
+			if err == nil {
+				s.running()
+				return measure, nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		switch {
+		case errors.Is(err, ErrEndOfData):
+			s.setState(StateStopped, err)
+			return nil, err
+		case errors.Is(err, ErrFatal):
+			s.setState(StateFailed, err)
+			return nil, err
+		}
+
+		s.backoff(ctx, err)
+	}
+}
+
+func (s *Supervisor) reopen() error {
+	s.setState(StateOpening, s.Status().LastError)
+
+	m, err := s.open()
+	if err != nil {
+		return fmt.Errorf("cannot open measurer: %w", err)
+	}
+
+	s.m = m
+
+	s.mu.Lock()
+	if s.opened {
+		s.status.Reopens++
+	}
+
+	s.opened = true
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Supervisor) running() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.Failures = 0
+
+	if s.status.State != StateRunning {
+		s.log.Infof("measurer state: %s -> %s", s.status.State, StateRunning)
+		s.status.State = StateRunning
+	}
+}
+
+// backoff выдерживает задержку после временной ошибки и при необходимости закрывает
+// измеритель, чтобы следующая итерация открыла его заново.
+func (s *Supervisor) backoff(ctx context.Context, err error) {
+	s.mu.Lock()
+	s.status.Failures++
+	failures := s.status.Failures
+	s.mu.Unlock()
+
+	s.setState(StateBackoff, err)
+
+	if s.m != nil && failures%s.reopenAt == 0 {
+		s.log.Warnf("%d failures in a row, reopen measurer", failures)
+
+		closeErr := s.m.Close()
+		if closeErr != nil {
+			s.log.Errorf("failed to close measurer: %v", closeErr)
+		}
+
+		s.m = nil
+	}
+
+	// первую ошибку повторяем сразу: единичные сбои чтения не должны терять измерения
+	if failures == 1 {
+		return
+	}
+
+	delay := s.maxBackoff
+	if shift := failures - 2; shift < 32 {
+		delay = min(s.minBackoff<<shift, s.maxBackoff)
+	}
+
+	s.log.Debugf("next measure attempt in %v, last error: %v", delay, err)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func (s *Supervisor) setState(state SupervisorState, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.status.State == state:
+	case state == StateOpening:
+		s.log.Debugf("measurer state: %s -> %s", s.status.State, state)
+	case err != nil:
+		s.log.Warnf("measurer state: %s -> %s (failures: %d): %v", s.status.State, state, s.status.Failures, err)
+	default:
+		s.log.Infof("measurer state: %s -> %s", s.status.State, state)
+	}
+
+	s.status.State = state
+	s.status.LastError = err
+}
+
+func (s *Supervisor) Close() error {
+	if s.m == nil {
+		return nil
+	}
+
+	err := s.m.Close()
+	s.m = nil
+
+	return err
+}
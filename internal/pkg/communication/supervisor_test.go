@@ -0,0 +1,115 @@
+package communication
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMeasurer struct {
+	errs   []error
+	closed bool
+}
+
+func (f *fakeMeasurer) Measure(_ context.Context) (proto.Packer, error) {
+	if len(f.errs) == 0 {
+		return &proto.CheckData{Value: 1}, nil
+	}
+
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+
+	return nil, err
+}
+
+func (f *fakeMeasurer) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestSupervisor(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	t.Run("переоткрытие измерителя после серии временных ошибок", func(t *testing.T) {
+		opened := []*fakeMeasurer{
+			{errs: []error{errTransient, errTransient, errTransient}},
+			{},
+		}
+		opens := 0
+
+		s := NewSupervisor(func() (MeasureCloser, error) {
+			m := opened[opens]
+			opens++
+
+			return m, nil
+		}).WithBackoff(time.Millisecond, time.Millisecond).WithReopenAt(3)
+
+		measure, err := s.Measure(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, &proto.CheckData{Value: 1}, measure)
+
+		require.Equal(t, 2, opens)
+		require.True(t, opened[0].closed)
+
+		status := s.Status()
+		require.Equal(t, StateRunning, status.State)
+		require.Equal(t, 1, status.Reopens)
+		require.Zero(t, status.Failures)
+	})
+
+	t.Run("повтор открытия при ошибке открытия", func(t *testing.T) {
+		opens := 0
+
+		s := NewSupervisor(func() (MeasureCloser, error) {
+			opens++
+			if opens < 3 {
+				return nil, errTransient
+			}
+
+			return &fakeMeasurer{}, nil
+		}).WithBackoff(time.Millisecond, time.Millisecond)
+
+		_, err := s.Measure(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 3, opens)
+	})
+
+	t.Run("остановка при исчерпании данных", func(t *testing.T) {
+		s := NewSupervisor(func() (MeasureCloser, error) {
+			return &fakeMeasurer{errs: []error{ErrEndOfData}}, nil
+		})
+
+		_, err := s.Measure(context.Background())
+		require.ErrorIs(t, err, ErrEndOfData)
+		require.Equal(t, StateStopped, s.Status().State)
+
+		_, err = s.Measure(context.Background())
+		require.ErrorIs(t, err, ErrEndOfData)
+	})
+
+	t.Run("неустранимая ошибка открытия", func(t *testing.T) {
+		s := NewSupervisor(func() (MeasureCloser, error) {
+			return nil, ErrFatal
+		})
+
+		_, err := s.Measure(context.Background())
+		require.ErrorIs(t, err, ErrFatal)
+		require.Equal(t, StateFailed, s.Status().State)
+	})
+
+	t.Run("отмена контекста во время задержки", func(t *testing.T) {
+		s := NewSupervisor(func() (MeasureCloser, error) {
+			return nil, errTransient
+		}).WithBackoff(time.Hour, time.Hour)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := s.Measure(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
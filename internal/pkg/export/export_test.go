@@ -0,0 +1,152 @@
+package export
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func frame(t *testing.T, msg *proto.Message) []byte {
+	t.Helper()
+
+	msg.SystemTime = 1500
+
+	raw, err := msg.MarshalKeepTime()
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestFields(t *testing.T) {
+	fields, ok := Fields(proto.Message{MsgID: proto.WritingModeA, Payload: &proto.IMUData{
+		AccFactor: 1 << 14, Az: 1 << 14,
+		GyrFactor: 1 << 4, Gz: 180 << 4,
+	}})
+	require.True(t, ok)
+	require.Equal(t, "az_m_s2", fields[2].Name)
+	require.InDelta(t, 9.80665, fields[2].Value, 1e-9)
+	require.Equal(t, "gz_rad_s", fields[5].Name)
+	require.InDelta(t, 3.14159265, fields[5].Value, 1e-6)
+
+	fields, ok = Fields(proto.Message{MsgID: proto.WritingModeB, Payload: &proto.GNSSData{Lat: 555000000, Height: 12345}})
+	require.True(t, ok)
+	require.Equal(t, Field{"lat_deg", 55.5}, fields[2])
+	require.Equal(t, Field{"height_m", 12.345}, fields[3])
+
+	_, ok = Fields(proto.Message{MsgID: proto.WritingModeC, Payload: &proto.CameraData{}})
+	require.False(t, ok)
+}
+
+func TestExportRecording(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Unix(1700000000, 0)
+
+	w := recording.NewWriter(dir, "rec")
+	src, err := w.Source("/dev/ttyAMA5")
+	require.NoError(t, err)
+
+	gnss := frame(t, proto.NewMessage(proto.GNSSModuleID, proto.WritingModeB, &proto.GNSSData{Lat: 555000000}))
+	check := frame(t, proto.NewMessage(proto.CheckModuleID, proto.WritingModeA, &proto.CheckData{Value: 7}))
+	sync := frame(t, proto.NewMessage(proto.GNSSModuleID, proto.SyncRequest, nil))
+
+	corrupted := append([]byte(nil), check...)
+	corrupted[len(corrupted)-1]++
+
+	require.NoError(t, w.Write(src, start, 0, sync))
+	require.NoError(t, w.Write(src, start.Add(time.Second), 0, gnss))
+	require.NoError(t, w.Write(src, start.Add(2*time.Second), recording.FlagChecksumFailed, corrupted))
+	require.NoError(t, w.Write(src, start.Add(3*time.Second), 0, check))
+	require.NoError(t, w.Write(src, start.Add(4*time.Second), 0, gnss))
+	require.NoError(t, w.Close())
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"+recording.FileExt))
+	require.NoError(t, err)
+
+	out := t.TempDir()
+
+	from, err := ParseBound("500ms")
+	require.NoError(t, err)
+
+	to, err := ParseBound(start.Add(4 * time.Second).Format(time.RFC3339))
+	require.NoError(t, err)
+
+	e := NewExporter(out, FormatCSV).WithInterval(from, to)
+
+	s, err := OpenSource(names[0])
+	require.NoError(t, err)
+	require.NoError(t, e.Export(s))
+	require.NoError(t, s.Close())
+
+	summary, err := e.Close()
+	require.NoError(t, err)
+	require.Equal(t, 5, summary.Frames)
+	require.Equal(t, 2, summary.Exported)
+	require.Equal(t, 1, summary.Filtered)
+	require.Equal(t, 1, summary.Service)
+	require.Equal(t, map[string]int{ErrorChecksum: 1}, summary.Errors)
+	require.Equal(t, map[string]int{"gnss_0x15": 1, "check_0x14": 1}, summary.Tables)
+
+	f, err := os.Open(filepath.Join(out, "gnss_0x15.csv"))
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, []string{"recv_time_s", "system_time_s", "source", "itow_posllh_s", "lon_deg", "lat_deg"}, rows[0][:6])
+	require.Equal(t, []string{"1700000001", "1.5", "/dev/ttyAMA5", "0", "0", "55.5"}, rows[1][:6])
+}
+
+func TestExportDump(t *testing.T) {
+	dir := t.TempDir()
+
+	var dump []byte
+	dump = append(dump, 0x00, 0x13, 0x37)
+	dump = append(dump, frame(t, proto.NewMessage(proto.CheckModuleID, proto.WritingModeA, &proto.CheckData{Value: 7}))...)
+	dump = append(dump, 0xFA)
+	dump = append(dump, frame(t, proto.NewMessage(proto.CheckModuleID, proto.WritingModeA, &proto.CheckData{Value: 8}))...)
+
+	path := filepath.Join(dir, "ttyAMA5.bin")
+	require.NoError(t, os.WriteFile(path, dump, 0600))
+
+	out := t.TempDir()
+	e := NewExporter(out, FormatJSONL)
+
+	s, err := OpenSource(path)
+	require.NoError(t, err)
+	require.NoError(t, e.Export(s))
+	require.NoError(t, s.Close())
+
+	summary, err := e.Close()
+	require.NoError(t, err)
+	require.Equal(t, 2, summary.Exported)
+
+	data, err := os.ReadFile(filepath.Join(out, "check_0x14.jsonl"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{"system_time_s":1.5,"source":"`+path+`","value":8}`, lines[1])
+
+	t.Run("абсолютное время для дампа", func(t *testing.T) {
+		from, err := ParseBound("2024-01-01T00:00:00Z")
+		require.NoError(t, err)
+
+		e := NewExporter(t.TempDir(), FormatCSV).WithInterval(from, Bound{})
+
+		s, err := OpenSource(path)
+		require.NoError(t, err)
+
+		defer s.Close()
+
+		require.Error(t, e.Export(s))
+	})
+}
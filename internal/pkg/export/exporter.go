@@ -0,0 +1,434 @@
+package export
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format формат выходных файлов
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// ParseFormat проверяет название формата.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatCSV, FormatJSONL:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q", s)
+	}
+}
+
+// Категории ошибок декодирования в Summary.Errors
+const (
+	ErrorChecksum      = "checksum mismatch"
+	ErrorInvalid       = "invalid frame"
+	ErrorUnknownModule = "unknown module"
+	ErrorUnsupported   = "unsupported mode"
+	ErrorUnreadable    = "skipped data"
+	ErrorCorrupted     = "corrupted record"
+)
+
+// Bound граница интервала времени экспорта: абсолютное время приема или смещение от первого
+// фрейма. Нулевое значение не ограничивает интервал.
+type Bound struct {
+	Time   time.Time
+	Offset time.Duration
+	set    bool
+}
+
+// ParseBound разбирает границу в формате RFC 3339 или длительность от первого фрейма (10m).
+// Пустая строка не ограничивает интервал.
+func ParseBound(s string) (Bound, error) {
+	if s == "" {
+		return Bound{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return Bound{Time: t, set: true}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Bound{}, fmt.Errorf("bad time bound %q: expected RFC 3339 time or duration", s)
+	}
+
+	return Bound{Offset: d, set: true}, nil
+}
+
+func (b Bound) absolute() bool {
+	return b.set && !b.Time.IsZero()
+}
+
+// Summary итоги экспорта
+type Summary struct {
+	Frames   int
+	Exported int
+	// Filtered фреймы вне интервала времени
+	Filtered int
+	// Service служебные фреймы: синхронизация, подтверждения, статистика
+	Service int
+	// Tables количество строк каждой таблицы
+	Tables map[string]int
+	// Errors количество ошибок декодирования по категориям
+	Errors map[string]int
+}
+
+func (s Summary) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "frames: %d, exported: %d, filtered: %d, service: %d\n", s.Frames, s.Exported, s.Filtered, s.Service)
+
+	for _, name := range sortedKeys(s.Tables) {
+		fmt.Fprintf(&b, "  %s: %d rows\n", name, s.Tables[name])
+	}
+
+	if len(s.Errors) == 0 {
+		b.WriteString("decode errors: none\n")
+	} else {
+		b.WriteString("decode errors:\n")
+
+		for _, name := range sortedKeys(s.Errors) {
+			fmt.Fprintf(&b, "  %s: %d\n", name, s.Errors[name])
+		}
+	}
+
+	return b.String()
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Exporter записывает данные фреймов в отдельный файл для каждого модуля и режима сообщения.
+type Exporter struct {
+	dir    string
+	format Format
+	from   Bound
+	to     Bound
+
+	start   time.Time
+	started bool
+	tables  map[string]*table
+	summary Summary
+}
+
+func NewExporter(dir string, format Format) *Exporter {
+	return &Exporter{
+		dir:    dir,
+		format: format,
+		tables: make(map[string]*table),
+		summary: Summary{
+			Tables: make(map[string]int),
+			Errors: make(map[string]int),
+		},
+	}
+}
+
+// WithInterval ограничивает экспорт фреймами, принятыми в интервале [from, to).
+func (e *Exporter) WithInterval(from, to Bound) *Exporter {
+	e.from = from
+	e.to = to
+
+	return e
+}
+
+// Export экспортирует все фреймы источника.
+func (e *Exporter) Export(src Source) error {
+	for {
+		frame, err := src.Next()
+
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, ErrSkipped):
+			e.summary.Errors[ErrorUnreadable]++
+			continue
+		case errors.Is(err, recording.ErrCorrupted):
+			// остаток файла после поврежденной записи не читается
+			e.summary.Errors[ErrorCorrupted]++
+			return nil
+		case err != nil:
+			return err
+		}
+
+		err = e.export(frame)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *Exporter) export(frame Frame) error {
+	e.summary.Frames++
+
+	// смещения границ интервала отсчитываются от первого фрейма записи
+	if !e.started && !frame.RecvTime.IsZero() {
+		e.start = frame.RecvTime
+		e.started = true
+	}
+
+	if frame.Flags&recording.FlagChecksumFailed != 0 {
+		e.summary.Errors[ErrorChecksum]++
+		return nil
+	}
+
+	info, err := proto.VerifyFrame(frame.Data)
+	if err != nil {
+		if errors.Is(err, proto.ErrChecksumMismatch) {
+			e.summary.Errors[ErrorChecksum]++
+		} else {
+			e.summary.Errors[ErrorInvalid]++
+		}
+
+		return nil
+	}
+
+	if info.MsgID < proto.ReadingModeA || info.MsgID > proto.WritingModeC {
+		e.summary.Service++
+		return nil
+	}
+
+	if _, ok := proto.NewPayload(info.ModuleID); !ok {
+		e.summary.Errors[ErrorUnknownModule]++
+		return nil
+	}
+
+	msg, err := unmarshal(frame.Data)
+	if err != nil {
+		e.summary.Errors[ErrorUnsupported]++
+		return nil
+	}
+
+	fields, ok := Fields(msg)
+	if !ok {
+		e.summary.Errors[ErrorUnsupported]++
+		return nil
+	}
+
+	ok, err = e.inInterval(frame, msg)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		e.summary.Filtered++
+		return nil
+	}
+
+	name := fmt.Sprintf("%s_%#x", proto.ModuleName(msg.ModuleID), uint8(msg.MsgID))
+
+	t, ok := e.tables[name]
+	if !ok {
+		t, err = newTable(filepath.Join(e.dir, name+"."+string(e.format)), e.format, fields)
+		if err != nil {
+			return err
+		}
+
+		e.tables[name] = t
+	}
+
+	err = t.write(frame, msg, fields)
+	if err != nil {
+		return fmt.Errorf("cannot write %s: %w", name, err)
+	}
+
+	e.summary.Exported++
+	e.summary.Tables[name]++
+
+	return nil
+}
+
+// unmarshal распаковывает фрейм; распаковка в неподдерживаемом режиме завершается паникой.
+func unmarshal(data []byte) (msg proto.Message, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+
+	err = msg.Unmarshal(data)
+
+	return msg, err
+}
+
+// frameTime возвращает время приема фрейма или, для дампов, системное время модуля.
+func frameTime(frame Frame, msg proto.Message) time.Time {
+	if !frame.RecvTime.IsZero() {
+		return frame.RecvTime
+	}
+
+	return time.UnixMilli(int64(msg.SystemTime))
+}
+
+func (e *Exporter) inInterval(frame Frame, msg proto.Message) (bool, error) {
+	if frame.RecvTime.IsZero() && (e.from.absolute() || e.to.absolute()) {
+		return false, fmt.Errorf("absolute time bounds are not supported for raw dumps, use durations")
+	}
+
+	t := frameTime(frame, msg)
+
+	if !e.started {
+		e.start = t
+		e.started = true
+	}
+
+	if e.from.set && t.Before(e.bound(e.from)) {
+		return false, nil
+	}
+
+	if e.to.set && !t.Before(e.bound(e.to)) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (e *Exporter) bound(b Bound) time.Time {
+	if b.absolute() {
+		return b.Time
+	}
+
+	return e.start.Add(b.Offset)
+}
+
+// Close закрывает файлы таблиц и возвращает итоги экспорта.
+func (e *Exporter) Close() (Summary, error) {
+	var errs []error
+
+	for _, t := range e.tables {
+		errs = append(errs, t.close())
+	}
+
+	return e.summary, errors.Join(errs...)
+}
+
+// table файл таблицы одного модуля и режима сообщения
+type table struct {
+	f      *os.File
+	w      *bufio.Writer
+	csv    *csv.Writer
+	format Format
+}
+
+func newTable(path string, format Format, fields []Field) (*table, error) {
+	f, err := os.Create(path) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	t := &table{f: f, w: bufio.NewWriter(f), format: format}
+
+	if format == FormatCSV {
+		t.csv = csv.NewWriter(t.w)
+
+		header := []string{"recv_time_s", "system_time_s", "source"}
+		for _, field := range fields {
+			header = append(header, field.Name)
+		}
+
+		err = t.csv.Write(header)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func (t *table) write(frame Frame, msg proto.Message, fields []Field) error {
+	recvTime := ""
+	if !frame.RecvTime.IsZero() {
+		recvTime = formatFloat(float64(frame.RecvTime.UnixNano()) / 1e9)
+	}
+
+	systemTime := formatFloat(ms(float64(msg.SystemTime)))
+
+	if t.format == FormatCSV {
+		row := []string{recvTime, systemTime, frame.Source}
+		for _, field := range fields {
+			row = append(row, formatFloat(field.Value))
+		}
+
+		return t.csv.Write(row)
+	}
+
+	source, err := json.Marshal(frame.Source)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	b.WriteByte('{')
+
+	if recvTime != "" {
+		fmt.Fprintf(&b, `"recv_time_s":%s,`, recvTime)
+	}
+
+	fmt.Fprintf(&b, `"system_time_s":%s,"source":%s`, systemTime, source)
+
+	for _, field := range fields {
+		value := "null"
+		if !math.IsNaN(field.Value) && !math.IsInf(field.Value, 0) {
+			value = formatFloat(field.Value)
+		}
+
+		fmt.Fprintf(&b, `,%q:%s`, field.Name, value)
+	}
+
+	b.WriteString("}\n")
+
+	_, err = t.w.WriteString(b.String())
+
+	return err
+}
+
+func (t *table) close() error {
+	if t.csv != nil {
+		t.csv.Flush()
+
+		err := t.csv.Error()
+		if err != nil {
+			_ = t.f.Close()
+			return err
+		}
+	}
+
+	err := t.w.Flush()
+	if err != nil {
+		_ = t.f.Close()
+		return err
+	}
+
+	return t.f.Close()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,215 @@
+// Package export предоставляет преобразование принятых фреймов в таблицы с данными модулей
+// в единицах СИ для анализа
+package export
+
+import (
+	"asvsoft/internal/pkg/imu"
+	"asvsoft/internal/pkg/proto"
+	"fmt"
+	"math"
+)
+
+// magScale цена младшего разряда магнитометра AK09918, Тл
+const magScale = 0.15e-6
+
+// Field поле таблицы. Имя поля содержит единицы измерения.
+type Field struct {
+	Name  string
+	Value float64
+}
+
+// Fields возвращает поля данных сообщения в единицах СИ. Возвращает false, если для модуля
+// или режима сообщения таблица не определена.
+func Fields(msg proto.Message) ([]Field, bool) {
+	switch p := msg.Payload.(type) {
+	case *proto.CheckData:
+		return []Field{{"value", float64(p.Value)}}, true
+	case *proto.IMUData:
+		return imuFields(p, msg.MsgID)
+	case *proto.GNSSData:
+		return gnssFields(p, msg.MsgID)
+	case *proto.DepthMeterData:
+		return []Field{
+			{"id", float64(p.ID)},
+			{"sensor_time_s", ms(float64(p.SystemTime))},
+			{"distance_m", mm(float64(p.Distance))},
+			{"status", float64(p.Status)},
+			{"strength", float64(p.Strength)},
+			{"precision", float64(p.Precision)},
+		}, true
+	case *proto.LidarData:
+		return lidarFields(p), true
+	case *proto.CameraData:
+		return cameraFields(p, msg.MsgID)
+	case *proto.ControlData:
+		return controlFields(p, msg.MsgID)
+	case *proto.NavigationData:
+		return navigationFields(p, msg.MsgID)
+	case *proto.PowerData:
+		charge := float64(p.Charge)
+		if p.Charge == proto.PowerChargeUnknown {
+			charge = math.NaN()
+		}
+
+		return []Field{
+			{"voltage_v", float64(p.Voltage) / 1000},
+			{"current_a", float64(p.Current) / 100},
+			{"charge_pct", charge},
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func imuFields(p *proto.IMUData, mode proto.MessageID) ([]Field, bool) {
+	s, ok := imu.FromProto(p, proto.WritingModeA)
+	if !ok {
+		// не заданы масштабные коэффициенты
+		nan := math.NaN()
+		s = imu.Sample{Gx: nan, Gy: nan, Gz: nan, Ax: nan, Ay: nan, Az: nan}
+	}
+
+	inertial := []Field{
+		{"ax_m_s2", s.Ax}, {"ay_m_s2", s.Ay}, {"az_m_s2", s.Az},
+		{"gx_rad_s", s.Gx}, {"gy_rad_s", s.Gy}, {"gz_rad_s", s.Gz},
+	}
+
+	mag := []Field{
+		{"mx_t", float64(p.Mx) * magScale}, {"my_t", float64(p.My) * magScale}, {"mz_t", float64(p.Mz) * magScale},
+	}
+
+	switch mode {
+	case proto.WritingModeA:
+		return inertial, true
+	case proto.WritingModeB:
+		return append(inertial, mag...), true
+	case proto.WritingModeC:
+		return mag, true
+	default:
+		return nil, false
+	}
+}
+
+func gnssFields(p *proto.GNSSData, mode proto.MessageID) ([]Field, bool) {
+	pos := []Field{
+		{"itow_posllh_s", ms(float64(p.ITowNAVPOSLLH))},
+		{"lon_deg", float64(p.Lon) * 1e-7},
+		{"lat_deg", float64(p.Lat) * 1e-7},
+		{"height_m", mm(float64(p.Height))},
+		{"hmsl_m", mm(float64(p.HMSL))},
+		{"hacc_m", mm(float64(p.HAcc))},
+		{"vacc_m", mm(float64(p.VAcc))},
+	}
+
+	vel := []Field{
+		{"itow_velned_s", ms(float64(p.ITowNAVVELNED))},
+		{"vel_n_m_s", cm(float64(p.VelN))},
+		{"vel_e_m_s", cm(float64(p.VelE))},
+		{"vel_d_m_s", cm(float64(p.VelD))},
+		{"speed_m_s", cm(float64(p.Speed))},
+		{"gspeed_m_s", cm(float64(p.GSppeed))},
+		{"heading_deg", float64(p.Heading) * 1e-5},
+		{"sacc_m_s", cm(float64(p.SAcc))},
+		{"cacc_deg", float64(p.CAcc) * 1e-5},
+	}
+
+	switch mode {
+	case proto.WritingModeA:
+		return append(pos, vel...), true
+	case proto.WritingModeB:
+		return pos, true
+	case proto.WritingModeC:
+		return vel, true
+	default:
+		return nil, false
+	}
+}
+
+func navigationFields(p *proto.NavigationData, mode proto.MessageID) ([]Field, bool) {
+	switch mode {
+	case proto.WritingModeA:
+		return []Field{
+			{"lat_deg", float64(p.Lat) * 1e-7},
+			{"lon_deg", float64(p.Lon) * 1e-7},
+			{"vel_n_m_s", cm(float64(p.VelN))},
+			{"vel_e_m_s", cm(float64(p.VelE))},
+			{"heading_deg", float64(p.Heading) / 100},
+			{"gyro_bias_deg_s", float64(p.GyroBias) / 1e3},
+			{"acc_bias_x_m_s2", mm(float64(p.AccBiasX))},
+			{"acc_bias_y_m_s2", mm(float64(p.AccBiasY))},
+			{"position_std_m", cm(float64(p.PositionStd))},
+			{"heading_std_deg", float64(p.HeadingStd) / 100},
+			{"status", float64(p.Status)},
+		}, true
+	case proto.WritingModeB:
+		return []Field{
+			{"roll_deg", float64(p.Roll) / 100},
+			{"pitch_deg", float64(p.Pitch) / 100},
+			{"heading_deg", float64(p.Heading) / 100},
+			{"status", float64(p.Status)},
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func lidarFields(p *proto.LidarData) []Field {
+	fields := []Field{
+		{"speed_deg_s", float64(p.Speed)},
+		{"start_angle_deg", float64(p.StartAngle) / 100},
+		{"end_angle_deg", float64(p.EndAngle) / 100},
+		{"sensor_time_s", ms(float64(p.Timestamp))},
+	}
+
+	for i, point := range p.Points {
+		fields = append(fields,
+			Field{fmt.Sprintf("p%d_distance_m", i), mm(float64(point.Distance))},
+			Field{fmt.Sprintf("p%d_intensity", i), float64(point.Intensity)},
+		)
+	}
+
+	return fields
+}
+
+func cameraFields(p *proto.CameraData, mode proto.MessageID) ([]Field, bool) {
+	switch mode {
+	case proto.WritingModeA:
+		return []Field{
+			{"yaw_deg", float64(p.Yaw) * 1e-4},
+			{"pitch_deg", float64(p.Pitch) * 1e-4},
+			{"roll_deg", float64(p.Roll) * 1e-4},
+		}, true
+	case proto.WritingModeB:
+		return []Field{
+			{"chunk", float64(p.CurrentChunck)},
+			{"total_chunks", float64(p.TotalChunckes)},
+			{"size_bytes", float64(len(p.RawImagePart))},
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func controlFields(p *proto.ControlData, mode proto.MessageID) ([]Field, bool) {
+	switch mode {
+	case proto.WritingModeA:
+		return []Field{
+			{"thrust_pct", float64(p.Thrust) / 10},
+			{"rudder_deg", float64(p.Rudder) / 100},
+			{"setpoint_source", float64(p.Source)},
+		}, true
+	case proto.WritingModeB:
+		return []Field{
+			{"event", float64(p.Event)},
+			{"subject", float64(p.Subject)},
+			{"active", float64(p.Active)},
+			{"value", float64(p.Value)},
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func ms(v float64) float64 { return v / 1e3 }
+func mm(v float64) float64 { return v / 1e3 }
+func cm(v float64) float64 { return v / 1e2 }
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// Frame фрейм, прочитанный из записи регистратора или из сырого дампа канала связи
+type Frame struct {
+	// RecvTime время приема фрейма, нулевое для дампов
+	RecvTime time.Time
+	Source   string
+	Flags    recording.Flags
+	Data     []byte
+}
+
+// Source источник фреймов. Next возвращает io.EOF в конце источника.
+type Source interface {
+	Next() (Frame, error)
+	Close() error
+}
+
+// ErrSkipped часть данных источника не содержит корректных фреймов и пропущена, чтение может
+// быть продолжено
+var ErrSkipped = errors.New("data skipped")
+
+// OpenSource открывает файл записи регистратора или, если файл не является записью, сырой дамп
+// канала связи.
+func OpenSource(path string) (Source, error) {
+	r, err := recording.Open(path)
+	if err == nil {
+		return &recordingSource{r: r}, nil
+	}
+
+	if !errors.Is(err, recording.ErrBadHeader) {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	return &dumpSource{data: data, name: path}, nil
+}
+
+type recordingSource struct {
+	r *recording.Reader
+}
+
+func (s *recordingSource) Next() (Frame, error) {
+	rec, err := s.r.Next()
+	if err != nil {
+		return Frame{}, err
+	}
+
+	return Frame{RecvTime: rec.Time, Source: rec.Source, Flags: rec.Flags, Data: rec.Frame}, nil
+}
+
+func (s *recordingSource) Close() error {
+	return s.r.Close()
+}
+
+// dumpSource ищет фреймы в сыром дампе канала связи. Данные между фреймами, в том числе
+// фреймы с неверной контрольной суммой, пропускаются.
+type dumpSource struct {
+	data []byte
+	pos  int
+	name string
+}
+
+func (s *dumpSource) Next() (Frame, error) {
+	if s.pos >= len(s.data) {
+		return Frame{}, io.EOF
+	}
+
+	start, size, ok := proto.FindFrame(s.data[s.pos:])
+	if !ok {
+		s.pos = len(s.data)
+		return Frame{}, ErrSkipped
+	}
+
+	if start > 0 {
+		s.pos += start
+		return Frame{}, ErrSkipped
+	}
+
+	frame := Frame{Source: s.name, Data: s.data[s.pos : s.pos+size]}
+	s.pos += size
+
+	return frame, nil
+}
+
+func (s *dumpSource) Close() error {
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package failsafe предоставляет политику аварийных реакций: признак аварии срабатывает, если
+// его нормальное состояние не подтверждалось дольше таймаута, и назначает реакцию; действует
+// реакция первого по порядку сработавшего признака
+package failsafe
+
+import (
+	"asvsoft/internal/pkg/watchdog"
+	"fmt"
+	"time"
+)
+
+// DefaultTimeout таймаут признака аварии по умолчанию
+const DefaultTimeout = 5 * time.Second
+
+// Response аварийная реакция
+type Response string
+
+const (
+	// None аварийной реакции нет
+	None Response = ""
+	// Hold удержание в точке срабатывания
+	Hold Response = "hold"
+	// Drift остановка исполнительных механизмов
+	Drift Response = "drift"
+	// Return возвращение в точку запуска
+	Return Response = "rtl"
+)
+
+// Responses допустимые аварийные реакции
+var Responses = []Response{Hold, Drift, Return}
+
+// Transition срабатывание или восстановление признака аварии
+type Transition struct {
+	// Index номер признака, Trigger его имя
+	Index   int
+	Trigger string
+	Active  bool
+	// LastHealthy время последнего подтверждения нормального состояния, нулевое - не было
+	LastHealthy time.Time
+	// Previous действующая реакция до перехода, Response - после
+	Previous Response
+	Response Response
+	At       time.Time
+}
+
+func (t Transition) String() string {
+	state := "recovered"
+	if t.Active {
+		state = "triggered"
+	}
+
+	return fmt.Sprintf("%s %s, response %q -> %q", t.Trigger, state, t.Previous, t.Response)
+}
+
+type trigger struct {
+	name     string
+	response Response
+	// latch сработавший признак не восстанавливается
+	latch bool
+	wd    *watchdog.Watchdog
+}
+
+// Policy политика аварийных реакций. Feed безопасен для одновременного вызова с Check, Add и
+// Check вызываются из одной горутины.
+type Policy struct {
+	triggers []*trigger
+	response Response
+}
+
+func NewPolicy() *Policy {
+	return &Policy{}
+}
+
+// Add добавляет признак name с реакцией response. Признак срабатывает, если нормальное
+// состояние не подтверждалось дольше timeout; до первого подтверждения отсчет идет от start.
+// Сработавший признак с latch не восстанавливается. Возвращает номер признака для Feed.
+func (p *Policy) Add(name string, timeout time.Duration, response Response, latch bool, start time.Time) int {
+	p.triggers = append(p.triggers, &trigger{
+		name:     name,
+		response: response,
+		latch:    latch,
+		wd:       watchdog.New(name, timeout, start),
+	})
+
+	return len(p.triggers) - 1
+}
+
+// Feed подтверждает нормальное состояние признака i в момент t.
+func (p *Policy) Feed(i int, t time.Time) {
+	p.triggers[i].wd.Feed(t)
+}
+
+// Check проверяет признаки в момент now и возвращает их переходы.
+func (p *Policy) Check(now time.Time) []Transition {
+	var transitions []Transition
+
+	for i, t := range p.triggers {
+		if t.latch && t.wd.Silent() {
+			continue
+		}
+
+		ev, ok := t.wd.Check(now)
+		if !ok {
+			continue
+		}
+
+		previous := p.response
+		p.response = p.current()
+
+		transitions = append(transitions, Transition{
+			Index:       i,
+			Trigger:     t.name,
+			Active:      ev.Kind == watchdog.Silent,
+			LastHealthy: ev.LastMessage,
+			Previous:    previous,
+			Response:    p.response,
+			At:          now,
+		})
+	}
+
+	return transitions
+}
+
+// current возвращает реакцию первого сработавшего признака.
+func (p *Policy) current() Response {
+	for _, t := range p.triggers {
+		if t.wd.Silent() {
+			return t.response
+		}
+	}
+
+	return None
+}
+
+// Response возвращает действующую реакцию по результату последней проверки.
+func (p *Policy) Response() Response {
+	return p.response
+}
@@ -0,0 +1,75 @@
+package failsafe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	p := NewPolicy()
+	link := p.Add("link", 10*time.Second, Return, false, start)
+	gnss := p.Add("gnss", 3*time.Second, Hold, false, start)
+	battery := p.Add("battery", 5*time.Second, Drift, true, start)
+
+	feedAll := func(at time.Time) {
+		p.Feed(link, at)
+		p.Feed(gnss, at)
+		p.Feed(battery, at)
+	}
+
+	feedAll(start.Add(time.Second))
+	require.Empty(t, p.Check(start.Add(2*time.Second)))
+	require.Equal(t, None, p.Response())
+
+	t.Run("срабатывание по таймауту", func(t *testing.T) {
+		p.Feed(link, start.Add(5*time.Second))
+		p.Feed(battery, start.Add(5*time.Second))
+
+		transitions := p.Check(start.Add(5 * time.Second))
+		require.Len(t, transitions, 1)
+		require.Equal(t, "gnss", transitions[0].Trigger)
+		require.True(t, transitions[0].Active)
+		require.Equal(t, None, transitions[0].Previous)
+		require.Equal(t, Hold, transitions[0].Response)
+		require.True(t, transitions[0].LastHealthy.Equal(start.Add(time.Second)))
+	})
+
+	t.Run("первый по порядку признак определяет реакцию", func(t *testing.T) {
+		p.Feed(battery, start.Add(12*time.Second))
+
+		transitions := p.Check(start.Add(16 * time.Second))
+		require.Len(t, transitions, 1)
+		require.Equal(t, "link", transitions[0].Trigger)
+		require.Equal(t, Return, p.Response())
+
+		p.Feed(link, start.Add(17*time.Second))
+
+		transitions = p.Check(start.Add(17 * time.Second))
+		require.Len(t, transitions, 1)
+		require.False(t, transitions[0].Active)
+		require.Equal(t, Return, transitions[0].Previous)
+		require.Equal(t, Hold, transitions[0].Response)
+	})
+
+	t.Run("сработавший признак с фиксацией не восстанавливается", func(t *testing.T) {
+		feedAll(start.Add(18 * time.Second))
+		require.Len(t, p.Check(start.Add(18*time.Second)), 1)
+		require.Equal(t, None, p.Response())
+
+		p.Feed(link, start.Add(25*time.Second))
+		p.Feed(gnss, start.Add(25*time.Second))
+
+		transitions := p.Check(start.Add(25 * time.Second))
+		require.Len(t, transitions, 1)
+		require.Equal(t, "battery", transitions[0].Trigger)
+		require.Equal(t, Drift, p.Response())
+
+		feedAll(start.Add(26 * time.Second))
+		require.Empty(t, p.Check(start.Add(26*time.Second)))
+		require.Equal(t, Drift, p.Response())
+	})
+}
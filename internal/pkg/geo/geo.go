@@ -0,0 +1,124 @@
+// Package geo предоставляет расчеты на сфере Земли: расстояния, пеленги, отклонение от линии
+// пути и переход к локальным координатам север-восток
+package geo
+
+import (
+	"math"
+)
+
+// EarthRadius средний радиус Земли в метрах
+const EarthRadius = 6371008.8
+
+// Point точка с широтой и долготой в градусах
+type Point struct {
+	Lat float64 `yaml:"lat" json:"lat"`
+	Lon float64 `yaml:"lon" json:"lon"`
+}
+
+// FromE7 возвращает точку по широте и долготе в 1e-7 градуса (см. proto.GNSSData).
+func FromE7(lat, lon int32) Point {
+	return Point{Lat: float64(lat) * 1e-7, Lon: float64(lon) * 1e-7}
+}
+
+// Valid сообщает, лежат ли координаты в допустимых пределах.
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// Rad переводит градусы в радианы.
+func Rad(deg float64) float64 { return deg * math.Pi / 180 }
+
+// Deg переводит радианы в градусы.
+func Deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Distance возвращает расстояние по дуге большого круга между a и b в метрах.
+func Distance(a, b Point) float64 {
+	return EarthRadius * angularDistance(a, b)
+}
+
+func angularDistance(a, b Point) float64 {
+	dLat := Rad(b.Lat - a.Lat)
+	dLon := Rad(b.Lon - a.Lon)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(Rad(a.Lat))*math.Cos(Rad(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+}
+
+// Bearing возвращает начальный пеленг из a на b в градусах [0, 360).
+func Bearing(a, b Point) float64 {
+	lat1, lat2 := Rad(a.Lat), Rad(b.Lat)
+	dLon := Rad(b.Lon - a.Lon)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+
+	return NormalizeBearing(Deg(math.Atan2(y, x)))
+}
+
+// CrossTrack возвращает отклонение точки p от линии пути from-to в метрах, положительное -
+// справа от линии пути.
+func CrossTrack(from, to, p Point) float64 {
+	d := angularDistance(from, p)
+	dBearing := Rad(Bearing(from, p) - Bearing(from, to))
+
+	return math.Asin(math.Sin(d)*math.Sin(dBearing)) * EarthRadius
+}
+
+// AlongTrack возвращает расстояние от from до проекции p на линию пути from-to в метрах,
+// отрицательное - проекция позади from.
+func AlongTrack(from, to, p Point) float64 {
+	d := angularDistance(from, p)
+	xt := CrossTrack(from, to, p) / EarthRadius
+
+	along := math.Acos(math.Max(-1, math.Min(1, math.Cos(d)/math.Cos(xt)))) * EarthRadius
+
+	if math.Abs(NormalizeAngle(Bearing(from, p)-Bearing(from, to))) > 90 {
+		return -along
+	}
+
+	return along
+}
+
+// Offset возвращает точку, смещенную от p на north метров к северу и east метров к востоку.
+// Погрешность пренебрежимо мала на расстояниях до десятков километров.
+func Offset(p Point, north, east float64) Point {
+	return Point{
+		Lat: p.Lat + Deg(north/EarthRadius),
+		Lon: p.Lon + Deg(east/(EarthRadius*math.Cos(Rad(p.Lat)))),
+	}
+}
+
+// NorthEast возвращает координаты p в метрах в локальной системе север-восток с началом
+// в origin. Обратное преобразование - Offset.
+func NorthEast(origin, p Point) (north, east float64) {
+	north = Rad(p.Lat-origin.Lat) * EarthRadius
+	east = Rad(NormalizeAngle(p.Lon-origin.Lon)) * EarthRadius * math.Cos(Rad(origin.Lat))
+
+	return north, east
+}
+
+// NormalizeAngle приводит угол в градусах к диапазону (-180, 180].
+func NormalizeAngle(a float64) float64 {
+	a = math.Mod(a, 360)
+
+	switch {
+	case a > 180:
+		a -= 360
+	case a <= -180:
+		a += 360
+	}
+
+	return a
+}
+
+// NormalizeBearing приводит пеленг в градусах к диапазону [0, 360).
+func NormalizeBearing(a float64) float64 {
+	a = math.Mod(a, 360)
+	if a < 0 {
+		a += 360
+	}
+
+	return a
+}
@@ -0,0 +1,52 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeo(t *testing.T) {
+	origin := Point{Lat: 59.93, Lon: 30.31}
+
+	t.Run("расстояние и пеленг", func(t *testing.T) {
+		north := Offset(origin, 1000, 0)
+		east := Offset(origin, 0, 1000)
+
+		require.InDelta(t, 1000, Distance(origin, north), 0.1)
+		require.InDelta(t, 1000, Distance(origin, east), 0.1)
+		require.InDelta(t, 0, Bearing(origin, north), 1e-6)
+		require.InDelta(t, 90, Bearing(origin, east), 0.01)
+		require.InDelta(t, 225, Bearing(origin, Offset(origin, -100, -100)), 0.01)
+	})
+
+	t.Run("отклонение от линии пути", func(t *testing.T) {
+		to := Offset(origin, 1000, 0)
+
+		require.InDelta(t, 50, CrossTrack(origin, to, Offset(origin, 500, 50)), 0.1)
+		require.InDelta(t, -50, CrossTrack(origin, to, Offset(origin, 500, -50)), 0.1)
+		require.InDelta(t, 500, AlongTrack(origin, to, Offset(origin, 500, 50)), 0.1)
+		require.InDelta(t, -200, AlongTrack(origin, to, Offset(origin, -200, 10)), 0.1)
+	})
+
+	t.Run("локальные координаты", func(t *testing.T) {
+		n, e := NorthEast(origin, Offset(origin, 120, -80))
+		require.InDelta(t, 120, n, 1e-6)
+		require.InDelta(t, -80, e, 1e-6)
+	})
+
+	t.Run("нормализация углов", func(t *testing.T) {
+		require.Equal(t, 180.0, NormalizeAngle(-180))
+		require.Equal(t, -90.0, NormalizeAngle(270))
+		require.Equal(t, 10.0, NormalizeAngle(370))
+		require.Equal(t, 350.0, NormalizeBearing(-10))
+		require.Equal(t, 0.0, NormalizeBearing(360))
+	})
+
+	t.Run("координаты ГНСС", func(t *testing.T) {
+		p := FromE7(599300000, 303100000)
+		require.InDelta(t, 59.93, p.Lat, 1e-9)
+		require.True(t, p.Valid())
+		require.False(t, Point{Lat: 91}.Valid())
+	})
+}
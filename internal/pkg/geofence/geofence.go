@@ -0,0 +1,248 @@
+// Package geofence предоставляет многоугольные зоны допустимого (inclusion) и запретного
+// (exclusion) плавания и контроль их нарушения с запасом и гистерезисом
+package geofence
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultMargin запас до границы зоны по умолчанию в метрах
+	DefaultMargin = 5.0
+	// DefaultHysteresis гистерезис по умолчанию в метрах
+	DefaultHysteresis = 5.0
+)
+
+// Kind вид зоны
+type Kind string
+
+const (
+	// Inclusion зона допустимого плавания: аппарат должен оставаться внутри
+	Inclusion Kind = "inclusion"
+	// Exclusion запретная зона: аппарат должен оставаться снаружи
+	Exclusion Kind = "exclusion"
+)
+
+// Fence зона, ограниченная многоугольником
+type Fence struct {
+	Name string `yaml:"name"`
+	Kind Kind   `yaml:"type"`
+	// Points вершины многоугольника; замыкающая вершина не обязательна
+	Points []geo.Point `yaml:"points"`
+}
+
+// Clearance возвращает расстояние от p до границы зоны в метрах: положительное - с допустимой
+// стороны границы, отрицательное - с запретной.
+func (f Fence) Clearance(p geo.Point) float64 {
+	d := f.signedDistance(p)
+	if f.Kind == Exclusion {
+		return -d
+	}
+
+	return d
+}
+
+// signedDistance возвращает расстояние от p до границы многоугольника в метрах, положительное
+// внутри. Вычисляется на плоскости север-восток с началом в первой вершине.
+func (f Fence) signedDistance(p geo.Point) float64 {
+	origin := f.Points[0]
+	pn, pe := geo.NorthEast(origin, p)
+
+	inside := false
+	dist := math.Inf(1)
+
+	for i := range f.Points {
+		an, ae := geo.NorthEast(origin, f.Points[i])
+		bn, be := geo.NorthEast(origin, f.Points[(i+1)%len(f.Points)])
+
+		// луч из p на восток пересекает ребро a-b
+		if (an > pn) != (bn > pn) && pe < ae+(pn-an)*(be-ae)/(bn-an) {
+			inside = !inside
+		}
+
+		dist = math.Min(dist, segmentDistance(pn, pe, an, ae, bn, be))
+	}
+
+	if inside {
+		return dist
+	}
+
+	return -dist
+}
+
+// segmentDistance возвращает расстояние на плоскости от точки p до отрезка a-b.
+func segmentDistance(pn, pe, an, ae, bn, be float64) float64 {
+	dn, de := bn-an, be-ae
+
+	t := 0.0
+	if l := dn*dn + de*de; l > 0 {
+		t = math.Max(0, math.Min(1, ((pn-an)*dn+(pe-ae)*de)/l))
+	}
+
+	return math.Hypot(pn-(an+t*dn), pe-(ae+t*de))
+}
+
+// Load загружает зоны из файла path: yaml (.yaml, .yml) со списком fences или GeoJSON
+// (.geojson, .json) с объектами Polygon и MultiPolygon.
+func Load(path string) ([]Fence, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read geofence: %w", err)
+	}
+
+	var fences []Fence
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		fences, err = parseYAML(data)
+	case ".geojson", ".json":
+		fences, err = parseGeoJSON(data)
+	default:
+		return nil, fmt.Errorf("unknown geofence format %q, want yaml or geojson", ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse geofence %s: %w", path, err)
+	}
+
+	if len(fences) == 0 {
+		return nil, fmt.Errorf("bad geofence %s: no fences", path)
+	}
+
+	for i := range fences {
+		f := &fences[i]
+
+		if f.Name == "" {
+			f.Name = fmt.Sprintf("fence%d", i+1)
+		}
+
+		if f.Kind == "" {
+			f.Kind = Inclusion
+		}
+
+		// замыкающая вершина совпадает с первой и не нужна
+		if n := len(f.Points); n > 1 && f.Points[0] == f.Points[n-1] {
+			f.Points = f.Points[:n-1]
+		}
+
+		err = f.validate()
+		if err != nil {
+			return nil, fmt.Errorf("bad geofence %s: fence %d (%s): %w", path, i, f.Name, err)
+		}
+	}
+
+	return fences, nil
+}
+
+func (f Fence) validate() error {
+	if f.Kind != Inclusion && f.Kind != Exclusion {
+		return fmt.Errorf("unknown type %q, want %s or %s", f.Kind, Inclusion, Exclusion)
+	}
+
+	if len(f.Points) < 3 {
+		return fmt.Errorf("polygon must have at least 3 points, got %d", len(f.Points))
+	}
+
+	if i := slices.IndexFunc(f.Points, func(p geo.Point) bool { return !p.Valid() }); i >= 0 {
+		return fmt.Errorf("point %d: coordinates out of range: %+v", i, f.Points[i])
+	}
+
+	return nil
+}
+
+func parseYAML(data []byte) ([]Fence, error) {
+	var file struct {
+		Fences []Fence `yaml:"fences"`
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	err := dec.Decode(&file)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.Fences, nil
+}
+
+type geoJSONFeature struct {
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Name string `json:"name"`
+		Type Kind   `json:"type"`
+	} `json:"properties"`
+}
+
+// parseGeoJSON разбирает коллекцию объектов GeoJSON: каждый многоугольник - зона. Внутренние
+// кольца многоугольников не поддерживаются: запретные участки задаются зонами exclusion.
+func parseGeoJSON(data []byte) ([]Fence, error) {
+	var fc struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}
+
+	err := json.Unmarshal(data, &fc)
+	if err != nil {
+		return nil, err
+	}
+
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("unexpected geojson type %q, want FeatureCollection", fc.Type)
+	}
+
+	var fences []Fence
+
+	for i, f := range fc.Features {
+		var polygons [][][][]float64
+
+		switch f.Geometry.Type {
+		case "Polygon":
+			var polygon [][][]float64
+
+			err = json.Unmarshal(f.Geometry.Coordinates, &polygon)
+			polygons = [][][][]float64{polygon}
+		case "MultiPolygon":
+			err = json.Unmarshal(f.Geometry.Coordinates, &polygons)
+		default:
+			return nil, fmt.Errorf("feature %d: unsupported geometry %q, want Polygon or MultiPolygon", i, f.Geometry.Type)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: bad coordinates: %w", i, err)
+		}
+
+		for _, polygon := range polygons {
+			if len(polygon) != 1 {
+				return nil, fmt.Errorf("feature %d: polygon must have exactly one ring, got %d", i, len(polygon))
+			}
+
+			fence := Fence{Name: f.Properties.Name, Kind: f.Properties.Type}
+
+			for _, c := range polygon[0] {
+				if len(c) < 2 {
+					return nil, fmt.Errorf("feature %d: position must have longitude and latitude", i)
+				}
+
+				fence.Points = append(fence.Points, geo.Point{Lat: c[1], Lon: c[0]})
+			}
+
+			fences = append(fences, fence)
+		}
+	}
+
+	return fences, nil
+}
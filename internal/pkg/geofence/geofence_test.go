@@ -0,0 +1,121 @@
+package geofence
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var origin = geo.Point{Lat: 59.93, Lon: 30.31}
+
+// square возвращает квадрат со стороной 2*half метров с центром в origin.
+func square(half float64) []geo.Point {
+	return []geo.Point{
+		geo.Offset(origin, -half, -half),
+		geo.Offset(origin, half, -half),
+		geo.Offset(origin, half, half),
+		geo.Offset(origin, -half, half),
+	}
+}
+
+func TestClearance(t *testing.T) {
+	area := Fence{Kind: Inclusion, Points: square(100)}
+	rock := Fence{Kind: Exclusion, Points: square(10)}
+
+	require.InDelta(t, 100, area.Clearance(origin), 0.1)
+	require.InDelta(t, 20, area.Clearance(geo.Offset(origin, 80, 0)), 0.1)
+	require.InDelta(t, -30, area.Clearance(geo.Offset(origin, 0, 130)), 0.1)
+	require.InDelta(t, 50, area.Clearance(geo.Offset(origin, -50, 20)), 0.1)
+
+	require.InDelta(t, -10, rock.Clearance(origin), 0.1)
+	require.InDelta(t, 5, rock.Clearance(geo.Offset(origin, 15, 0)), 0.1)
+}
+
+func TestMonitor(t *testing.T) {
+	m := NewMonitor([]Fence{{Name: "area", Kind: Inclusion, Points: square(100)}}, 10, 5)
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	kinds := func(events []Event) []EventKind {
+		var result []EventKind
+		for _, e := range events {
+			result = append(result, e.Kind)
+		}
+
+		return result
+	}
+
+	require.Empty(t, m.Update(origin, at))
+	require.False(t, m.Breached())
+
+	t.Run("нарушение запаса", func(t *testing.T) {
+		events := m.Update(geo.Offset(origin, 95, 0), at)
+		require.Equal(t, []EventKind{Breached}, kinds(events))
+		require.InDelta(t, 5, events[0].Clearance, 0.1)
+		require.True(t, m.Breached())
+	})
+
+	t.Run("гистерезис", func(t *testing.T) {
+		require.Empty(t, m.Update(geo.Offset(origin, 120, 0), at))
+		require.Empty(t, m.Update(geo.Offset(origin, 88, 0), at))
+
+		events := m.Update(geo.Offset(origin, 84, 0), at)
+		require.Equal(t, []EventKind{Cleared}, kinds(events))
+		require.False(t, m.Breached())
+	})
+}
+
+func TestLoad(t *testing.T) {
+	write := func(t *testing.T, name, data string) string {
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+		return path
+	}
+
+	t.Run("geojson", func(t *testing.T) {
+		fences, err := Load(write(t, "area.geojson", `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "area"}, "geometry": {"type": "Polygon",
+			 "coordinates": [[[30.30, 59.92], [30.32, 59.92], [30.32, 59.94], [30.30, 59.94], [30.30, 59.92]]]}},
+			{"type": "Feature", "properties": {"type": "exclusion"}, "geometry": {"type": "MultiPolygon",
+			 "coordinates": [[[[30.31, 59.93], [30.311, 59.93], [30.311, 59.931]]]]}}
+		]}`))
+		require.NoError(t, err)
+		require.Len(t, fences, 2)
+
+		require.Equal(t, "area", fences[0].Name)
+		require.Equal(t, Inclusion, fences[0].Kind)
+		require.Len(t, fences[0].Points, 4)
+		require.Equal(t, geo.Point{Lat: 59.92, Lon: 30.30}, fences[0].Points[0])
+		require.Equal(t, "fence2", fences[1].Name)
+		require.Equal(t, Exclusion, fences[1].Kind)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		fences, err := Load(write(t, "area.yaml", `
+fences:
+  - name: pier
+    type: exclusion
+    points:
+      - {lat: 59.93, lon: 30.31}
+      - {lat: 59.931, lon: 30.31}
+      - {lat: 59.931, lon: 30.311}
+`))
+		require.NoError(t, err)
+		require.Equal(t, Exclusion, fences[0].Kind)
+	})
+
+	t.Run("ошибки", func(t *testing.T) {
+		_, err := Load(write(t, "empty.yaml", "fences: []\n"))
+		require.ErrorContains(t, err, "no fences")
+
+		_, err = Load(write(t, "line.yaml", "fences:\n  - points: [{lat: 1, lon: 1}, {lat: 2, lon: 2}]\n"))
+		require.ErrorContains(t, err, "at least 3 points")
+
+		_, err = Load(write(t, "kind.yaml", "fences:\n  - type: allowed\n    points: [{lat: 1, lon: 1}, {lat: 2, lon: 2}, {lat: 1, lon: 2}]\n"))
+		require.ErrorContains(t, err, "unknown type")
+	})
+}
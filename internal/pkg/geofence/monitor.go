@@ -0,0 +1,89 @@
+package geofence
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// EventKind вид события зоны
+type EventKind string
+
+const (
+	Breached EventKind = "breached"
+	Cleared  EventKind = "cleared"
+)
+
+// Event нарушение зоны или его завершение
+type Event struct {
+	Kind EventKind
+	// Index номер зоны
+	Index int
+	Fence Fence
+	// Clearance расстояние до границы зоны в метрах (см. Fence.Clearance)
+	Clearance float64
+	Position  geo.Point
+	At        time.Time
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s fence %d (%s), clearance %.1f m", e.Kind, e.Fence.Kind, e.Index, e.Fence.Name, e.Clearance)
+}
+
+// Monitor контролирует нарушение зон. Зона нарушена, когда расстояние до ее границы с
+// допустимой стороны меньше запаса margin, и снова соблюдается, когда оно не меньше
+// margin+hysteresis. Не безопасен для одновременного использования.
+type Monitor struct {
+	fences     []Fence
+	margin     float64
+	hysteresis float64
+	breached   []bool
+}
+
+func NewMonitor(fences []Fence, margin, hysteresis float64) *Monitor {
+	return &Monitor{
+		fences:     fences,
+		margin:     margin,
+		hysteresis: hysteresis,
+		breached:   make([]bool, len(fences)),
+	}
+}
+
+// Update учитывает положение p в момент at и возвращает изменения состояния зон.
+func (m *Monitor) Update(p geo.Point, at time.Time) []Event {
+	var events []Event
+
+	for i, f := range m.fences {
+		clearance := f.Clearance(p)
+
+		var kind EventKind
+
+		switch {
+		case !m.breached[i] && clearance < m.margin:
+			kind = Breached
+		case m.breached[i] && clearance >= m.margin+m.hysteresis:
+			kind = Cleared
+		default:
+			continue
+		}
+
+		m.breached[i] = kind == Breached
+
+		events = append(events, Event{
+			Kind:      kind,
+			Index:     i,
+			Fence:     f,
+			Clearance: clearance,
+			Position:  p,
+			At:        at,
+		})
+	}
+
+	return events
+}
+
+// Breached сообщает, нарушена ли хотя бы одна зона.
+func (m *Monitor) Breached() bool {
+	return slices.Contains(m.breached, true)
+}
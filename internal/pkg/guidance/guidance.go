@@ -0,0 +1,105 @@
+// Package guidance предоставляет регуляторы курса и скорости, преобразующие заданные курс
+// и скорость в уставки исполнительных механизмов
+package guidance
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/proto"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultRate частота выдачи уставок по умолчанию в Гц
+	DefaultRate = 10.0
+	// DefaultMinCourseSpeed скорость по умолчанию в м/с, ниже которой курс ГНСС недостоверен
+	DefaultMinCourseSpeed = 0.3
+	// DefaultTimeout время по умолчанию, после которого измерение курса или скорости устаревает
+	DefaultTimeout = 2 * time.Second
+	// DefaultMaxRudder предел угла пера руля по умолчанию в градусах
+	DefaultMaxRudder = 30.0
+	// DefaultMaxThrust предел тяги по умолчанию в процентах
+	DefaultMaxThrust = 100.0
+)
+
+// Setpoint уставка исполнительных механизмов
+type Setpoint struct {
+	// Thrust тяга в процентах от максимальной, отрицательная - задний ход
+	Thrust float64
+	// Rudder угол пера руля в градусах, положительный - вправо
+	Rudder float64
+}
+
+// Command заданное движение
+type Command struct {
+	// Course заданный курс в градусах
+	Course float64
+	// Speed заданная скорость в м/с
+	Speed float64
+}
+
+// Measurement измеренное движение аппарата
+type Measurement struct {
+	// Heading курс в градусах, HeadingValid - курс известен
+	Heading      float64
+	HeadingValid bool
+	// Speed скорость относительно грунта в м/с, SpeedValid - скорость известна
+	Speed      float64
+	SpeedValid bool
+}
+
+// Controller регуляторы курса и скорости. Выход регулятора курса - угол пера руля в градусах,
+// регулятора скорости - тяга в процентах. Не безопасен для одновременного использования.
+type Controller struct {
+	heading *PID
+	speed   *PID
+}
+
+func NewController(heading, speed *PID) *Controller {
+	return &Controller{
+		heading: heading,
+		speed:   speed,
+	}
+}
+
+// Update возвращает уставку по заданному движению cmd и измерениям m через dt секунд после
+// предыдущего вызова. Регулятор без измерения сбрасывается, его выход нулевой.
+func (c *Controller) Update(cmd Command, m Measurement, dt float64) Setpoint {
+	var sp Setpoint
+
+	if m.HeadingValid {
+		// ошибка по кратчайшему повороту: положительная - поворот вправо
+		sp.Rudder = c.heading.Update(geo.NormalizeAngle(cmd.Course-m.Heading), dt)
+	} else {
+		c.heading.Reset()
+	}
+
+	if m.SpeedValid {
+		sp.Thrust = c.speed.Update(cmd.Speed-m.Speed, dt)
+	} else {
+		c.speed.Reset()
+	}
+
+	return sp
+}
+
+// Stop сбрасывает регуляторы и возвращает уставку остановки.
+func (c *Controller) Stop() Setpoint {
+	c.heading.Reset()
+	c.speed.Reset()
+
+	return Setpoint{}
+}
+
+// GNSSCourse возвращает курс относительно грунта в градусах и скорость относительно грунта
+// в м/с из сообщения ГНСС режима WritingModeA или WritingModeC.
+func GNSSCourse(d *proto.GNSSData) (course, speed float64) {
+	return geo.NormalizeBearing(float64(d.Heading) * 1e-5), float64(d.GSppeed) / 100
+}
+
+// MagneticHeading возвращает магнитный курс в градусах по магнитометру IMU (режимы
+// WritingModeB и WritingModeC) без компенсации крена и дифферента: ось X направлена в нос,
+// ось Y - на правый борт.
+func MagneticHeading(d *proto.IMUData) float64 {
+	return geo.NormalizeBearing(math.Atan2(-float64(d.My), float64(d.Mx)) * 180 / math.Pi)
+}
@@ -0,0 +1,116 @@
+package guidance
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/proto"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPID(t *testing.T) {
+	t.Run("ограничение выхода и скорости изменения", func(t *testing.T) {
+		p := &PID{Kp: 10, Min: -30, Max: 30, RateLimit: 20}
+
+		require.InDelta(t, 2, p.Update(50, 0.1), 1e-9)
+		require.InDelta(t, 4, p.Update(50, 0.1), 1e-9)
+
+		for range 20 {
+			p.Update(50, 0.1)
+		}
+
+		require.Equal(t, 30.0, p.Output())
+	})
+
+	t.Run("защита от насыщения интегратора", func(t *testing.T) {
+		p := &PID{Kp: 1, Ki: 1, Min: -10, Max: 10}
+
+		// долгое насыщение не накапливает интеграл
+		for range 1000 {
+			p.Update(20, 0.1)
+		}
+
+		require.Equal(t, 10.0, p.Output())
+
+		// при смене знака ошибки выход сразу выходит из насыщения
+		require.InDelta(t, -5.5, p.Update(-5, 0.1), 1e-9)
+	})
+
+	t.Run("сброс", func(t *testing.T) {
+		p := &PID{Kp: 1, Ki: 1, Min: -10, Max: 10}
+		p.Update(5, 1)
+		p.Reset()
+
+		require.Equal(t, 0.0, p.Output())
+		require.InDelta(t, 1.1, p.Update(1, 0.1), 1e-9)
+	})
+}
+
+func newTestController() *Controller {
+	return NewController(
+		&PID{Kp: 1.5, Ki: 0.05, Kd: 1, Min: -DefaultMaxRudder, Max: DefaultMaxRudder, RateLimit: 20},
+		&PID{Kp: 40, Ki: 10, Min: 0, Max: DefaultMaxThrust, RateLimit: 20},
+	)
+}
+
+func TestControllerWithVessel(t *testing.T) {
+	const dt = 0.1
+
+	v := &Vessel{
+		Position: geo.Point{Lat: 59.93, Lon: 30.31},
+		MaxSpeed: 3,
+		SpeedTau: 3,
+		TurnGain: 0.5,
+		YawTau:   1,
+	}
+
+	c := newTestController()
+	cmd := Command{Course: 90, Speed: 1.5}
+
+	var maxRudderStep float64
+
+	prev := Setpoint{}
+
+	for range int(120 / dt) {
+		sp := c.Update(cmd, v.Measurement(), dt)
+
+		require.LessOrEqual(t, math.Abs(sp.Rudder), DefaultMaxRudder)
+		require.GreaterOrEqual(t, sp.Thrust, 0.0)
+		maxRudderStep = math.Max(maxRudderStep, math.Abs(sp.Rudder-prev.Rudder))
+
+		v.Step(sp, dt)
+		prev = sp
+	}
+
+	require.InDelta(t, 90, v.Heading, 1)
+	require.InDelta(t, 1.5, v.Speed, 0.05)
+	require.LessOrEqual(t, maxRudderStep, 20*dt+1e-9)
+
+	t.Run("разворот через север", func(t *testing.T) {
+		v.Heading = 350
+		v.YawRate = 0
+		c.Stop()
+
+		for range int(60 / dt) {
+			v.Step(c.Update(Command{Course: 10, Speed: 1.5}, v.Measurement(), dt), dt)
+		}
+
+		require.InDelta(t, 0, geo.NormalizeAngle(v.Heading-10), 1)
+	})
+
+	t.Run("без измерений", func(t *testing.T) {
+		sp := c.Update(cmd, Measurement{}, dt)
+		require.Equal(t, Setpoint{}, sp)
+	})
+}
+
+func TestMeasurements(t *testing.T) {
+	course, speed := GNSSCourse(&proto.GNSSData{Heading: 27000000, GSppeed: 150})
+	require.InDelta(t, 270, course, 1e-9)
+	require.InDelta(t, 1.5, speed, 1e-9)
+
+	require.InDelta(t, 0, MagneticHeading(&proto.IMUData{Mx: 300}), 1e-9)
+	require.InDelta(t, 90, MagneticHeading(&proto.IMUData{My: -300}), 1e-9)
+	require.InDelta(t, 270, MagneticHeading(&proto.IMUData{My: 300}), 1e-9)
+}
@@ -0,0 +1,72 @@
+package guidance
+
+import (
+	"math"
+)
+
+// PID регулятор с ограничением выхода, защитой от насыщения интегратора и ограничением
+// скорости изменения выхода. Не безопасен для одновременного использования.
+type PID struct {
+	Kp, Ki, Kd float64
+	// Min, Max пределы выхода
+	Min, Max float64
+	// RateLimit наибольшая скорость изменения выхода в единицах в секунду, 0 - без ограничения
+	RateLimit float64
+
+	integral float64
+	prevErr  float64
+	output   float64
+	started  bool
+}
+
+// Update возвращает выход по ошибке err через dt секунд после предыдущего вызова.
+func (p *PID) Update(err, dt float64) float64 {
+	if dt <= 0 {
+		return p.output
+	}
+
+	var derivative float64
+	if p.started {
+		derivative = (err - p.prevErr) / dt
+	}
+
+	integral := p.integral + p.Ki*err*dt
+	u := p.Kp*err + integral + p.Kd*derivative
+
+	// интегратор не накапливается, пока выход в насыщении и ошибка углубляет насыщение
+	if (u > p.Max && err > 0) || (u < p.Min && err < 0) {
+		integral = p.integral
+		u = p.Kp*err + integral + p.Kd*derivative
+	}
+
+	u = clamp(u, p.Min, p.Max)
+
+	if p.RateLimit > 0 {
+		step := p.RateLimit * dt
+		u = clamp(u, p.output-step, p.output+step)
+	}
+
+	p.integral = integral
+	p.prevErr = err
+	p.output = u
+	p.started = true
+
+	return u
+}
+
+// Output возвращает последний выход регулятора.
+func (p *PID) Output() float64 {
+	return p.output
+}
+
+// Reset сбрасывает состояние регулятора, выход становится нулевым.
+func (p *PID) Reset() {
+	p.integral = 0
+	p.prevErr = 0
+	p.output = 0
+	p.started = false
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
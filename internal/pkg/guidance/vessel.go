@@ -0,0 +1,45 @@
+package guidance
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"math"
+)
+
+// Vessel упрощенная модель движения аппарата для проверки регуляторов без аппаратуры: скорость
+// и угловая скорость следуют за тягой и рулем с запаздыванием первого порядка.
+type Vessel struct {
+	Position geo.Point
+	// Heading курс в градусах, Speed скорость в м/с, YawRate угловая скорость в град/с
+	Heading float64
+	Speed   float64
+	YawRate float64
+
+	// MaxSpeed установившаяся скорость при полной тяге в м/с, SpeedTau постоянная времени
+	// разгона в секундах
+	MaxSpeed float64
+	SpeedTau float64
+	// TurnGain установившаяся угловая скорость в град/с на градус руля и м/с скорости, YawTau
+	// постоянная времени поворота в секундах
+	TurnGain float64
+	YawTau   float64
+}
+
+// Step продвигает модель на dt секунд с уставкой sp.
+func (v *Vessel) Step(sp Setpoint, dt float64) {
+	v.Speed += (v.MaxSpeed*sp.Thrust/100 - v.Speed) * dt / v.SpeedTau
+	v.YawRate += (v.TurnGain*sp.Rudder*v.Speed - v.YawRate) * dt / v.YawTau
+	v.Heading = geo.NormalizeBearing(v.Heading + v.YawRate*dt)
+
+	rad := v.Heading * math.Pi / 180
+	v.Position = geo.Offset(v.Position, v.Speed*math.Cos(rad)*dt, v.Speed*math.Sin(rad)*dt)
+}
+
+// Measurement возвращает измерения модели.
+func (v *Vessel) Measurement() Measurement {
+	return Measurement{
+		Heading:      v.Heading,
+		HeadingValid: true,
+		Speed:        v.Speed,
+		SpeedValid:   true,
+	}
+}
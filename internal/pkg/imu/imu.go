@@ -0,0 +1,55 @@
+// Package imu предоставляет измерения ИНС в единицах СИ, общие для оценки ориентации и
+// навигационного фильтра
+package imu
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/proto"
+)
+
+// Gravity ускорение свободного падения, м/с^2
+const Gravity = 9.80665
+
+// Sample измерение ИНС в связанной системе координат: ось X направлена в нос, ось Y - на
+// правый борт, ось Z - вниз.
+type Sample struct {
+	// Gx, Gy, Gz угловые скорости в рад/с, положительная Gz - поворот вправо
+	Gx, Gy, Gz float64
+	// Ax, Ay, Az ускорения в м/с^2, в покое Az = -g
+	Ax, Ay, Az float64
+	// Mx, My, Mz магнитное поле в произвольных единицах; нулевое - не измерено
+	Mx, My, Mz float64
+}
+
+// Magnetic сообщает, измерено ли магнитное поле.
+func (s Sample) Magnetic() bool {
+	return s.Mx != 0 || s.My != 0 || s.Mz != 0
+}
+
+// FromProto возвращает измерение по сообщению IMU режима WritingModeA или WritingModeB
+// (с магнитометром); false - режим без инерциальных измерений или не заданы масштабные
+// коэффициенты.
+func FromProto(d *proto.IMUData, mode proto.MessageID) (Sample, bool) {
+	// AccFactor и GyrFactor - количество единиц младшего разряда на g и на град/с
+	if d.AccFactor == 0 || d.GyrFactor == 0 {
+		return Sample{}, false
+	}
+
+	acc := Gravity / float64(d.AccFactor)
+	gyr := geo.Rad(1) / float64(d.GyrFactor)
+
+	s := Sample{
+		Gx: float64(d.Gx) * gyr, Gy: float64(d.Gy) * gyr, Gz: float64(d.Gz) * gyr,
+		Ax: float64(d.Ax) * acc, Ay: float64(d.Ay) * acc, Az: float64(d.Az) * acc,
+	}
+
+	switch mode {
+	case proto.WritingModeA:
+	case proto.WritingModeB:
+		s.Mx, s.My, s.Mz = float64(d.Mx), float64(d.My), float64(d.Mz)
+	default:
+		return Sample{}, false
+	}
+
+	return s, true
+}
@@ -0,0 +1,32 @@
+package imu
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/proto"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromProto(t *testing.T) {
+	d := &proto.IMUData{AccFactor: 4096, GyrFactor: 64, Ax: 2048, Ay: -4096, Az: -4096, Gz: 640, Mx: 100}
+
+	s, ok := FromProto(d, proto.WritingModeA)
+	require.True(t, ok)
+	require.InDelta(t, Gravity/2, s.Ax, 1e-9)
+	require.InDelta(t, -Gravity, s.Ay, 1e-9)
+	require.InDelta(t, -Gravity, s.Az, 1e-9)
+	require.InDelta(t, geo.Rad(10), s.Gz, 1e-9)
+	require.False(t, s.Magnetic())
+
+	s, ok = FromProto(d, proto.WritingModeB)
+	require.True(t, ok)
+	require.Equal(t, 100.0, s.Mx)
+	require.True(t, s.Magnetic())
+
+	_, ok = FromProto(d, proto.WritingModeC)
+	require.False(t, ok)
+
+	_, ok = FromProto(&proto.IMUData{Az: -4096}, proto.WritingModeA)
+	require.False(t, ok)
+}
@@ -0,0 +1,260 @@
+// Package linkstats предоставляет счетчики и гистограммы качества канала связи
+package linkstats
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLogInterval период вывода статистики в лог по умолчанию
+const DefaultLogInterval = time.Minute
+
+// DefaultBuckets верхние границы корзин гистограммы длительностей
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// Provider источник статистики канала связи
+type Provider interface {
+	Stats() Snapshot
+}
+
+// Counter монотонно возрастающий счетчик, безопасный для конкурентного использования
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(n int) {
+	if n > 0 {
+		c.v.Add(uint64(n))
+	}
+}
+
+func (c *Counter) Load() uint64 {
+	return c.v.Load()
+}
+
+// Histogram гистограмма длительностей с фиксированными корзинами. Последняя корзина
+// содержит значения больше последней границы.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	counts  []uint64
+	count   uint64
+	sum     time.Duration
+	min     time.Duration
+	max     time.Duration
+}
+
+func NewHistogram(buckets []time.Duration) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// Observe добавляет значение d в гистограмму.
+func (h *Histogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.buckets) && d > h.buckets[i] {
+		i++
+	}
+
+	h.counts[i]++
+
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+
+	h.max = max(h.max, d)
+	h.count++
+	h.sum += d
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  append([]uint64(nil), h.counts...),
+		Count:   h.count,
+		Sum:     h.sum,
+		Min:     h.min,
+		Max:     h.max,
+	}
+}
+
+// HistogramSnapshot снимок гистограммы
+type HistogramSnapshot struct {
+	Buckets []time.Duration
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+	Min     time.Duration
+	Max     time.Duration
+}
+
+func (h HistogramSnapshot) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile возвращает оценку квантиля q сверху: границу корзины, в которую попадает квантиль.
+// Для значений за последней границей возвращается максимум.
+func (h HistogramSnapshot) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.Count)))
+
+	var seen uint64
+
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= rank && i < len(h.Buckets) {
+			return min(h.Buckets[i], h.Max)
+		}
+	}
+
+	return h.Max
+}
+
+func (h HistogramSnapshot) String() string {
+	if h.Count == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf(
+		"mean=%v p50<=%v p95<=%v max=%v",
+		h.Mean().Round(time.Microsecond), h.Quantile(0.5).Round(time.Microsecond),
+		h.Quantile(0.95).Round(time.Microsecond), h.Max.Round(time.Microsecond),
+	)
+}
+
+// Stats статистика канала связи
+type Stats struct {
+	FramesSent       Counter
+	BytesSent        Counter
+	FramesReceived   Counter
+	BytesReceived    Counter
+	Retries          Counter
+	AckTimeouts      Counter
+	AckFailures      Counter
+	ChecksumFailures Counter
+	Resyncs          Counter
+	Reopens          Counter
+	Decimated        Counter
+	// AckRTT время от начала отправки фрейма до получения подтверждения
+	AckRTT *Histogram
+	// QueueLatency время от получения измерения до начала его отправки
+	QueueLatency *Histogram
+}
+
+func New() *Stats {
+	return &Stats{
+		AckRTT:       NewHistogram(DefaultBuckets),
+		QueueLatency: NewHistogram(DefaultBuckets),
+	}
+}
+
+// Snapshot снимок статистики канала связи
+type Snapshot struct {
+	FramesSent       uint64
+	BytesSent        uint64
+	FramesReceived   uint64
+	BytesReceived    uint64
+	Retries          uint64
+	AckTimeouts      uint64
+	AckFailures      uint64
+	ChecksumFailures uint64
+	Resyncs          uint64
+	Reopens          uint64
+	Decimated        uint64
+	AckRTT           HistogramSnapshot
+	QueueLatency     HistogramSnapshot
+}
+
+func (s *Stats) Snapshot() Snapshot {
+	return Snapshot{
+		FramesSent:       s.FramesSent.Load(),
+		BytesSent:        s.BytesSent.Load(),
+		FramesReceived:   s.FramesReceived.Load(),
+		BytesReceived:    s.BytesReceived.Load(),
+		Retries:          s.Retries.Load(),
+		AckTimeouts:      s.AckTimeouts.Load(),
+		AckFailures:      s.AckFailures.Load(),
+		ChecksumFailures: s.ChecksumFailures.Load(),
+		Resyncs:          s.Resyncs.Load(),
+		Reopens:          s.Reopens.Load(),
+		Decimated:        s.Decimated.Load(),
+		AckRTT:           s.AckRTT.Snapshot(),
+		QueueLatency:     s.QueueLatency.Snapshot(),
+	}
+}
+
+func (s Snapshot) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "sent: %d frames/%d B, received: %d frames/%d B", s.FramesSent, s.BytesSent, s.FramesReceived, s.BytesReceived)
+	fmt.Fprintf(&b, ", retries: %d, ack timeouts: %d, ack failures: %d", s.Retries, s.AckTimeouts, s.AckFailures)
+	fmt.Fprintf(&b, ", checksum failures: %d, resyncs: %d, reopens: %d", s.ChecksumFailures, s.Resyncs, s.Reopens)
+	fmt.Fprintf(&b, ", decimated: %d", s.Decimated)
+
+	if s.AckRTT.Count > 0 {
+		fmt.Fprintf(&b, ", ack rtt: %s", s.AckRTT)
+	}
+
+	if s.QueueLatency.Count > 0 {
+		fmt.Fprintf(&b, ", queue latency: %s", s.QueueLatency)
+	}
+
+	return b.String()
+}
+
+// Log выводит статистику p в лог с периодом interval до отмены контекста.
+func Log(ctx context.Context, log logger.Logger, interval time.Duration, p Provider) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Infof("link stats: %s", p.Stats())
+		}
+	}
+}
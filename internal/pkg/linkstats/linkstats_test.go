@@ -0,0 +1,59 @@
+package linkstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	t.Run("пустая гистограмма", func(t *testing.T) {
+		s := NewHistogram(DefaultBuckets).Snapshot()
+
+		require.Zero(t, s.Mean())
+		require.Zero(t, s.Quantile(0.5))
+		require.Equal(t, "-", s.String())
+	})
+
+	t.Run("распределение по корзинам и квантили", func(t *testing.T) {
+		h := NewHistogram([]time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond})
+
+		for range 90 {
+			h.Observe(500 * time.Microsecond)
+		}
+
+		for range 9 {
+			h.Observe(50 * time.Millisecond)
+		}
+
+		h.Observe(time.Second)
+
+		s := h.Snapshot()
+		require.Equal(t, []uint64{90, 0, 9, 1}, s.Counts)
+		require.Equal(t, uint64(100), s.Count)
+		require.Equal(t, 500*time.Microsecond, s.Min)
+		require.Equal(t, time.Second, s.Max)
+		require.Equal(t, time.Millisecond, s.Quantile(0.5))
+		require.Equal(t, 100*time.Millisecond, s.Quantile(0.95))
+		require.Equal(t, time.Second, s.Quantile(1))
+	})
+}
+
+func TestStatsSnapshot(t *testing.T) {
+	s := New()
+
+	s.FramesSent.Inc()
+	s.BytesSent.Add(15)
+	s.BytesSent.Add(-1)
+	s.Retries.Inc()
+	s.AckRTT.Observe(3 * time.Millisecond)
+
+	snapshot := s.Snapshot()
+	require.Equal(t, uint64(1), snapshot.FramesSent)
+	require.Equal(t, uint64(15), snapshot.BytesSent)
+	require.Equal(t, uint64(1), snapshot.Retries)
+	require.Equal(t, uint64(1), snapshot.AckRTT.Count)
+	require.Contains(t, snapshot.String(), "ack rtt: mean=3ms")
+	require.NotContains(t, snapshot.String(), "queue latency")
+}
@@ -0,0 +1,293 @@
+// Package metrics предоставляет метрики в текстовом формате Prometheus: набор значений,
+// собираемый при каждом запросе, и HTTP-сервер /metrics
+package metrics
+
+import (
+	"asvsoft/internal/pkg/linkstats"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/utils"
+	"bufio"
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultListen адрес метрик контроллера по умолчанию, доступный только локально
+	DefaultListen = "127.0.0.1:9642"
+	// ContentType тип содержимого текстового формата Prometheus
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// Namespace префикс имен метрик
+	Namespace = "asvsoft_"
+)
+
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// Label метка значения метрики
+type Label struct {
+	Name  string
+	Value string
+}
+
+func L(name, value string) Label {
+	return Label{Name: name, Value: value}
+}
+
+type sample struct {
+	suffix string
+	labels []Label
+	value  float64
+}
+
+// family значения одной метрики. Формат требует, чтобы значения метрики шли подряд,
+// поэтому они группируются до записи.
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	samples []sample
+}
+
+// Set набор значений метрик. Значения одной метрики группируются в порядке добавления,
+// метрики выводятся в порядке первого добавления.
+type Set struct {
+	families []*family
+	index    map[string]*family
+}
+
+func NewSet() *Set {
+	return &Set{index: make(map[string]*family)}
+}
+
+func (s *Set) family(name, help string, typ metricType) *family {
+	name = Namespace + name
+
+	f, ok := s.index[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		s.families = append(s.families, f)
+		s.index[name] = f
+	}
+
+	return f
+}
+
+// Counter добавляет значение счетчика name. Имя счетчика должно оканчиваться на _total.
+func (s *Set) Counter(name, help string, v float64, labels ...Label) {
+	f := s.family(name, help, counterType)
+	f.samples = append(f.samples, sample{labels: labels, value: v})
+}
+
+func (s *Set) Gauge(name, help string, v float64, labels ...Label) {
+	f := s.family(name, help, gaugeType)
+	f.samples = append(f.samples, sample{labels: labels, value: v})
+}
+
+// Histogram добавляет гистограмму длительностей name в секундах с накопленными значениями корзин.
+func (s *Set) Histogram(name, help string, h linkstats.HistogramSnapshot, labels ...Label) {
+	f := s.family(name, help, histogramType)
+
+	var cumulative uint64
+
+	for i, le := range h.Buckets {
+		if i < len(h.Counts) {
+			cumulative += h.Counts[i]
+		}
+
+		f.samples = append(f.samples, sample{
+			suffix: "_bucket",
+			labels: withLabel(labels, L("le", formatFloat(le.Seconds()))),
+			value:  float64(cumulative),
+		})
+	}
+
+	f.samples = append(f.samples,
+		sample{suffix: "_bucket", labels: withLabel(labels, L("le", "+Inf")), value: float64(h.Count)},
+		sample{suffix: "_sum", labels: labels, value: h.Sum.Seconds()},
+		sample{suffix: "_count", labels: labels, value: float64(h.Count)},
+	)
+}
+
+// Link добавляет статистику канала связи.
+func (s *Set) Link(snapshot linkstats.Snapshot, labels ...Label) {
+	counters := []struct {
+		name  string
+		help  string
+		value uint64
+	}{
+		{"link_frames_sent_total", "Frames sent.", snapshot.FramesSent},
+		{"link_bytes_sent_total", "Bytes sent.", snapshot.BytesSent},
+		{"link_frames_received_total", "Frames received.", snapshot.FramesReceived},
+		{"link_bytes_received_total", "Bytes received.", snapshot.BytesReceived},
+		{"link_retries_total", "Frame resends after a failed delivery.", snapshot.Retries},
+		{"link_ack_timeouts_total", "Acknowledgements not received in time.", snapshot.AckTimeouts},
+		{"link_ack_failures_total", "Negative acknowledgements.", snapshot.AckFailures},
+		{"link_checksum_failures_total", "Frames with a bad checksum.", snapshot.ChecksumFailures},
+		{"link_resyncs_total", "Frame header searches after a broken frame.", snapshot.Resyncs},
+		{"link_reopens_total", "Port reopens after the port was closed.", snapshot.Reopens},
+		{"link_decimated_total", "Measurements dropped to fit the link budget.", snapshot.Decimated},
+	}
+
+	for _, c := range counters {
+		s.Counter(c.name, c.help, float64(c.value), labels...)
+	}
+
+	s.Histogram("link_ack_rtt_seconds", "Time from the start of sending a frame to its acknowledgement.",
+		snapshot.AckRTT, labels...)
+	s.Histogram("link_queue_latency_seconds", "Time from taking a measurement to the start of sending it.",
+		snapshot.QueueLatency, labels...)
+}
+
+// WriteTo записывает метрики в текстовом формате Prometheus.
+func (s *Set) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	for _, f := range s.families {
+		cw.write("# HELP ", f.name, " ", escape(f.help, false), "\n")
+		cw.write("# TYPE ", f.name, " ", string(f.typ), "\n")
+
+		for _, smp := range f.samples {
+			cw.write(f.name, smp.suffix)
+
+			if len(smp.labels) > 0 {
+				cw.write("{")
+
+				for i, l := range smp.labels {
+					if i > 0 {
+						cw.write(",")
+					}
+
+					cw.write(l.Name, `="`, escape(l.Value, true), `"`)
+				}
+
+				cw.write("}")
+			}
+
+			cw.write(" ", formatFloat(smp.value), "\n")
+		}
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+
+	return cw.n, cw.err
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) write(parts ...string) {
+	for _, p := range parts {
+		if w.err != nil {
+			return
+		}
+
+		n, err := w.w.WriteString(p)
+		w.n += int64(n)
+		w.err = err
+	}
+}
+
+func withLabel(labels []Label, l Label) []Label {
+	return append(labels[:len(labels):len(labels)], l)
+}
+
+// escape экранирует текст справки или, при quoted, значение метки.
+func escape(s string, quoted bool) string {
+	r := strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	if quoted {
+		r = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	}
+
+	return r.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Collector источник метрик, добавляющий в набор текущие значения
+type Collector interface {
+	Collect(s *Set)
+}
+
+// CollectorFunc позволяет использовать функцию как источник метрик
+type CollectorFunc func(s *Set)
+
+func (f CollectorFunc) Collect(s *Set) {
+	f(s)
+}
+
+// Server HTTP-сервер метрик: GET /metrics собирает значения всех источников.
+type Server struct {
+	collectors []Collector
+	log        logger.Logger
+	mux        *http.ServeMux
+}
+
+func NewServer(collectors ...Collector) *Server {
+	s := &Server{
+		collectors: collectors,
+		log:        logger.DummyLogger{},
+		mux:        http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /metrics", s.metrics)
+
+	return s
+}
+
+func (s *Server) WithLogger(log logger.Logger) *Server {
+	s.log = log
+	return s
+}
+
+// Handler возвращает обработчик запросов метрик.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Serve обслуживает запросы, принимаемые l, до отмены контекста.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	s.log.Infof("serving metrics on http://%s/metrics", l.Addr())
+
+	return utils.ServeHTTP(ctx, l, s.mux)
+}
+
+func (s *Server) metrics(w http.ResponseWriter, _ *http.Request) {
+	set := NewSet()
+
+	for _, c := range s.collectors {
+		c.Collect(set)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+
+	_, err := set.WriteTo(w)
+	if err != nil {
+		s.log.Errorf("cannot write metrics: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"asvsoft/internal/pkg/linkstats"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	t.Run("значения метрики группируются", func(t *testing.T) {
+		s := NewSet()
+		s.Counter("messages_total", "Messages received.", 3, L("module", "gnss"))
+		s.Gauge("up", "Module is running.", 1)
+		s.Counter("messages_total", "Messages received.", 5, L("module", "lidar"))
+
+		var b strings.Builder
+
+		n, err := s.WriteTo(&b)
+		require.NoError(t, err)
+		require.Equal(t, int64(b.Len()), n)
+		require.Equal(t, strings.Join([]string{
+			"# HELP asvsoft_messages_total Messages received.",
+			"# TYPE asvsoft_messages_total counter",
+			`asvsoft_messages_total{module="gnss"} 3`,
+			`asvsoft_messages_total{module="lidar"} 5`,
+			"# HELP asvsoft_up Module is running.",
+			"# TYPE asvsoft_up gauge",
+			"asvsoft_up 1",
+			"",
+		}, "\n"), b.String())
+	})
+
+	t.Run("экранирование и особые значения", func(t *testing.T) {
+		s := NewSet()
+		s.Gauge("offset_seconds", "Offset\nof \\ time.", math.NaN(), L("port", `C:\ "a"`+"\n"))
+		s.Gauge("offset_seconds", "", math.Inf(1))
+
+		var b strings.Builder
+
+		_, err := s.WriteTo(&b)
+		require.NoError(t, err)
+		require.Contains(t, b.String(), `# HELP asvsoft_offset_seconds Offset\nof \\ time.`)
+		require.Contains(t, b.String(), `asvsoft_offset_seconds{port="C:\\ \"a\"\n"} NaN`)
+		require.Contains(t, b.String(), "asvsoft_offset_seconds +Inf\n")
+	})
+
+	t.Run("гистограмма с накопленными корзинами", func(t *testing.T) {
+		h := linkstats.NewHistogram([]time.Duration{time.Millisecond, 10 * time.Millisecond})
+		h.Observe(500 * time.Microsecond)
+		h.Observe(5 * time.Millisecond)
+		h.Observe(time.Second)
+
+		s := NewSet()
+		s.Histogram("latency_seconds", "Latency.", h.Snapshot(), L("module", "gnss"))
+
+		var b strings.Builder
+
+		_, err := s.WriteTo(&b)
+		require.NoError(t, err)
+		require.Equal(t, strings.Join([]string{
+			"# HELP asvsoft_latency_seconds Latency.",
+			"# TYPE asvsoft_latency_seconds histogram",
+			`asvsoft_latency_seconds_bucket{module="gnss",le="0.001"} 1`,
+			`asvsoft_latency_seconds_bucket{module="gnss",le="0.01"} 2`,
+			`asvsoft_latency_seconds_bucket{module="gnss",le="+Inf"} 3`,
+			`asvsoft_latency_seconds_sum{module="gnss"} 1.0055`,
+			`asvsoft_latency_seconds_count{module="gnss"} 3`,
+			"",
+		}, "\n"), b.String())
+	})
+}
+
+func TestServer(t *testing.T) {
+	stats := linkstats.New()
+	stats.ChecksumFailures.Inc()
+	stats.Reopens.Add(2)
+
+	srv := httptest.NewServer(NewServer(CollectorFunc(func(s *Set) {
+		s.Link(stats.Snapshot(), L("channel", "gnss"))
+	})).Handler())
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `asvsoft_link_checksum_failures_total{channel="gnss"} 1`)
+	require.Contains(t, string(body), `asvsoft_link_reopens_total{channel="gnss"} 2`)
+	require.Contains(t, string(body), `asvsoft_link_queue_latency_seconds_count{channel="gnss"} 0`)
+}
@@ -0,0 +1,223 @@
+// Package mission предоставляет миссии по точкам маршрута: загрузку из yaml или GeoJSON,
+// отслеживание прохождения точек по координатам ГНСС и сохранение прогресса
+package mission
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultAcceptanceRadius радиус достижения точки по умолчанию в метрах
+	DefaultAcceptanceRadius = 5.0
+	// DefaultSpeed скорость движения к точке по умолчанию в м/с
+	DefaultSpeed = 1.5
+)
+
+// Waypoint точка маршрута
+type Waypoint struct {
+	Name      string `yaml:"name" json:"name,omitempty"`
+	geo.Point `yaml:",inline"`
+	// AcceptanceRadius радиус достижения точки в метрах, 0 - значение миссии
+	AcceptanceRadius float64 `yaml:"acceptance_radius" json:"acceptance_radius"`
+	// Speed скорость движения к точке в м/с, 0 - значение миссии
+	Speed float64 `yaml:"speed" json:"speed"`
+	// Loiter время удержания в точке после ее достижения
+	Loiter time.Duration `yaml:"loiter" json:"loiter"`
+}
+
+// Mission миссия: точки маршрута, проходимые по порядку
+type Mission struct {
+	Name string `yaml:"name"`
+	// AcceptanceRadius и Speed значения для точек, в которых они не заданы
+	AcceptanceRadius float64    `yaml:"acceptance_radius"`
+	Speed            float64    `yaml:"speed"`
+	Waypoints        []Waypoint `yaml:"waypoints"`
+	// ID контрольная сумма файла миссии, по которой прогресс сопоставляется с миссией
+	ID string `yaml:"-"`
+}
+
+// Load загружает миссию из файла path: yaml (.yaml, .yml) или GeoJSON (.geojson, .json).
+func Load(path string) (*Mission, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read mission: %w", err)
+	}
+
+	var m *Mission
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		m, err = parseYAML(data)
+	case ".geojson", ".json":
+		m, err = parseGeoJSON(data)
+	default:
+		return nil, fmt.Errorf("unknown mission format %q, want yaml or geojson", ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse mission %s: %w", path, err)
+	}
+
+	if m.Name == "" {
+		m.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	sum := sha256.Sum256(data)
+	m.ID = hex.EncodeToString(sum[:8])
+
+	m.setDefaults()
+
+	err = m.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("bad mission %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+func parseYAML(data []byte) (*Mission, error) {
+	var m Mission
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	err := dec.Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Name     string           `json:"name"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Name             string  `json:"name"`
+		AcceptanceRadius float64 `json:"acceptance_radius"`
+		Speed            float64 `json:"speed"`
+		// Loiter время удержания в секундах
+		Loiter float64 `json:"loiter"`
+	} `json:"properties"`
+}
+
+// parseGeoJSON разбирает коллекцию объектов GeoJSON: каждая точка (Point) - точка маршрута,
+// каждая вершина линии (LineString) - точка маршрута со свойствами линии.
+func parseGeoJSON(data []byte) (*Mission, error) {
+	var fc geoJSONFeatureCollection
+
+	err := json.Unmarshal(data, &fc)
+	if err != nil {
+		return nil, err
+	}
+
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("unexpected geojson type %q, want FeatureCollection", fc.Type)
+	}
+
+	m := &Mission{Name: fc.Name}
+
+	for i, f := range fc.Features {
+		var coords [][]float64
+
+		switch f.Geometry.Type {
+		case "Point":
+			var c []float64
+
+			err = json.Unmarshal(f.Geometry.Coordinates, &c)
+			coords = [][]float64{c}
+		case "LineString":
+			err = json.Unmarshal(f.Geometry.Coordinates, &coords)
+		default:
+			return nil, fmt.Errorf("feature %d: unsupported geometry %q, want Point or LineString", i, f.Geometry.Type)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: bad coordinates: %w", i, err)
+		}
+
+		for _, c := range coords {
+			if len(c) < 2 {
+				return nil, fmt.Errorf("feature %d: position must have longitude and latitude", i)
+			}
+
+			m.Waypoints = append(m.Waypoints, Waypoint{
+				Name:             f.Properties.Name,
+				Point:            geo.Point{Lat: c[1], Lon: c[0]},
+				AcceptanceRadius: f.Properties.AcceptanceRadius,
+				Speed:            f.Properties.Speed,
+				Loiter:           time.Duration(f.Properties.Loiter * float64(time.Second)),
+			})
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Mission) setDefaults() {
+	if m.AcceptanceRadius == 0 {
+		m.AcceptanceRadius = DefaultAcceptanceRadius
+	}
+
+	if m.Speed == 0 {
+		m.Speed = DefaultSpeed
+	}
+
+	for i := range m.Waypoints {
+		wp := &m.Waypoints[i]
+
+		if wp.Name == "" {
+			wp.Name = fmt.Sprintf("wp%d", i+1)
+		}
+
+		if wp.AcceptanceRadius == 0 {
+			wp.AcceptanceRadius = m.AcceptanceRadius
+		}
+
+		if wp.Speed == 0 {
+			wp.Speed = m.Speed
+		}
+	}
+}
+
+// Validate проверяет точки маршрута миссии.
+func (m *Mission) Validate() error {
+	if len(m.Waypoints) == 0 {
+		return fmt.Errorf("mission has no waypoints")
+	}
+
+	for i, wp := range m.Waypoints {
+		switch {
+		case !wp.Valid():
+			return fmt.Errorf("waypoint %d (%s): coordinates out of range: %+v", i, wp.Name, wp.Point)
+		case wp.AcceptanceRadius <= 0:
+			return fmt.Errorf("waypoint %d (%s): acceptance radius must be positive, got %v", i, wp.Name, wp.AcceptanceRadius)
+		case wp.Speed < 0:
+			return fmt.Errorf("waypoint %d (%s): speed must not be negative, got %v", i, wp.Name, wp.Speed)
+		case wp.Loiter < 0:
+			return fmt.Errorf("waypoint %d (%s): loiter must not be negative, got %v", i, wp.Name, wp.Loiter)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package mission
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const missionYAML = `
+name: harbor
+acceptance_radius: 10
+waypoints:
+  - lat: 59.9300
+    lon: 30.3100
+  - name: buoy
+    lat: 59.9310
+    lon: 30.3100
+    acceptance_radius: 3
+    speed: 2
+    loiter: 30s
+`
+
+const missionGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [30.31, 59.93]}, "properties": {"name": "start"}},
+    {"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[30.311, 59.93], [30.312, 59.931]]},
+     "properties": {"speed": 1, "loiter": 5}}
+  ]
+}`
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		m, err := Load(writeFile(t, "harbor.yaml", missionYAML))
+		require.NoError(t, err)
+
+		require.Equal(t, "harbor", m.Name)
+		require.NotEmpty(t, m.ID)
+		require.Len(t, m.Waypoints, 2)
+		require.Equal(t, "wp1", m.Waypoints[0].Name)
+		require.Equal(t, 10.0, m.Waypoints[0].AcceptanceRadius)
+		require.Equal(t, DefaultSpeed, m.Waypoints[0].Speed)
+		require.Equal(t, 3.0, m.Waypoints[1].AcceptanceRadius)
+		require.Equal(t, 30*time.Second, m.Waypoints[1].Loiter)
+	})
+
+	t.Run("geojson", func(t *testing.T) {
+		m, err := Load(writeFile(t, "loop.geojson", missionGeoJSON))
+		require.NoError(t, err)
+
+		require.Equal(t, "loop", m.Name)
+		require.Len(t, m.Waypoints, 3)
+		require.Equal(t, geo.Point{Lat: 59.93, Lon: 30.31}, m.Waypoints[0].Point)
+		require.Equal(t, 1.0, m.Waypoints[2].Speed)
+		require.Equal(t, 5*time.Second, m.Waypoints[2].Loiter)
+	})
+
+	t.Run("ошибки", func(t *testing.T) {
+		_, err := Load(writeFile(t, "empty.yaml", "name: empty\n"))
+		require.ErrorContains(t, err, "no waypoints")
+
+		_, err = Load(writeFile(t, "bad.yaml", "waypoints:\n  - lat: 95\n    lon: 0\n"))
+		require.ErrorContains(t, err, "out of range")
+
+		_, err = Load(writeFile(t, "unknown.yaml", "waypoints:\n  - lat: 1\n    lng: 0\n"))
+		require.Error(t, err)
+
+		_, err = Load(writeFile(t, "mission.kml", ""))
+		require.ErrorContains(t, err, "unknown mission format")
+	})
+}
+
+func TestTracker(t *testing.T) {
+	origin := geo.Point{Lat: 59.93, Lon: 30.31}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	m := &Mission{ID: "test", Waypoints: []Waypoint{
+		{Name: "a", Point: geo.Offset(origin, 100, 0), AcceptanceRadius: 5, Speed: 1},
+		{Name: "b", Point: geo.Offset(origin, 100, 100), AcceptanceRadius: 5, Speed: 2, Loiter: 10 * time.Second},
+	}}
+
+	tr := NewTracker(m)
+
+	kinds := func(events []Event) []EventKind {
+		var result []EventKind
+		for _, e := range events {
+			result = append(result, e.Kind)
+		}
+
+		return result
+	}
+
+	t.Run("запуск и положение относительно точки", func(t *testing.T) {
+		state, events := tr.Update(origin, start)
+		require.Equal(t, []EventKind{Started}, kinds(events))
+		require.InDelta(t, 100, state.Distance, 0.1)
+		require.InDelta(t, 0, state.Bearing, 1e-6)
+
+		state, events = tr.Update(geo.Offset(origin, 50, 20), start.Add(time.Minute))
+		require.Empty(t, events)
+		require.InDelta(t, 20, state.CrossTrack, 0.1)
+		require.Equal(t, 1.0, state.Speed)
+	})
+
+	t.Run("достижение точки без удержания", func(t *testing.T) {
+		state, events := tr.Update(geo.Offset(origin, 98, 1), start.Add(2*time.Minute))
+		require.Equal(t, []EventKind{Reached, Departed}, kinds(events))
+		require.Equal(t, 1, state.Index)
+		require.InDelta(t, 2, state.CrossTrack, 0.1)
+	})
+
+	t.Run("удержание в точке", func(t *testing.T) {
+		at := start.Add(3 * time.Minute)
+
+		state, events := tr.Update(geo.Offset(origin, 100, 98), at)
+		require.Equal(t, []EventKind{Reached}, kinds(events))
+		require.True(t, state.Loitering)
+		require.Equal(t, 10*time.Second, state.LoiterLeft)
+
+		_, events = tr.Update(geo.Offset(origin, 100, 98), at.Add(5*time.Second))
+		require.Empty(t, events)
+
+		state, events = tr.Update(geo.Offset(origin, 100, 90), at.Add(10*time.Second))
+		require.Equal(t, []EventKind{Completed}, kinds(events))
+		require.True(t, state.Complete)
+	})
+
+	t.Run("продолжение после перезапуска", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "progress.json")
+
+		_, err := LoadProgress(path)
+		require.True(t, errors.Is(err, fs.ErrNotExist))
+
+		p := tr.Progress()
+		p.Index = 1
+		p.Arrived = nil
+		require.NoError(t, SaveProgress(path, p))
+
+		loaded, err := LoadProgress(path)
+		require.NoError(t, err)
+
+		resumed := NewTracker(m)
+		require.NoError(t, resumed.Resume(loaded))
+
+		state, events := resumed.Update(origin, start.Add(time.Hour))
+		require.Equal(t, []EventKind{Resumed}, kinds(events))
+		require.Equal(t, 1, state.Index)
+		require.Equal(t, "b", state.Target.Name)
+
+		other := NewTracker(&Mission{ID: "other", Waypoints: m.Waypoints})
+		require.ErrorIs(t, other.Resume(loaded), ErrOtherMission)
+	})
+}
@@ -0,0 +1,246 @@
+package mission
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrOtherMission прогресс сохранен для другой миссии
+var ErrOtherMission = errors.New("progress belongs to another mission")
+
+// EventKind вид события миссии
+type EventKind string
+
+const (
+	Started   EventKind = "started"
+	Resumed   EventKind = "resumed"
+	Reached   EventKind = "reached"
+	Departed  EventKind = "departed"
+	Completed EventKind = "completed"
+)
+
+// Event событие миссии
+type Event struct {
+	Kind EventKind
+	// Index номер точки маршрута, к которой относится событие
+	Index    int
+	Waypoint Waypoint
+	Position geo.Point
+	At       time.Time
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s waypoint %d (%s)", e.Kind, e.Index, e.Waypoint.Name)
+}
+
+// Progress прогресс миссии, достаточный для продолжения после перезапуска
+type Progress struct {
+	MissionID string `json:"mission_id"`
+	// Index целевая точка маршрута, количество точек - миссия завершена
+	Index int `json:"index"`
+	// Origin начало первого участка: положение при запуске миссии
+	Origin *geo.Point `json:"origin,omitempty"`
+	// Arrived время достижения целевой точки, nil - точка не достигнута
+	Arrived   *time.Time `json:"arrived,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// State положение относительно целевой точки маршрута
+type State struct {
+	Index    int
+	Target   Waypoint
+	Complete bool
+	// Distance расстояние до целевой точки в метрах
+	Distance float64
+	// Bearing пеленг на целевую точку в градусах
+	Bearing float64
+	// CrossTrack отклонение от линии пути участка в метрах, положительное - справа
+	CrossTrack float64
+	// Speed заданная скорость на участке в м/с
+	Speed float64
+	// Loitering целевая точка достигнута, идет удержание; LoiterLeft - оставшееся время
+	Loitering  bool
+	LoiterLeft time.Duration
+}
+
+// Tracker отслеживает прохождение точек маршрута миссии по положению аппарата. Не безопасен
+// для одновременного использования.
+type Tracker struct {
+	m       *Mission
+	p       Progress
+	started bool
+}
+
+func NewTracker(m *Mission) *Tracker {
+	return &Tracker{
+		m: m,
+		p: Progress{MissionID: m.ID},
+	}
+}
+
+// Resume продолжает миссию с прогресса p или возвращает ErrOtherMission.
+func (t *Tracker) Resume(p Progress) error {
+	if p.MissionID != t.m.ID {
+		return fmt.Errorf("%w: %s, want %s", ErrOtherMission, p.MissionID, t.m.ID)
+	}
+
+	if p.Index < 0 || p.Index > len(t.m.Waypoints) {
+		return fmt.Errorf("bad waypoint index %d in progress", p.Index)
+	}
+
+	t.p = p
+
+	return nil
+}
+
+func (t *Tracker) Progress() Progress {
+	return t.p
+}
+
+func (t *Tracker) Mission() *Mission {
+	return t.m
+}
+
+func (t *Tracker) Complete() bool {
+	return t.p.Index >= len(t.m.Waypoints)
+}
+
+// legStart возвращает начало текущего участка: предыдущую точку или положение при запуске.
+func (t *Tracker) legStart(pos geo.Point) geo.Point {
+	if t.p.Index > 0 {
+		return t.m.Waypoints[t.p.Index-1].Point
+	}
+
+	if t.p.Origin != nil {
+		return *t.p.Origin
+	}
+
+	return pos
+}
+
+// Update учитывает положение pos в момент now и возвращает положение относительно целевой
+// точки и произошедшие события. Прогресс изменяется только вместе с событиями.
+func (t *Tracker) Update(pos geo.Point, now time.Time) (State, []Event) {
+	var events []Event
+
+	event := func(kind EventKind, index int) {
+		e := Event{Kind: kind, Index: index, Position: pos, At: now}
+		if index < len(t.m.Waypoints) {
+			e.Waypoint = t.m.Waypoints[index]
+		}
+
+		events = append(events, e)
+		t.p.UpdatedAt = now
+	}
+
+	if !t.started {
+		t.started = true
+
+		if t.p.Origin == nil {
+			origin := pos
+			t.p.Origin = &origin
+
+			event(Started, t.p.Index)
+		} else {
+			event(Resumed, t.p.Index)
+		}
+	}
+
+	for !t.Complete() {
+		wp := t.m.Waypoints[t.p.Index]
+
+		if t.p.Arrived == nil {
+			if geo.Distance(pos, wp.Point) > wp.AcceptanceRadius {
+				break
+			}
+
+			arrived := now
+			t.p.Arrived = &arrived
+
+			event(Reached, t.p.Index)
+		}
+
+		if now.Sub(*t.p.Arrived) < wp.Loiter {
+			break
+		}
+
+		t.p.Index++
+		t.p.Arrived = nil
+
+		if t.Complete() {
+			event(Completed, t.p.Index-1)
+		} else {
+			event(Departed, t.p.Index-1)
+		}
+	}
+
+	return t.state(pos, now), events
+}
+
+func (t *Tracker) state(pos geo.Point, now time.Time) State {
+	if t.Complete() {
+		return State{Index: t.p.Index, Complete: true}
+	}
+
+	wp := t.m.Waypoints[t.p.Index]
+	start := t.legStart(pos)
+
+	s := State{
+		Index:    t.p.Index,
+		Target:   wp,
+		Distance: geo.Distance(pos, wp.Point),
+		Bearing:  geo.Bearing(pos, wp.Point),
+		Speed:    wp.Speed,
+	}
+
+	if geo.Distance(start, wp.Point) > 0 {
+		s.CrossTrack = geo.CrossTrack(start, wp.Point, pos)
+	}
+
+	if t.p.Arrived != nil {
+		s.Loitering = true
+		s.LoiterLeft = wp.Loiter - now.Sub(*t.p.Arrived)
+	}
+
+	return s
+}
+
+// LoadProgress читает прогресс миссии из файла path. Если файла нет, ошибка оборачивает
+// fs.ErrNotExist.
+func LoadProgress(path string) (Progress, error) {
+	var p Progress
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return p, fmt.Errorf("bad mission progress %s: %w", path, err)
+	}
+
+	return p, nil
+}
+
+// SaveProgress записывает прогресс миссии в файл path.
+func SaveProgress(path string, p Progress) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal mission progress: %w", err)
+	}
+
+	// файл заменяется целиком, чтобы при аварийном завершении не остался обрезанный прогресс
+	tmp := path + ".tmp"
+
+	err = os.WriteFile(tmp, data, 0644) // nolint:gosec
+	if err != nil {
+		return fmt.Errorf("cannot write mission progress: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
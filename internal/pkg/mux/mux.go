@@ -0,0 +1,344 @@
+// Package mux предоставляет мультиплексор, передающий фреймы нескольких модулей по одному
+// каналу связи с контроллером управления
+package mux
+
+import (
+	"asvsoft/internal/pkg/linkstats"
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetriesLimit количество попыток доставки фрейма по каналу
+	DefaultRetriesLimit = 3
+	// defaultAwaitSkips количество посторонних фреймов, пропускаемых при ожидании ответа
+	defaultAwaitSkips = 4
+)
+
+// Input вход мультиплексора: канал связи с одним модулем
+type Input struct {
+	Name string
+	RW   io.ReadWriteCloser
+	// Priority приоритет входа, фреймы входов с большим приоритетом передаются раньше
+	Priority int
+	// Weight вес входа среди входов с одинаковым приоритетом
+	Weight int
+	// QueueSize размер очереди входа. При заполнении очереди чтение входа приостанавливается.
+	QueueSize int
+	// Sync флаг отправки подтверждений модулю
+	Sync bool
+}
+
+// Mux передает фреймы входов по общему каналу uplink. Доставка выполняется поучастково:
+// мультиплексор подтверждает модулю получение фрейма после постановки в очередь, а доставку
+// по общему каналу подтверждает получатель. Запросы синхронизации времени передаются вне
+// очереди, ответ на них возвращается модулю, отправившему запрос.
+type Mux struct {
+	uplink       io.ReadWriter
+	inputs       []Input
+	sync         bool
+	retriesLimit int
+	log          logger.Logger
+	stats        *linkstats.Stats
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	sched   *Scheduler
+	control int
+}
+
+func New(uplink io.ReadWriter) *Mux {
+	m := &Mux{
+		uplink:       uplink,
+		retriesLimit: DefaultRetriesLimit,
+		log:          logger.DummyLogger{},
+		stats:        linkstats.New(),
+		sched:        NewScheduler(),
+	}
+
+	m.cond = sync.NewCond(&m.mu)
+	m.control = m.sched.AddQueue(math.MaxInt, DefaultWeight, DefaultQueueSize)
+
+	return m
+}
+
+func (m *Mux) WithSync(sync bool) *Mux {
+	m.sync = sync
+	return m
+}
+
+func (m *Mux) WithRetriesLimit(retriesLimit int) *Mux {
+	m.retriesLimit = retriesLimit
+	return m
+}
+
+func (m *Mux) WithLogger(log logger.Logger) *Mux {
+	m.log = log
+	return m
+}
+
+// WithInput добавляет вход. Номер очереди входа совпадает с его номером, очередь запросов
+// синхронизации создается первой, поэтому номера входов смещены на единицу.
+func (m *Mux) WithInput(in Input) *Mux {
+	m.sched.AddQueue(in.Priority, in.Weight, in.QueueSize)
+	m.inputs = append(m.inputs, in)
+
+	return m
+}
+
+// Stats возвращает статистику общего канала.
+func (m *Mux) Stats() linkstats.Snapshot {
+	snapshot := m.stats.Snapshot()
+
+	if p, ok := m.uplink.(linkstats.Provider); ok {
+		snapshot.Reopens += p.Stats().Reopens
+	}
+
+	return snapshot
+}
+
+// Run запускает чтение входов и передачу фреймов по общему каналу до отмены контекста.
+func (m *Mux) Run(ctx context.Context) error {
+	if len(m.inputs) == 0 {
+		return errors.New("mux has no inputs")
+	}
+
+	for i := range m.inputs {
+		go m.readInput(ctx, i)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		m.mu.Lock()
+		m.cond.Broadcast()
+		m.mu.Unlock()
+	}()
+
+	for {
+		item, ok := m.next(ctx)
+		if !ok {
+			break
+		}
+
+		m.stats.QueueLatency.Observe(time.Since(item.At))
+
+		err := m.transmit(item)
+		if err != nil {
+			m.log.Errorf("[%s] cannot transmit frame: %v", m.inputs[item.Input].Name, err)
+		}
+	}
+
+	// чтение входов завершается после их закрытия вызывающей стороной
+	return ctx.Err()
+}
+
+// readInput читает фреймы входа i и ставит их в очередь.
+func (m *Mux) readInput(ctx context.Context, i int) {
+	in := m.inputs[i]
+	log := logger.Wrap(m.log, fmt.Sprintf("[%s]", in.Name))
+
+	for ctx.Err() == nil {
+		frame, err := proto.Read(in.RW)
+		if err != nil {
+			log.Debugf("read frame failed: %v", err)
+			continue
+		}
+
+		info, err := proto.VerifyFrame(frame)
+		if err != nil {
+			log.Warnf("drop bad frame: %v", err)
+			m.reply(in, proto.ResponseFail)
+
+			continue
+		}
+
+		item := Item{Input: i, Frame: frame, At: time.Now()}
+
+		if info.MsgID == proto.SyncRequest {
+			m.push(ctx, m.control, item)
+			continue
+		}
+
+		if !m.push(ctx, i+1, item) {
+			return
+		}
+
+		m.reply(in, proto.ResponseOK)
+	}
+}
+
+// push ставит фрейм в очередь, ожидая освобождения места. Возвращает false при отмене контекста.
+func (m *Mux) push(ctx context.Context, queue int, item Item) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for !m.sched.Push(queue, item) {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		m.cond.Wait()
+	}
+
+	m.cond.Broadcast()
+
+	return true
+}
+
+// next ожидает следующий фрейм для передачи. Возвращает false при отмене контекста.
+func (m *Mux) next(ctx context.Context) (Item, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		if ctx.Err() != nil {
+			return Item{}, false
+		}
+
+		item, ok := m.sched.Pop()
+		if ok {
+			m.cond.Broadcast()
+			return item, true
+		}
+
+		m.cond.Wait()
+	}
+}
+
+// transmit передает фрейм по общему каналу. Ответ на запрос синхронизации возвращается модулю.
+func (m *Mux) transmit(item Item) error {
+	in := m.inputs[item.Input]
+
+	info, err := proto.VerifyFrame(item.Frame)
+	if err != nil {
+		return err
+	}
+
+	if info.MsgID == proto.SyncRequest {
+		resp, err := m.exchange(item.Frame, proto.SyncResponse)
+		if err != nil {
+			return fmt.Errorf("sync request failed: %w", err)
+		}
+
+		_, err = in.RW.Write(resp)
+		if err != nil {
+			return fmt.Errorf("cannot forward sync response: %w", err)
+		}
+
+		return nil
+	}
+
+	if !m.sync {
+		return m.write(item.Frame)
+	}
+
+	_, err = m.exchange(item.Frame, proto.ResponseOK)
+
+	return err
+}
+
+// exchange передает фрейм и ожидает ответ want, повторяя передачу до m.retriesLimit раз.
+func (m *Mux) exchange(frame []byte, want proto.MessageID) ([]byte, error) {
+	var err error
+
+	for attempt := range max(m.retriesLimit, 1) {
+		if attempt > 0 {
+			m.stats.Retries.Inc()
+		}
+
+		start := time.Now()
+
+		err = m.write(frame)
+		if err != nil {
+			continue
+		}
+
+		var resp []byte
+
+		resp, err = m.await(want)
+		if err != nil {
+			continue
+		}
+
+		m.stats.AckRTT.Observe(time.Since(start))
+
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("no response after %d attempts: %w", m.retriesLimit, err)
+}
+
+func (m *Mux) write(frame []byte) error {
+	n, err := m.uplink.Write(frame)
+	if err != nil {
+		return fmt.Errorf("cannot write frame: %w", err)
+	}
+
+	m.stats.FramesSent.Inc()
+	m.stats.BytesSent.Add(n)
+
+	return nil
+}
+
+// await читает ответ want из общего канала, пропуская посторонние фреймы, например ответы,
+// запоздавшие после предыдущей попытки.
+func (m *Mux) await(want proto.MessageID) ([]byte, error) {
+	for range defaultAwaitSkips {
+		resp, err := proto.Read(m.uplink)
+		if err != nil {
+			m.stats.AckTimeouts.Inc()
+			return nil, fmt.Errorf("cannot read response: %w", err)
+		}
+
+		m.stats.FramesReceived.Inc()
+		m.stats.BytesReceived.Add(len(resp))
+
+		info, err := proto.VerifyFrame(resp)
+
+		switch {
+		case errors.Is(err, proto.ErrChecksumMismatch):
+			m.stats.ChecksumFailures.Inc()
+			return nil, err
+		case err != nil:
+			return nil, err
+		case info.MsgID == want:
+			return resp, nil
+		case info.MsgID == proto.ResponseFail:
+			m.stats.AckFailures.Inc()
+			return nil, fmt.Errorf("response is not ok: %#X", info.MsgID)
+		case slices.Contains([]proto.MessageID{proto.ResponseOK, proto.SyncResponse}, info.MsgID):
+			m.log.Debugf("skip stale response %#X while waiting %#X", info.MsgID, want)
+		default:
+			return nil, fmt.Errorf("unexpected response: %#X", info.MsgID)
+		}
+	}
+
+	return nil, fmt.Errorf("no response %#X after %d frames", want, defaultAwaitSkips)
+}
+
+// reply отправляет модулю подтверждение от имени контроллера управления.
+func (m *Mux) reply(in Input, msgID proto.MessageID) {
+	if !in.Sync {
+		return
+	}
+
+	resp, err := proto.NewMessage(proto.ControlModuleID, msgID, nil).Marshal()
+	if err != nil {
+		m.log.Errorf("[%s] cannot marshal response: %v", in.Name, err)
+		return
+	}
+
+	_, err = in.RW.Write(resp)
+	if err != nil {
+		m.log.Errorf("[%s] cannot write response: %v", in.Name, err)
+	}
+}
@@ -0,0 +1,114 @@
+package mux
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fullConn дочитывает буфер целиком, как это делают каналы пакета transport.
+type fullConn struct {
+	net.Conn
+}
+
+func (c fullConn) Read(p []byte) (int, error) {
+	return io.ReadFull(c.Conn, p)
+}
+
+func pipe() (fullConn, fullConn) {
+	a, b := net.Pipe()
+	return fullConn{a}, fullConn{b}
+}
+
+func TestMux(t *testing.T) {
+	imuModule, imuInput := pipe()
+	gnssModule, gnssInput := pipe()
+	controller, uplink := pipe()
+
+	m := New(uplink).WithSync(true).
+		WithInput(Input{Name: "imu", RW: imuInput, Sync: true}).
+		WithInput(Input{Name: "gnss", RW: gnssInput, Sync: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = m.Run(ctx)
+	}()
+
+	received := make(chan proto.ModuleID, 4)
+
+	// контроллер подтверждает фреймы и отвечает на запросы синхронизации
+	go func() {
+		for {
+			frame, err := proto.Read(controller)
+			if err != nil {
+				return
+			}
+
+			info, err := proto.VerifyFrame(frame)
+			if err != nil {
+				return
+			}
+
+			resp := proto.NewMessage(proto.ControlModuleID, proto.ResponseOK, nil)
+
+			if info.MsgID == proto.SyncRequest {
+				stamp := proto.SyncData(42)
+				resp = proto.NewMessage(proto.ControlModuleID, proto.SyncResponse, &stamp)
+			} else {
+				received <- info.ModuleID
+			}
+
+			raw, err := resp.Marshal()
+			if err != nil {
+				return
+			}
+
+			_, _ = controller.Write(raw)
+		}
+	}()
+
+	send := func(conn fullConn, msg *proto.Message) proto.Message {
+		raw, err := msg.Marshal()
+		require.NoError(t, err)
+
+		_, err = conn.Write(raw)
+		require.NoError(t, err)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+		rawResp, err := proto.Read(conn)
+		require.NoError(t, err)
+
+		var resp proto.Message
+
+		require.NoError(t, resp.Unmarshal(rawResp))
+
+		return resp
+	}
+
+	resp := send(gnssModule, proto.NewMessage(proto.GNSSModuleID, proto.SyncRequest, nil))
+	require.Equal(t, proto.SyncResponse, resp.MsgID)
+
+	stamp, ok := resp.Payload.(*proto.SyncData)
+	require.True(t, ok)
+	require.Equal(t, proto.SyncData(42), *stamp)
+
+	resp = send(imuModule, proto.NewMessage(proto.IMUModuleID, proto.WritingModeA, &proto.IMUData{}))
+	require.Equal(t, proto.ResponseOK, resp.MsgID)
+
+	resp = send(gnssModule, proto.NewMessage(proto.GNSSModuleID, proto.WritingModeA, &proto.GNSSData{}))
+	require.Equal(t, proto.ResponseOK, resp.MsgID)
+
+	require.ElementsMatch(t, []proto.ModuleID{proto.IMUModuleID, proto.GNSSModuleID}, []proto.ModuleID{<-received, <-received})
+
+	require.Eventually(t, func() bool {
+		return m.Stats().FramesSent == 3
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,141 @@
+package mux
+
+import (
+	"time"
+)
+
+const (
+	// DefaultQuantum количество байт, добавляемое входу с единичным весом за один круг обхода
+	DefaultQuantum = 64
+	// DefaultWeight вес входа по умолчанию
+	DefaultWeight = 1
+	// DefaultQueueSize размер очереди входа по умолчанию
+	DefaultQueueSize = 16
+)
+
+// Item фрейм в очереди планировщика
+type Item struct {
+	// Input номер входа, с которого получен фрейм
+	Input int
+	Frame []byte
+	// At время постановки в очередь
+	At time.Time
+}
+
+type queue struct {
+	priority int
+	weight   int
+	limit    int
+	items    []Item
+	deficit  int
+}
+
+// Scheduler планировщик передачи фреймов нескольких входов по одному каналу. Входы с большим
+// приоритетом обслуживаются строго раньше входов с меньшим. Входы с одинаковым приоритетом
+// обслуживаются по алгоритму Deficit Round Robin: за круг обхода вход получает право передать
+// weight*DefaultQuantum байт, поэтому пропускная способность делится пропорционально весам
+// независимо от размера фреймов. Планировщик не безопасен для конкурентного использования.
+type Scheduler struct {
+	queues  []*queue
+	cur     int
+	arrived bool
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{arrived: true}
+}
+
+// AddQueue добавляет очередь и возвращает ее номер. Неположительные weight и limit
+// заменяются значениями по умолчанию.
+func (s *Scheduler) AddQueue(priority, weight, limit int) int {
+	if weight <= 0 {
+		weight = DefaultWeight
+	}
+
+	if limit <= 0 {
+		limit = DefaultQueueSize
+	}
+
+	s.queues = append(s.queues, &queue{
+		priority: priority,
+		weight:   weight,
+		limit:    limit,
+	})
+
+	return len(s.queues) - 1
+}
+
+// Push ставит фрейм в очередь queue. Возвращает false, если очередь заполнена.
+func (s *Scheduler) Push(queue int, item Item) bool {
+	q := s.queues[queue]
+	if len(q.items) >= q.limit {
+		return false
+	}
+
+	q.items = append(q.items, item)
+
+	return true
+}
+
+// Len возвращает количество фреймов во всех очередях.
+func (s *Scheduler) Len() int {
+	n := 0
+	for _, q := range s.queues {
+		n += len(q.items)
+	}
+
+	return n
+}
+
+// Pop возвращает следующий фрейм для передачи. Возвращает false, если все очереди пусты.
+func (s *Scheduler) Pop() (Item, bool) {
+	top, ok := s.topPriority()
+	if !ok {
+		return Item{}, false
+	}
+
+	for {
+		q := s.queues[s.cur]
+
+		switch {
+		case len(q.items) == 0:
+			q.deficit = 0
+		case q.priority == top:
+			if s.arrived {
+				q.deficit += q.weight * DefaultQuantum
+				s.arrived = false
+			}
+
+			if size := len(q.items[0].Frame); size <= q.deficit {
+				item := q.items[0]
+				q.items = q.items[1:]
+				q.deficit -= size
+
+				if len(q.items) == 0 {
+					q.deficit = 0
+				}
+
+				return item, true
+			}
+		}
+
+		s.cur = (s.cur + 1) % len(s.queues)
+		s.arrived = true
+	}
+}
+
+func (s *Scheduler) topPriority() (int, bool) {
+	var (
+		top   int
+		found bool
+	)
+
+	for _, q := range s.queues {
+		if len(q.items) > 0 && (!found || q.priority > top) {
+			top = q.priority
+			found = true
+		}
+	}
+
+	return top, found
+}
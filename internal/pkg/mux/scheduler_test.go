@@ -0,0 +1,67 @@
+package mux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Run("строгий приоритет", func(t *testing.T) {
+		s := NewScheduler()
+		low := s.AddQueue(0, 1, 8)
+		high := s.AddQueue(1, 1, 8)
+
+		require.True(t, s.Push(low, Item{Input: low, Frame: make([]byte, 10)}))
+		require.True(t, s.Push(low, Item{Input: low, Frame: make([]byte, 10)}))
+		require.True(t, s.Push(high, Item{Input: high, Frame: make([]byte, 10)}))
+
+		item, ok := s.Pop()
+		require.True(t, ok)
+		require.Equal(t, high, item.Input)
+
+		for range 2 {
+			item, ok = s.Pop()
+			require.True(t, ok)
+			require.Equal(t, low, item.Input)
+		}
+
+		_, ok = s.Pop()
+		require.False(t, ok)
+	})
+
+	t.Run("доли пропускной способности пропорциональны весам", func(t *testing.T) {
+		s := NewScheduler()
+		light := s.AddQueue(0, 1, 1000)
+		heavy := s.AddQueue(0, 3, 1000)
+		// фреймы разного размера: делится объем данных, а не количество фреймов
+		big := s.AddQueue(0, 1, 1000)
+
+		for range 1000 {
+			s.Push(light, Item{Input: light, Frame: make([]byte, 16)})
+			s.Push(heavy, Item{Input: heavy, Frame: make([]byte, 16)})
+			s.Push(big, Item{Input: big, Frame: make([]byte, 200)})
+		}
+
+		sent := map[int]int{}
+
+		for range 600 {
+			item, ok := s.Pop()
+			require.True(t, ok)
+
+			sent[item.Input] += len(item.Frame)
+		}
+
+		require.InDelta(t, 3, float64(sent[heavy])/float64(sent[light]), 0.1)
+		require.InDelta(t, 1, float64(sent[big])/float64(sent[light]), 0.2)
+	})
+
+	t.Run("заполненная очередь", func(t *testing.T) {
+		s := NewScheduler()
+		q := s.AddQueue(0, 0, 1)
+
+		require.True(t, s.Push(q, Item{Frame: []byte{1}}))
+		require.False(t, s.Push(q, Item{Frame: []byte{2}}))
+		require.Equal(t, 1, s.Len())
+	})
+}
@@ -0,0 +1,333 @@
+package navfilter
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/imu"
+	"math"
+	"time"
+)
+
+// индексы вектора состояния: положение и скорость в локальной системе север-восток с началом
+// в первом решении ГНСС, курс и смещения нуля
+const (
+	iN = iota
+	iE
+	iVN
+	iVE
+	iPsi
+	iBg
+	iBax
+	iBay
+	stateSize
+)
+
+type vector [stateSize]float64
+
+type matrix [stateSize][stateSize]float64
+
+// Filter слабосвязанный расширенный фильтр Калмана. Прогноз ведется по измерениям ИНС,
+// коррекция - по положению, скорости и курсу ГНСС. Время измерений - системное время
+// сообщений в мс; измерение ГНСС, запаздывающее относительно фильтра, применяется к текущему
+// состоянию, если запаздывание не больше секунды. Не безопасен для одновременного
+// использования.
+type Filter struct {
+	noise Noise
+
+	x vector
+	p matrix
+
+	origin        geo.Point
+	positionValid bool
+	headingValid  bool
+
+	// t время состояния
+	t     uint32
+	tSet  bool
+	imu   imu.Sample
+	imuAt uint32
+	imuOK bool
+	fixAt uint32
+}
+
+func NewFilter(noise Noise) *Filter {
+	return &Filter{noise: noise}
+}
+
+// elapsed возвращает время от a до b с учетом переполнения системного времени.
+func elapsed(a, b uint32) time.Duration {
+	return time.Duration(int32(b-a)) * time.Millisecond
+}
+
+// UpdateIMU продвигает состояние к моменту t по предыдущему измерению ИНС и запоминает
+// измерение s.
+func (f *Filter) UpdateIMU(t uint32, s imu.Sample) {
+	f.advance(t)
+
+	f.imu, f.imuAt, f.imuOK = s, t, true
+}
+
+// advance продвигает состояние к моменту t; более ранний момент не меняет состояние.
+func (f *Filter) advance(t uint32) {
+	if !f.tSet {
+		f.t, f.tSet = t, true
+		return
+	}
+
+	dt := elapsed(f.t, t)
+	if dt <= 0 {
+		return
+	}
+
+	if f.positionValid {
+		// без свежих измерений ИНС скорость и курс сохраняются
+		f.predict(f.imu, f.imuOK && elapsed(f.imuAt, t) <= maxIMUAge, dt.Seconds())
+	}
+
+	f.t = t
+}
+
+// predict выполняет прогноз на dt секунд по измерению s; без inertial - с постоянными
+// скоростью и курсом.
+func (f *Filter) predict(s imu.Sample, inertial bool, dt float64) {
+	x := &f.x
+
+	var aN, aE, rate float64
+
+	// до определения курса ускорения нельзя перевести в систему север-восток
+	accel := inertial && f.headingValid
+
+	if accel {
+		ax, ay := s.Ax-x[iBax], s.Ay-x[iBay]
+		sin, cos := math.Sincos(x[iPsi])
+		aN = cos*ax - sin*ay
+		aE = sin*ax + cos*ay
+	}
+
+	F := identity()
+	F[iN][iVN], F[iE][iVE] = dt, dt
+
+	if inertial {
+		rate = s.Gz - x[iBg]
+		F[iPsi][iBg] = -dt
+	}
+
+	if accel {
+		sin, cos := math.Sincos(x[iPsi])
+		dt2 := dt * dt / 2
+
+		F[iN][iPsi], F[iE][iPsi] = -aE*dt2, aN*dt2
+		F[iVN][iPsi], F[iVE][iPsi] = -aE*dt, aN*dt
+		F[iN][iBax], F[iN][iBay] = -cos*dt2, sin*dt2
+		F[iE][iBax], F[iE][iBay] = -sin*dt2, -cos*dt2
+		F[iVN][iBax], F[iVN][iBay] = -cos*dt, sin*dt
+		F[iVE][iBax], F[iVE][iBay] = -sin*dt, -cos*dt
+	}
+
+	x[iN] += x[iVN]*dt + aN*dt*dt/2
+	x[iE] += x[iVE]*dt + aE*dt*dt/2
+	x[iVN] += aN * dt
+	x[iVE] += aE * dt
+	x[iPsi] = wrap(x[iPsi] + rate*dt)
+
+	qa := f.noise.Acc * f.noise.Acc
+	qg := geo.Rad(f.noise.Gyro) * geo.Rad(f.noise.Gyro)
+
+	var q vector
+	q[iN], q[iE] = qa*dt*dt*dt/3, qa*dt*dt*dt/3
+	q[iVN], q[iVE] = qa*dt, qa*dt
+	q[iPsi] = qg * dt
+	q[iBg] = geo.Rad(f.noise.GyroBias) * geo.Rad(f.noise.GyroBias) * dt
+	q[iBax] = f.noise.AccBias * f.noise.AccBias * dt
+	q[iBay] = q[iBax]
+
+	f.p = F.mul(f.p).mul(F.transpose())
+
+	for i := range q {
+		f.p[i][i] += q[i]
+	}
+}
+
+// measure продвигает состояние к моменту измерения t; false - измерение устарело.
+func (f *Filter) measure(t uint32) bool {
+	if f.tSet && elapsed(f.t, t) < -maxLag {
+		return false
+	}
+
+	f.advance(t)
+
+	return true
+}
+
+// UpdatePosition корректирует состояние по положению p в момент t с СКО std метров. Первое
+// положение задает начало локальной системы координат.
+func (f *Filter) UpdatePosition(t uint32, p geo.Point, std float64) {
+	if !f.measure(t) {
+		return
+	}
+
+	if !f.positionValid {
+		f.origin = p
+		f.positionValid = true
+		f.fixAt = t
+
+		f.p[iN][iN], f.p[iE][iE] = std*std, std*std
+		f.p[iVN][iVN], f.p[iVE][iVE] = 1, 1
+		f.p[iPsi][iPsi] = initialHeadingStd * initialHeadingStd
+		f.p[iBg][iBg] = initialGyroBiasStd * initialGyroBiasStd
+		f.p[iBax][iBax] = initialAccBiasStd * initialAccBiasStd
+		f.p[iBay][iBay] = f.p[iBax][iBax]
+
+		return
+	}
+
+	north, east := geo.NorthEast(f.origin, p)
+
+	f.update(iN, north-f.x[iN], std*std)
+	f.update(iE, east-f.x[iE], std*std)
+
+	f.fixAt = t
+}
+
+// UpdateVelocity корректирует состояние по составляющим скорости velN, velE в м/с в момент t
+// с СКО std м/с.
+func (f *Filter) UpdateVelocity(t uint32, velN, velE, std float64) {
+	if !f.positionValid || !f.measure(t) {
+		return
+	}
+
+	f.update(iVN, velN-f.x[iVN], std*std)
+	f.update(iVE, velE-f.x[iVE], std*std)
+}
+
+// UpdateCourse корректирует курс по курсу ГНСС course в градусах в момент t. Курс относительно
+// грунта совпадает с курсом аппарата только при движении без сноса, поэтому вызывающий
+// отбрасывает курс на малой скорости. Первый курс задает курс фильтра.
+func (f *Filter) UpdateCourse(t uint32, course float64) {
+	f.UpdateHeading(t, course, f.noise.Course)
+}
+
+// UpdateHeading корректирует курс по измерению heading в градусах в момент t с СКО std
+// градусов. Первое измерение задает курс фильтра.
+func (f *Filter) UpdateHeading(t uint32, heading, std float64) {
+	if !f.positionValid || !f.measure(t) {
+		return
+	}
+
+	r := geo.Rad(std) * geo.Rad(std)
+
+	if !f.headingValid {
+		f.x[iPsi] = wrap(geo.Rad(heading))
+
+		for i := range f.p {
+			f.p[i][iPsi], f.p[iPsi][i] = 0, 0
+		}
+
+		f.p[iPsi][iPsi] = r
+		f.headingValid = true
+
+		return
+	}
+
+	f.update(iPsi, wrap(geo.Rad(heading)-f.x[iPsi]), r)
+}
+
+// update выполняет коррекцию по измерению компоненты i вектора состояния с невязкой y и
+// дисперсией r.
+func (f *Filter) update(i int, y, r float64) {
+	s := f.p[i][i] + r
+
+	var k vector
+	for j := range k {
+		k[j] = f.p[j][i] / s
+	}
+
+	for j := range f.x {
+		f.x[j] += k[j] * y
+	}
+
+	f.x[iPsi] = wrap(f.x[iPsi])
+
+	row := f.p[i]
+
+	for j := range f.p {
+		for l := range f.p[j] {
+			f.p[j][l] -= k[j] * row[l]
+		}
+	}
+
+	// симметрия ковариации теряется из-за ошибок округления
+	for j := range f.p {
+		for l := j + 1; l < stateSize; l++ {
+			v := (f.p[j][l] + f.p[l][j]) / 2
+			f.p[j][l], f.p[l][j] = v, v
+		}
+	}
+}
+
+// State возвращает решение на момент последнего измерения.
+func (f *Filter) State() State {
+	s := State{
+		Time:          f.t,
+		PositionValid: f.positionValid,
+		HeadingValid:  f.headingValid,
+	}
+
+	if !f.positionValid {
+		return s
+	}
+
+	s.Position = geo.Offset(f.origin, f.x[iN], f.x[iE])
+	s.VelN, s.VelE = f.x[iVN], f.x[iVE]
+	s.Heading = geo.NormalizeBearing(geo.Deg(f.x[iPsi]))
+	s.GyroBias = geo.Deg(f.x[iBg])
+	s.AccBiasX, s.AccBiasY = f.x[iBax], f.x[iBay]
+	s.PositionStd = math.Sqrt(f.p[iN][iN] + f.p[iE][iE])
+	s.HeadingStd = geo.Deg(math.Sqrt(f.p[iPsi][iPsi]))
+	s.FixAge = elapsed(f.fixAt, f.t)
+
+	return s
+}
+
+func identity() matrix {
+	var m matrix
+	for i := range m {
+		m[i][i] = 1
+	}
+
+	return m
+}
+
+func (m matrix) mul(o matrix) matrix {
+	var r matrix
+
+	for i := range r {
+		for k := range m[i] {
+			if m[i][k] == 0 {
+				continue
+			}
+
+			for j := range r[i] {
+				r[i][j] += m[i][k] * o[k][j]
+			}
+		}
+	}
+
+	return r
+}
+
+func (m matrix) transpose() matrix {
+	var r matrix
+
+	for i := range m {
+		for j := range m[i] {
+			r[j][i] = m[i][j]
+		}
+	}
+
+	return r
+}
+
+// wrap приводит угол в радианах к диапазону (-π, π].
+func wrap(a float64) float64 {
+	return geo.Rad(geo.NormalizeAngle(geo.Deg(a)))
+}
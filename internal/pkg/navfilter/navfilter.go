@@ -0,0 +1,90 @@
+// Package navfilter предоставляет слабосвязанный расширенный фильтр Калмана, объединяющий
+// измерения ИНС и ГНСС в навигационное решение в плоскости горизонта: положение, скорость,
+// курс и смещения нуля гироскопа и акселерометров
+package navfilter
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultRate частота публикации решения по умолчанию, Гц
+	DefaultRate = 10
+	// DefaultTimeout время без решения ГНСС, после которого положение считается счисленным
+	DefaultTimeout = 3 * time.Second
+	// DefaultMinCourseSpeed скорость в м/с, начиная с которой курс ГНСС используется как курс
+	// аппарата
+	DefaultMinCourseSpeed = 1.0
+)
+
+// Шумы по умолчанию (см. Noise)
+const (
+	DefaultGyroNoise     = 0.5
+	DefaultAccNoise      = 0.5
+	DefaultGyroBiasNoise = 0.01
+	DefaultAccBiasNoise  = 0.01
+	DefaultCourseNoise   = 5.0
+)
+
+const (
+	// maxIMUAge возраст последнего измерения ИНС, после которого прогноз ведется без него
+	maxIMUAge = 500 * time.Millisecond
+	// maxLag наибольшее запаздывание измерения ГНСС относительно времени фильтра
+	maxLag = time.Second
+	// initialGyroBiasStd, initialAccBiasStd начальные СКО смещений нуля
+	initialGyroBiasStd = 1 * math.Pi / 180
+	initialAccBiasStd  = 0.3
+	// initialHeadingStd СКО курса до первого измерения курса
+	initialHeadingStd = math.Pi
+)
+
+// Noise шумы модели фильтра. Шумы гироскопа и акселерометров и случайного блуждания их
+// смещений нуля - спектральные плотности: град/с/√Гц, м/с^2/√Гц, град/с/√с и м/с^2/√с;
+// Course - СКО курса ГНСС в градусах.
+type Noise struct {
+	Gyro     float64
+	Acc      float64
+	GyroBias float64
+	AccBias  float64
+	Course   float64
+}
+
+// DefaultNoise возвращает шумы по умолчанию.
+func DefaultNoise() Noise {
+	return Noise{
+		Gyro:     DefaultGyroNoise,
+		Acc:      DefaultAccNoise,
+		GyroBias: DefaultGyroBiasNoise,
+		AccBias:  DefaultAccBiasNoise,
+		Course:   DefaultCourseNoise,
+	}
+}
+
+// State навигационное решение
+type State struct {
+	// Time системное время решения в мс (см. proto.Message.SystemTime)
+	Time     uint32
+	Position geo.Point
+	// VelN, VelE составляющие скорости в м/с
+	VelN, VelE float64
+	// Heading курс в градусах, [0, 360)
+	Heading float64
+	// GyroBias смещение нуля гироскопа в град/с, AccBiasX и AccBiasY - акселерометров в м/с^2
+	GyroBias           float64
+	AccBiasX, AccBiasY float64
+	// PositionStd СКО положения в метрах, HeadingStd СКО курса в градусах
+	PositionStd float64
+	HeadingStd  float64
+	// PositionValid положение определено, HeadingValid курс определен
+	PositionValid bool
+	HeadingValid  bool
+	// FixAge время от последнего решения ГНСС до Time
+	FixAge time.Duration
+}
+
+// Speed возвращает скорость относительно грунта в м/с.
+func (s State) Speed() float64 {
+	return math.Hypot(s.VelN, s.VelE)
+}
@@ -0,0 +1,117 @@
+package navfilter
+
+import (
+	"asvsoft/internal/pkg/geo"
+	"asvsoft/internal/pkg/imu"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// trajectory синтетическое движение аппарата: разгон, прямые участки и циркуляции
+type trajectory struct {
+	position geo.Point
+	// heading курс в радианах, speed скорость в м/с, yawRate угловая скорость в рад/с
+	heading, speed, yawRate float64
+	accel                   float64
+}
+
+// step продвигает движение на dt секунд в момент t секунд от начала.
+func (tr *trajectory) step(t, dt float64) {
+	switch {
+	case t < 10:
+		tr.accel, tr.yawRate = 0.2, 0
+	case int(t/30)%2 == 0:
+		tr.accel, tr.yawRate = 0, geo.Rad(3)
+	default:
+		tr.accel, tr.yawRate = 0, geo.Rad(-2)
+	}
+
+	tr.speed += tr.accel * dt
+	tr.heading = wrap(tr.heading + tr.yawRate*dt)
+
+	sin, cos := math.Sincos(tr.heading)
+	tr.position = geo.Offset(tr.position, tr.speed*cos*dt, tr.speed*sin*dt)
+}
+
+func TestFilter(t *testing.T) {
+	const (
+		imuPeriod  = 10 * time.Millisecond
+		gyroBias   = 0.4 // град/с
+		accBiasX   = 0.15
+		accBiasY   = -0.1
+		positionSD = 1.5
+		velocitySD = 0.1
+	)
+
+	rnd := rand.New(rand.NewSource(1))
+	tr := &trajectory{position: geo.Point{Lat: 59.93, Lon: 30.31}, heading: geo.Rad(40)}
+	f := NewFilter(DefaultNoise())
+
+	var now uint32
+
+	// run моделирует движение длительностью d; без gnss решения ГНСС не поступают.
+	run := func(d time.Duration, gnss bool) {
+		for end := now + uint32(d.Milliseconds()); now < end; now += uint32(imuPeriod.Milliseconds()) {
+			dt := imuPeriod.Seconds()
+			tr.step(float64(now)/1000, dt)
+
+			f.UpdateIMU(now, imu.Sample{
+				Gz: tr.yawRate + geo.Rad(gyroBias) + rnd.NormFloat64()*geo.Rad(0.1),
+				Ax: tr.accel + accBiasX + rnd.NormFloat64()*0.05,
+				Ay: tr.speed*tr.yawRate + accBiasY + rnd.NormFloat64()*0.05,
+			})
+
+			if !gnss || now%1000 != 0 {
+				continue
+			}
+
+			sin, cos := math.Sincos(tr.heading)
+			p := geo.Offset(tr.position, rnd.NormFloat64()*positionSD, rnd.NormFloat64()*positionSD)
+
+			f.UpdatePosition(now, p, positionSD)
+			f.UpdateVelocity(now, tr.speed*cos+rnd.NormFloat64()*velocitySD, tr.speed*sin+rnd.NormFloat64()*velocitySD, velocitySD)
+
+			if tr.speed >= DefaultMinCourseSpeed {
+				f.UpdateCourse(now, geo.NormalizeBearing(geo.Deg(tr.heading)+rnd.NormFloat64()*2))
+			}
+		}
+	}
+
+	run(300*time.Second, true)
+
+	t.Run("решение сходится к движению", func(t *testing.T) {
+		s := f.State()
+		require.True(t, s.PositionValid)
+		require.True(t, s.HeadingValid)
+		require.Equal(t, now-uint32(imuPeriod.Milliseconds()), s.Time)
+		require.Less(t, geo.Distance(s.Position, tr.position), 2.0)
+		require.InDelta(t, tr.speed, s.Speed(), 0.2)
+		require.InDelta(t, 0, geo.NormalizeAngle(s.Heading-geo.Deg(tr.heading)), 2)
+		require.InDelta(t, gyroBias, s.GyroBias, 0.05)
+		require.InDelta(t, accBiasX, s.AccBiasX, 0.05)
+		require.InDelta(t, accBiasY, s.AccBiasY, 0.05)
+	})
+
+	t.Run("счисление при отсутствии ГНСС", func(t *testing.T) {
+		before := f.State()
+
+		run(20*time.Second, false)
+
+		s := f.State()
+		require.GreaterOrEqual(t, s.FixAge, 20*time.Second)
+		require.Greater(t, s.PositionStd, before.PositionStd)
+		require.Less(t, geo.Distance(s.Position, tr.position), 10.0)
+		require.InDelta(t, 0, geo.NormalizeAngle(s.Heading-geo.Deg(tr.heading)), 3)
+	})
+
+	t.Run("запаздывающее измерение", func(t *testing.T) {
+		s := f.State()
+
+		f.UpdatePosition(now-5000, geo.Offset(tr.position, 100, 0), positionSD)
+		require.Equal(t, s, f.State())
+	})
+}
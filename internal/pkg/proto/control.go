@@ -0,0 +1,99 @@
+package proto
+
+import (
+	"asvsoft/internal/pkg/encoder"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	controlDataPayloadSizeModeA = 5
+	controlDataPayloadSizeModeB = 7
+)
+
+// Источники уставок исполнительных механизмов (ControlData.Source)
+const (
+	// ControlSourceFailsafe уставка аварийного режима
+	ControlSourceFailsafe uint8 = 0x01 + iota
+	// ControlSourceGuidance уставка регуляторов курса и скорости
+	ControlSourceGuidance
+	// ControlSourceGeofence уставка остановки при нарушении зоны
+	ControlSourceGeofence
+)
+
+// События контроллера (ControlData.Event)
+const (
+	// EventModuleSilent модуль перестал присылать сообщения, Value - время без сообщений в мс
+	EventModuleSilent uint8 = 0x01 + iota
+	// EventMissionStarted миссия запущена или продолжена, Value - номер целевой точки
+	EventMissionStarted
+	// EventWaypointReached достигнута точка маршрута, Value - номер точки
+	EventWaypointReached
+	// EventMissionCompleted пройдена последняя точка маршрута, Value - номер точки
+	EventMissionCompleted
+	// EventGeofenceBreach нарушена зона плавания, Value - номер зоны
+	EventGeofenceBreach
+	// EventFailsafe сработал признак аварии, Value - номер признака
+	EventFailsafe
+)
+
+// ControlData данные модуля управления. Режим WritingModeA - уставки исполнительных механизмов,
+// WritingModeB - события контроллера.
+type ControlData struct {
+	// Thrust тяга в 0.1% от максимальной, отрицательная - задний ход
+	Thrust int16
+	// Rudder угол пера руля в 0.01 град, положительный - вправо
+	Rudder int16
+	Source uint8
+
+	Event uint8
+	// Subject модуль, к которому относится событие
+	Subject uint8
+	// Active 1 - событие возникло, 0 - завершилось
+	Active uint8
+	Value  int32
+}
+
+func (cd ControlData) String() string {
+	type _ControlData ControlData
+	return fmt.Sprintf("%+v", _ControlData(cd))
+}
+
+func (cd *ControlData) Pack(msgID MessageID) ([]byte, error) {
+	var buf *bytes.Buffer
+
+	switch msgID {
+	case WritingModeA:
+		buf = bytes.NewBuffer(make([]byte, 0, controlDataPayloadSizeModeA))
+
+		err := encoder.NewEncoder(buf).Encode(cd.Thrust, cd.Rudder, cd.Source)
+		if err != nil {
+			return nil, err
+		}
+	case WritingModeB:
+		buf = bytes.NewBuffer(make([]byte, 0, controlDataPayloadSizeModeB))
+
+		err := encoder.NewEncoder(buf).Encode(cd.Event, cd.Subject, cd.Active, cd.Value)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		panic(fmt.Sprintf("packControlData is not implemented for this message ID: %x", msgID))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (cd *ControlData) Unpack(in []byte, msgID MessageID) error {
+	dec := encoder.NewDecoder(io.NopCloser(bytes.NewReader(in)))
+
+	switch msgID {
+	case WritingModeA:
+		return dec.Decode(&cd.Thrust, &cd.Rudder, &cd.Source)
+	case WritingModeB:
+		return dec.Decode(&cd.Event, &cd.Subject, &cd.Active, &cd.Value)
+	default:
+		panic(fmt.Sprintf("unpackControlData is not implemented for this message ID: %x", msgID))
+	}
+}
@@ -0,0 +1,46 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlDataSuccess(t *testing.T) {
+	tests := []struct {
+		name  string
+		msgID MessageID
+		data  *ControlData
+		size  int
+	}{
+		{
+			name:  "уставки исполнительных механизмов",
+			msgID: WritingModeA,
+			data:  &ControlData{Thrust: -250, Rudder: 1500, Source: ControlSourceFailsafe},
+			size:  controlDataPayloadSizeModeA,
+		},
+		{
+			name:  "событие контроллера",
+			msgID: WritingModeB,
+			data:  &ControlData{Event: EventModuleSilent, Subject: uint8(GNSSModuleID), Active: 1, Value: 3000},
+			size:  controlDataPayloadSizeModeB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentMsg := NewMessage(ControlModuleID, tt.msgID, tt.data)
+
+			msgBytes, err := sentMsg.Marshal()
+			require.NoError(t, err)
+			require.Len(t, msgBytes, FrameSize(tt.size))
+
+			receivedMsg := new(Message)
+
+			err = receivedMsg.Unmarshal(msgBytes)
+			require.NoError(t, err)
+
+			require.Equal(t, sentMsg, receivedMsg)
+		})
+	}
+}
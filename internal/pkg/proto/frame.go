@@ -0,0 +1,125 @@
+package proto
+
+import (
+	"asvsoft/pkg/crc8"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// FrameInfo служебные поля фрейма, доступные без распаковки полезной нагрузки
+type FrameInfo struct {
+	ModuleID    ModuleID
+	MsgID       MessageID
+	PayloadSize uint8
+}
+
+// VerifyFrame проверяет размер и контрольную сумму фрейма, полученного Read, и возвращает
+// его служебные поля. В отличие от Message.Unmarshal не распаковывает полезную нагрузку,
+// поэтому применим к фреймам любых модулей.
+func VerifyFrame(data []byte) (FrameInfo, error) {
+	if len(data) < serviceBytesSize {
+		return FrameInfo{}, fmt.Errorf("frame is too short: %d bytes", len(data))
+	}
+
+	info := FrameInfo{
+		ModuleID:    ModuleID(data[headerSize+sytemByteSize]),
+		MsgID:       MessageID(data[headerSize+sytemByteSize+moduleIDSize]),
+		PayloadSize: data[payloadFirstByte-payloadBytesSize],
+	}
+
+	if len(data) != FrameSize(int(info.PayloadSize)) {
+		return info, fmt.Errorf("frame size %d mismatches payload size %d", len(data), info.PayloadSize)
+	}
+
+	checkSum := crc8.ChecksumSMBus(data[headerSize : len(data)-checkSumSize])
+	if data[len(data)-1] != checkSum {
+		return info, fmt.Errorf(
+			"%w: recieved cs: %#X, calculated cs: %#X", ErrChecksumMismatch, data[len(data)-1], checkSum,
+		)
+	}
+
+	return info, nil
+}
+
+var moduleNames = map[ModuleID]string{
+	ControlModuleID:        "control",
+	RegistratorModuleID:    "registrar",
+	CheckModuleID:          "check",
+	RadioTelemetryModuleID: "radio",
+	CommunicationModule:    "communication",
+	IMUModuleID:            "imu",
+	GNSSModuleID:           "gnss",
+	NavigationModuleID:     "navigation",
+	DepthMeterModuleID:     "depthmeter",
+	LidarModuleID:          "lidar",
+	CameraModuleID:         "camera",
+	PowerModuleID:          "power",
+}
+
+// ModuleName возвращает имя модуля, используемое в конфигурации.
+func ModuleName(id ModuleID) string {
+	name, ok := moduleNames[id]
+	if !ok {
+		return fmt.Sprintf("%#X", uint8(id))
+	}
+
+	return name
+}
+
+// ModuleNames возвращает имена всех известных модулей в алфавитном порядке.
+func ModuleNames() []string {
+	names := make([]string, 0, len(moduleNames))
+	for _, name := range moduleNames {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ModuleIDByName возвращает идентификатор модуля по имени из конфигурации.
+func ModuleIDByName(name string) (ModuleID, bool) {
+	for id, n := range moduleNames {
+		if n == name {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// FindFrame ищет в data первый фрейм с корректным размером и контрольной суммой. Возвращает
+// смещение и размер фрейма.
+func FindFrame(data []byte) (start, size int, ok bool) {
+	for start = 0; start+serviceBytesSize <= len(data); start++ {
+		if data[start] != header[0] || data[start+1] != header[1] {
+			continue
+		}
+
+		size = FrameSize(int(data[start+payloadFirstByte-payloadBytesSize]))
+		if start+size > len(data) {
+			continue
+		}
+
+		if _, err := VerifyFrame(data[start : start+size]); err == nil {
+			return start, size, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// SetSystemTime заменяет системное время корректного фрейма и пересчитывает контрольную сумму.
+func SetSystemTime(data []byte, systemTime uint32) error {
+	_, err := VerifyFrame(data)
+	if err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(data[headerSize+sytemByteSize+moduleIDSize+msgIDSize:], systemTime)
+	data[len(data)-1] = crc8.ChecksumSMBus(data[headerSize : len(data)-checkSumSize])
+
+	return nil
+}
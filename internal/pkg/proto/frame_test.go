@@ -0,0 +1,85 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyFrame(t *testing.T) {
+	frame, err := NewMessage(GNSSModuleID, WritingModeA, &GNSSData{}).Marshal()
+	require.NoError(t, err)
+
+	t.Run("корректный фрейм", func(t *testing.T) {
+		info, err := VerifyFrame(frame)
+		require.NoError(t, err)
+		require.Equal(t, GNSSModuleID, info.ModuleID)
+		require.Equal(t, WritingModeA, info.MsgID)
+		require.Equal(t, FrameSize(int(info.PayloadSize)), len(frame))
+	})
+
+	t.Run("фрейм неизвестного модуля", func(t *testing.T) {
+		unknown, err := NewMessage(RadioTelemetryModuleID, ResponseOK, nil).Marshal()
+		require.NoError(t, err)
+
+		info, err := VerifyFrame(unknown)
+		require.NoError(t, err)
+		require.Equal(t, RadioTelemetryModuleID, info.ModuleID)
+	})
+
+	t.Run("ошибка контрольной суммы", func(t *testing.T) {
+		corrupted := append([]byte(nil), frame...)
+		corrupted[len(corrupted)-2]++
+
+		_, err := VerifyFrame(corrupted)
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("обрезанный фрейм", func(t *testing.T) {
+		_, err := VerifyFrame(frame[:len(frame)-1])
+		require.Error(t, err)
+
+		_, err = VerifyFrame(frame[:3])
+		require.Error(t, err)
+	})
+}
+
+func TestFindFrame(t *testing.T) {
+	frame, err := NewMessage(CheckModuleID, WritingModeA, &CheckData{Value: 7}).Marshal()
+	require.NoError(t, err)
+
+	// лишний байт заголовка перед фреймом
+	data := append([]byte{0x01, 0xFA}, frame...)
+
+	start, size, ok := FindFrame(data)
+	require.True(t, ok)
+	require.Equal(t, 2, start)
+	require.Equal(t, len(frame), size)
+
+	_, _, ok = FindFrame(data[:len(data)-1])
+	require.False(t, ok)
+}
+
+func TestSetSystemTime(t *testing.T) {
+	frame, err := NewMessage(CheckModuleID, WritingModeA, &CheckData{Value: 7}).Marshal()
+	require.NoError(t, err)
+
+	require.NoError(t, SetSystemTime(frame, 123456))
+
+	var msg Message
+
+	require.NoError(t, msg.Unmarshal(frame))
+	require.Equal(t, uint32(123456), msg.SystemTime)
+	require.Equal(t, &CheckData{Value: 7}, msg.Payload)
+}
+
+func TestModuleNames(t *testing.T) {
+	id, ok := ModuleIDByName("depthmeter")
+	require.True(t, ok)
+	require.Equal(t, DepthMeterModuleID, id)
+	require.Equal(t, "depthmeter", ModuleName(id))
+
+	_, ok = ModuleIDByName("unknown")
+	require.False(t, ok)
+	require.Equal(t, "0X42", ModuleName(0x42))
+}
@@ -0,0 +1,106 @@
+package proto
+
+import (
+	"asvsoft/internal/pkg/encoder"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	navigationDataPayloadSizeModeA = 25
+	navigationDataPayloadSizeModeB = 7
+)
+
+// Признаки NavigationData.Status
+const (
+	// NavigationPositionValid положение и скорость определены
+	NavigationPositionValid uint8 = 1 << iota
+	// NavigationHeadingValid курс определен; в режиме WritingModeB - по магнитометру
+	NavigationHeadingValid
+	// NavigationDeadReckoning решения ГНСС нет, положение счислено по ИНС
+	NavigationDeadReckoning
+)
+
+// NavigationData навигационное решение фильтра ИНС и ГНСС (WritingModeA) или ориентация
+// (WritingModeB: Roll, Pitch, Heading и Status)
+type NavigationData struct {
+	// Lat, Lon широта и долгота в 1e-7 градуса
+	Lat, Lon int32
+	// VelN, VelE северная и восточная составляющие скорости в см/с
+	VelN, VelE int16
+	// Heading курс в 0.01 градуса, [0, 36000)
+	Heading uint16
+	// Roll крен (положительный - на правый борт), Pitch дифферент (положительный - на корму)
+	// в 0.01 градуса
+	Roll, Pitch int16
+	// GyroBias смещение нуля гироскопа по вертикальной оси в 0.001 град/с
+	GyroBias int16
+	// AccBiasX, AccBiasY смещения нуля акселерометров по продольной и поперечной осям в мм/с^2
+	AccBiasX, AccBiasY int16
+	// PositionStd СКО положения в см, HeadingStd СКО курса в 0.01 градуса
+	PositionStd uint16
+	HeadingStd  uint16
+	// Status признаки решения: NavigationPositionValid, NavigationHeadingValid,
+	// NavigationDeadReckoning
+	Status uint8
+}
+
+func (nd NavigationData) String() string {
+	type _NavigationData NavigationData
+	return fmt.Sprintf("%+v", _NavigationData(nd))
+}
+
+func (nd *NavigationData) Pack(msgID MessageID) ([]byte, error) {
+	var buf *bytes.Buffer
+
+	switch msgID {
+	case WritingModeA:
+		buf = bytes.NewBuffer(make([]byte, 0, navigationDataPayloadSizeModeA))
+
+		err := encoder.NewEncoder(buf).Encode(
+			nd.Lat, nd.Lon,
+			nd.VelN, nd.VelE,
+			nd.Heading,
+			nd.GyroBias,
+			nd.AccBiasX, nd.AccBiasY,
+			nd.PositionStd, nd.HeadingStd,
+			nd.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+	case WritingModeB:
+		buf = bytes.NewBuffer(make([]byte, 0, navigationDataPayloadSizeModeB))
+
+		err := encoder.NewEncoder(buf).Encode(nd.Roll, nd.Pitch, nd.Heading, nd.Status)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		panic(fmt.Sprintf("packNavigationData is not implemented for this message ID: %x", msgID))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (nd *NavigationData) Unpack(in []byte, msgID MessageID) error {
+	dec := encoder.NewDecoder(io.NopCloser(bytes.NewReader(in)))
+
+	switch msgID {
+	case WritingModeA:
+		return dec.Decode(
+			&nd.Lat, &nd.Lon,
+			&nd.VelN, &nd.VelE,
+			&nd.Heading,
+			&nd.GyroBias,
+			&nd.AccBiasX, &nd.AccBiasY,
+			&nd.PositionStd, &nd.HeadingStd,
+			&nd.Status,
+		)
+	case WritingModeB:
+		return dec.Decode(&nd.Roll, &nd.Pitch, &nd.Heading, &nd.Status)
+	default:
+		panic(fmt.Sprintf("unpackNavigationData is not implemented for this message ID: %x", msgID))
+	}
+}
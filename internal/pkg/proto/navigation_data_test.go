@@ -0,0 +1,56 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNavigationDataSuccess(t *testing.T) {
+	t.Run("успешная упаковка и распаковка данных", func(t *testing.T) {
+		sentMsg := NewMessage(NavigationModuleID, WritingModeA, &NavigationData{
+			Lat:         599300000,
+			Lon:         303100000,
+			VelN:        120,
+			VelE:        -35,
+			Heading:     34512,
+			GyroBias:    -250,
+			AccBiasX:    40,
+			AccBiasY:    -12,
+			PositionStd: 180,
+			HeadingStd:  250,
+			Status:      NavigationPositionValid | NavigationHeadingValid,
+		})
+
+		msgBytes, err := sentMsg.Marshal()
+		require.NoError(t, err)
+		require.Len(t, msgBytes, FrameSize(navigationDataPayloadSizeModeA))
+
+		receivedMsg := new(Message)
+
+		err = receivedMsg.Unmarshal(msgBytes)
+		require.NoError(t, err)
+
+		require.Equal(t, sentMsg, receivedMsg)
+	})
+
+	t.Run("успешная упаковка и распаковка ориентации", func(t *testing.T) {
+		sentMsg := NewMessage(NavigationModuleID, WritingModeB, &NavigationData{
+			Roll:    -512,
+			Pitch:   130,
+			Heading: 27015,
+			Status:  NavigationHeadingValid,
+		})
+
+		msgBytes, err := sentMsg.Marshal()
+		require.NoError(t, err)
+		require.Len(t, msgBytes, FrameSize(navigationDataPayloadSizeModeB))
+
+		receivedMsg := new(Message)
+
+		err = receivedMsg.Unmarshal(msgBytes)
+		require.NoError(t, err)
+
+		require.Equal(t, sentMsg, receivedMsg)
+	})
+}
@@ -0,0 +1,59 @@
+package proto
+
+import (
+	"asvsoft/internal/pkg/encoder"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	powerDataPayloadSizeModeA = 5
+)
+
+// PowerData данные модуля питания (WritingModeA)
+type PowerData struct {
+	// Voltage напряжение батареи в мВ
+	Voltage uint16
+	// Current ток потребления в 10 мА, отрицательный - заряд
+	Current int16
+	// Charge остаток заряда в процентах, 0xFF - неизвестен
+	Charge uint8
+}
+
+// PowerChargeUnknown значение PowerData.Charge, если остаток заряда неизвестен
+const PowerChargeUnknown uint8 = 0xFF
+
+func (pd PowerData) String() string {
+	type _PowerData PowerData
+	return fmt.Sprintf("%+v", _PowerData(pd))
+}
+
+func (pd *PowerData) Pack(msgID MessageID) ([]byte, error) {
+	var buf *bytes.Buffer
+
+	switch msgID {
+	case WritingModeA:
+		buf = bytes.NewBuffer(make([]byte, 0, powerDataPayloadSizeModeA))
+
+		err := encoder.NewEncoder(buf).Encode(pd.Voltage, pd.Current, pd.Charge)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		panic(fmt.Sprintf("packPowerData is not implemented for this message ID: %x", msgID))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (pd *PowerData) Unpack(in []byte, msgID MessageID) error {
+	dec := encoder.NewDecoder(io.NopCloser(bytes.NewReader(in)))
+
+	switch msgID {
+	case WritingModeA:
+		return dec.Decode(&pd.Voltage, &pd.Current, &pd.Charge)
+	default:
+		panic(fmt.Sprintf("unpackPowerData is not implemented for this message ID: %x", msgID))
+	}
+}
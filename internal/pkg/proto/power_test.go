@@ -0,0 +1,24 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowerDataSuccess(t *testing.T) {
+	t.Run("успешная упаковка и распаковка данных", func(t *testing.T) {
+		sentMsg := NewMessage(PowerModuleID, WritingModeA, &PowerData{Voltage: 12400, Current: -150, Charge: 87})
+
+		msgBytes, err := sentMsg.Marshal()
+		require.NoError(t, err)
+		require.Len(t, msgBytes, FrameSize(powerDataPayloadSizeModeA))
+
+		receivedMsg := new(Message)
+
+		err = receivedMsg.Unmarshal(msgBytes)
+		require.NoError(t, err)
+
+		require.Equal(t, sentMsg, receivedMsg)
+	})
+}
@@ -6,8 +6,10 @@ import (
 	"asvsoft/internal/pkg/encoder"
 	"asvsoft/pkg/crc8"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"time"
 )
 
@@ -28,6 +30,7 @@ const (
 	DepthMeterModuleID
 	LidarModuleID
 	CameraModuleID
+	PowerModuleID
 )
 
 type MessageID uint8
@@ -48,6 +51,12 @@ const (
 	ResponseFail
 )
 
+// StatsReport сообщение со статистикой канала связи модуля (см. StatsData)
+const StatsReport MessageID = 0xF0
+
+// ErrChecksumMismatch контрольная сумма фрейма не совпадает с вычисленной
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
 const (
 	headerSize       = 2
 	sytemByteSize    = 1
@@ -68,6 +77,11 @@ const serviceBytesSize = headerSize +
 
 const payloadFirstByte = serviceBytesSize - checkSumSize
 
+// FrameSize возвращает размер фрейма с полезной нагрузкой размером payloadSize байт.
+func FrameSize(payloadSize int) int {
+	return serviceBytesSize + payloadSize
+}
+
 const (
 	defaultBuffSize    = 512
 	defaultReadRetries = 1024
@@ -232,6 +246,17 @@ type Packer interface {
 
 // Marshal ...
 func (m *Message) Marshal() ([]byte, error) {
+	m.SystemTime = systemTime()
+	return m.marshal()
+}
+
+// MarshalKeepTime аналогично Marshal, но сохраняет системное время сообщения. Используется
+// при пересылке полученных сообщений.
+func (m *Message) MarshalKeepTime() ([]byte, error) {
+	return m.marshal()
+}
+
+func (m *Message) marshal() ([]byte, error) {
 	var (
 		err        error
 		rawPayload []byte
@@ -247,8 +272,11 @@ func (m *Message) Marshal() ([]byte, error) {
 		return nil, err
 	}
 
+	if len(rawPayload) > math.MaxUint8 {
+		return nil, fmt.Errorf("payload is too large: %d bytes", len(rawPayload))
+	}
+
 	m.PayloadSize = uint8(len(rawPayload))
-	m.SystemTime = systemTime()
 
 	enc := encoder.NewEncoder(bytes.NewBuffer(make([]byte, 0, serviceBytesSize+int(m.PayloadSize))))
 
@@ -307,8 +335,8 @@ func (m *Message) Unmarshal(data []byte) error {
 
 	if m.CheckSum != checkSum {
 		return fmt.Errorf(
-			"check sum missmatch: recieved cs: %#X, calculated cs: %#X, message: %s",
-			m.CheckSum, checkSum, m,
+			"%w: recieved cs: %#X, calculated cs: %#X, message: %s",
+			ErrChecksumMismatch, m.CheckSum, checkSum, m,
 		)
 	}
 
@@ -322,24 +350,49 @@ func (m *Message) Unmarshal(data []byte) error {
 }
 
 func (m *Message) unpack(rawPayload []byte) error {
-	switch m.ModuleID {
+	switch m.MsgID {
+	case SyncResponse:
+		m.Payload = new(SyncData)
+		return m.Payload.Unpack(rawPayload, m.MsgID)
+	case StatsReport:
+		m.Payload = new(StatsData)
+		return m.Payload.Unpack(rawPayload, m.MsgID)
+	}
+
+	payload, ok := NewPayload(m.ModuleID)
+	if !ok {
+		panic(fmt.Sprintf("Unpack is not implemented for this addr (%x)", m.ModuleID))
+	}
+
+	m.Payload = payload
+
+	return m.Payload.Unpack(rawPayload, m.MsgID)
+}
+
+// NewPayload возвращает пустую полезную нагрузку сообщений модуля moduleID.
+func NewPayload(moduleID ModuleID) (Packer, bool) {
+	switch moduleID {
 	case DepthMeterModuleID:
-		m.Payload = &DepthMeterData{}
+		return &DepthMeterData{}, true
 	case LidarModuleID:
-		m.Payload = &LidarData{}
+		return &LidarData{}, true
 	case IMUModuleID:
-		m.Payload = &IMUData{}
+		return &IMUData{}, true
 	case GNSSModuleID:
-		m.Payload = &GNSSData{}
+		return &GNSSData{}, true
+	case NavigationModuleID:
+		return &NavigationData{}, true
 	case CameraModuleID:
-		m.Payload = &CameraData{}
+		return &CameraData{}, true
 	case CheckModuleID:
-		m.Payload = &CheckData{}
+		return &CheckData{}, true
+	case ControlModuleID:
+		return &ControlData{}, true
+	case PowerModuleID:
+		return &PowerData{}, true
 	default:
-		panic(fmt.Sprintf("Unpack is not implemented for this addr (%x)", m.ModuleID))
+		return nil, false
 	}
-
-	return m.Payload.Unpack(rawPayload, m.MsgID)
 }
 
 var startStamp = time.Now().UnixMilli()
@@ -352,6 +405,11 @@ func GetStartStamp() uint32 {
 	return uint32(startStamp / 1000)
 }
 
+// SystemTime возвращает текущее системное время в мс (см. Message.SystemTime).
+func SystemTime() uint32 {
+	return systemTime()
+}
+
 func systemTime() uint32 {
 	return uint32(time.Now().UnixMilli() - startStamp)
 }
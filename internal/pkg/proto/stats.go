@@ -0,0 +1,61 @@
+package proto
+
+import (
+	"asvsoft/internal/pkg/encoder"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const statsDataPayloadSize = 60
+
+// StatsData статистика канала связи модуля. Длительности передаются в микросекундах.
+type StatsData struct {
+	FramesSent       uint32
+	BytesSent        uint32
+	FramesReceived   uint32
+	BytesReceived    uint32
+	Retries          uint32
+	AckTimeouts      uint32
+	AckFailures      uint32
+	ChecksumFailures uint32
+	Resyncs          uint32
+	Reopens          uint32
+	Decimated        uint32
+	AckRTTMean       uint32
+	AckRTTMax        uint32
+	QueueLatencyMean uint32
+	QueueLatencyMax  uint32
+}
+
+func (sd StatsData) String() string {
+	type _StatsData StatsData
+	return fmt.Sprintf("%+v", _StatsData(sd))
+}
+
+func (sd *StatsData) Pack(_ MessageID) ([]byte, error) {
+	enc := encoder.NewEncoder(bytes.NewBuffer(make([]byte, 0, statsDataPayloadSize)))
+
+	err := enc.Encode(
+		sd.FramesSent, sd.BytesSent, sd.FramesReceived, sd.BytesReceived,
+		sd.Retries, sd.AckTimeouts, sd.AckFailures, sd.ChecksumFailures,
+		sd.Resyncs, sd.Reopens, sd.Decimated,
+		sd.AckRTTMean, sd.AckRTTMax, sd.QueueLatencyMean, sd.QueueLatencyMax,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.Bytes(), nil
+}
+
+func (sd *StatsData) Unpack(in []byte, _ MessageID) error {
+	dec := encoder.NewDecoder(io.NopCloser(bytes.NewReader(in)))
+
+	return dec.Decode(
+		&sd.FramesSent, &sd.BytesSent, &sd.FramesReceived, &sd.BytesReceived,
+		&sd.Retries, &sd.AckTimeouts, &sd.AckFailures, &sd.ChecksumFailures,
+		&sd.Resyncs, &sd.Reopens, &sd.Decimated,
+		&sd.AckRTTMean, &sd.AckRTTMax, &sd.QueueLatencyMean, &sd.QueueLatencyMax,
+	)
+}
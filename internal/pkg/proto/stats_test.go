@@ -0,0 +1,43 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsDataSuccess(t *testing.T) {
+	t.Run("успешная упаковка и распаковка статистики канала связи", func(t *testing.T) {
+		sentMsg := NewMessage(CheckModuleID, StatsReport, &StatsData{
+			FramesSent:       120,
+			BytesSent:        1800,
+			Retries:          3,
+			AckTimeouts:      2,
+			ChecksumFailures: 1,
+			AckRTTMean:       1500,
+			AckRTTMax:        40000,
+			QueueLatencyMax:  200,
+		})
+
+		msgBytes, err := sentMsg.Marshal()
+		require.NoError(t, err)
+		require.Len(t, msgBytes, FrameSize(statsDataPayloadSize))
+
+		receivedMsg := new(Message)
+
+		err = receivedMsg.Unmarshal(msgBytes)
+		require.NoError(t, err)
+
+		require.Equal(t, sentMsg, receivedMsg)
+	})
+
+	t.Run("ошибка контрольной суммы", func(t *testing.T) {
+		msgBytes, err := NewMessage(CheckModuleID, StatsReport, &StatsData{}).Marshal()
+		require.NoError(t, err)
+
+		msgBytes[len(msgBytes)-1]++
+
+		err = new(Message).Unmarshal(msgBytes)
+		require.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+}
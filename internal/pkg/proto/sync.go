@@ -11,7 +11,7 @@ type SyncData uint32
 func (sd *SyncData) Pack(msgID MessageID) ([]byte, error) {
 	enc := encoder.NewEncoder(bytes.NewBuffer(make([]byte, 0, 4)))
 
-	err := enc.Encode(sd)
+	err := enc.Encode(uint32(*sd))
 	if err != nil {
 		return nil, err
 	}
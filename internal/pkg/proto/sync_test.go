@@ -0,0 +1,24 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncDataSuccess(t *testing.T) {
+	t.Run("успешная упакова и распаковка ответа синхронизации", func(t *testing.T) {
+		data := SyncData(1718000000)
+		sentMsg := NewMessage(ControlModuleID, SyncResponse, &data)
+
+		msgBytes, err := sentMsg.Marshal()
+		require.NoError(t, err)
+
+		receivedMsg := new(Message)
+
+		err = receivedMsg.Unmarshal(msgBytes)
+		require.NoError(t, err)
+
+		require.Equal(t, sentMsg, receivedMsg)
+	})
+}
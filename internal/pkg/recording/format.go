@@ -0,0 +1,183 @@
+// Package recording предоставляет бинарный формат записи принятых фреймов регистратора.
+//
+// Файл записи состоит из заголовка, последовательности записей и необязательного индекса в конце
+// файла. Все числа записываются в порядке little-endian.
+//
+// Заголовок файла (16 байт):
+//
+//	magic   [6]byte  "ASVREC"
+//	version uint16   версия формата, сейчас 1
+//	created int64    время создания файла, нс от начала эпохи Unix
+//
+// Запись:
+//
+//	type uint8    тип записи
+//	size uint32   размер тела записи
+//	body [size]byte
+//	crc  uint32   CRC-32 (IEEE) полей type, size и body
+//
+// Типы записей:
+//
+//   - RecordSource: id uint8, имя источника (порт, с которого приняты фреймы). Записи источников
+//     пишутся в начало каждого файла и при регистрации нового источника.
+//   - RecordFrame: time int64 (время приема, нс от начала эпохи Unix), source uint8, flags uint8
+//     (см. Flags), фрейм целиком в том виде, в котором он был принят.
+//   - RecordIndex: количество источников uint8, для каждого id uint8, длина имени uint8 и имя;
+//     количество точек индекса uint32, для каждой time int64 и смещение записи фрейма от начала
+//     файла uint64.
+//
+// Запись индекса пишется при закрытии файла, за ней следует окончание файла (16 байт): смещение
+// записи индекса uint64 и magic [8]byte "ASVINDEX". Файл без окончания (например, после аварийного
+// завершения) читается последовательно до последней целой записи.
+package recording
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"time"
+)
+
+// Version версия формата записи
+const Version = 1
+
+const (
+	fileMagic    = "ASVREC"
+	trailerMagic = "ASVINDEX"
+
+	fileHeaderSize    = 16
+	recordHeaderSize  = 5
+	recordCRCSize     = 4
+	trailerSize       = 16
+	frameHeaderSize   = 10
+	indexEntrySize    = 16
+	maxSourceNameSize = 255
+	// maxRecordSize ограничивает размер тела записи при чтении поврежденного файла
+	maxRecordSize = 64 << 20
+)
+
+// RecordType тип записи
+type RecordType uint8
+
+const (
+	RecordSource RecordType = 0x01
+	RecordFrame  RecordType = 0x02
+	RecordIndex  RecordType = 0x03
+)
+
+// Flags признаки принятого фрейма
+type Flags uint8
+
+const (
+	// FlagChecksumFailed контрольная сумма фрейма не совпала с вычисленной
+	FlagChecksumFailed Flags = 1 << iota
+	// FlagInvalid фрейм не удалось распаковать по другой причине
+	FlagInvalid
+)
+
+var (
+	// ErrBadHeader файл не является записью регистратора или имеет неподдерживаемую версию
+	ErrBadHeader = errors.New("bad recording header")
+	// ErrCorrupted запись повреждена или обрезана
+	ErrCorrupted = errors.New("corrupted record")
+)
+
+// Record принятый фрейм
+type Record struct {
+	// Time время приема фрейма
+	Time time.Time
+	// Source имя источника фрейма
+	Source string
+	Flags  Flags
+	// Frame фрейм целиком, включая заголовок и контрольную сумму
+	Frame []byte
+}
+
+// IndexEntry точка индекса: время и смещение записи фрейма от начала файла
+type IndexEntry struct {
+	Time   time.Time
+	Offset int64
+}
+
+func appendRecord(buf []byte, typ RecordType, body []byte) []byte {
+	start := len(buf)
+
+	buf = append(buf, byte(typ))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(body))) // nolint:gosec
+	buf = append(buf, body...)
+
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[start:]))
+}
+
+func appendSource(buf []byte, id uint8, name string) []byte {
+	buf = append(buf, id)
+	return append(buf, name...)
+}
+
+func appendFrame(buf []byte, t time.Time, source uint8, flags Flags, frame []byte) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(t.UnixNano())) // nolint:gosec
+	buf = append(buf, source, byte(flags))
+
+	return append(buf, frame...)
+}
+
+func appendIndex(buf []byte, sources []string, entries []IndexEntry) []byte {
+	buf = append(buf, uint8(len(sources))) // nolint:gosec
+
+	for id, name := range sources {
+		buf = append(buf, uint8(id), uint8(len(name))) // nolint:gosec
+		buf = append(buf, name...)
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entries))) // nolint:gosec
+
+	for _, e := range entries {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(e.Time.UnixNano())) // nolint:gosec
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(e.Offset))          // nolint:gosec
+	}
+
+	return buf
+}
+
+func parseIndex(body []byte) (map[uint8]string, []IndexEntry, error) {
+	if len(body) < 1 {
+		return nil, nil, ErrCorrupted
+	}
+
+	count := int(body[0])
+	body = body[1:]
+
+	sources := make(map[uint8]string, count)
+
+	for range count {
+		if len(body) < 2 || len(body) < 2+int(body[1]) {
+			return nil, nil, ErrCorrupted
+		}
+
+		sources[body[0]] = string(body[2 : 2+int(body[1])])
+		body = body[2+int(body[1]):]
+	}
+
+	if len(body) < 4 {
+		return nil, nil, ErrCorrupted
+	}
+
+	n := int(binary.LittleEndian.Uint32(body))
+	body = body[4:]
+
+	if len(body) != n*indexEntrySize {
+		return nil, nil, ErrCorrupted
+	}
+
+	entries := make([]IndexEntry, n)
+
+	for i := range entries {
+		e := body[i*indexEntrySize:]
+		entries[i] = IndexEntry{
+			Time:   time.Unix(0, int64(binary.LittleEndian.Uint64(e))), // nolint:gosec
+			Offset: int64(binary.LittleEndian.Uint64(e[8:])),           // nolint:gosec
+		}
+	}
+
+	return sources, entries, nil
+}
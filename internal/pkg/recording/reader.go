@@ -0,0 +1,250 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Reader последовательно читает фреймы из файла записи.
+type Reader struct {
+	f       *os.File
+	r       *bufio.Reader
+	created time.Time
+	offset  int64
+	end     int64
+	sources map[uint8]string
+	index   []IndexEntry
+}
+
+// Open открывает файл записи. Если файл содержит индекс, он доступен через Index.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{
+		f:       f,
+		sources: make(map[uint8]string),
+	}
+
+	err = r.init()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return r, nil
+}
+
+func (r *Reader) init() error {
+	header := make([]byte, fileHeaderSize)
+
+	_, err := io.ReadFull(r.f, header)
+	if err != nil {
+		return ErrBadHeader
+	}
+
+	if string(header[:len(fileMagic)]) != fileMagic || binary.LittleEndian.Uint16(header[6:]) != Version {
+		return ErrBadHeader
+	}
+
+	r.created = time.Unix(0, int64(binary.LittleEndian.Uint64(header[8:]))) // nolint:gosec
+
+	info, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+
+	r.end = info.Size()
+
+	// файл без индекса читается до конца
+	_ = r.readIndex()
+
+	return r.seekOffset(fileHeaderSize)
+}
+
+func (r *Reader) readIndex() error {
+	if r.end < fileHeaderSize+trailerSize {
+		return ErrCorrupted
+	}
+
+	trailer := make([]byte, trailerSize)
+
+	_, err := r.f.ReadAt(trailer, r.end-trailerSize)
+	if err != nil {
+		return err
+	}
+
+	if string(trailer[8:]) != trailerMagic {
+		return ErrCorrupted
+	}
+
+	offset := int64(binary.LittleEndian.Uint64(trailer)) // nolint:gosec
+	if offset < fileHeaderSize || offset >= r.end-trailerSize {
+		return ErrCorrupted
+	}
+
+	typ, body, err := readRecord(bufio.NewReader(io.NewSectionReader(r.f, offset, r.end-trailerSize-offset)))
+	if err != nil || typ != RecordIndex {
+		return ErrCorrupted
+	}
+
+	sources, index, err := parseIndex(body)
+	if err != nil {
+		return err
+	}
+
+	r.sources = sources
+	r.index = index
+	r.end = offset
+
+	return nil
+}
+
+// Created возвращает время создания файла.
+func (r *Reader) Created() time.Time {
+	return r.created
+}
+
+// Index возвращает индекс файла или nil, если файл был закрыт некорректно.
+func (r *Reader) Index() []IndexEntry {
+	return r.index
+}
+
+// Sources возвращает имена источников по их идентификаторам.
+func (r *Reader) Sources() map[uint8]string {
+	return r.sources
+}
+
+// Next возвращает следующий фрейм. В конце файла возвращает io.EOF, при повреждении или
+// обрезанной записи - ErrCorrupted.
+func (r *Reader) Next() (Record, error) {
+	for {
+		if r.offset >= r.end {
+			return Record{}, io.EOF
+		}
+
+		typ, body, err := readRecord(r.r)
+		if err != nil {
+			return Record{}, fmt.Errorf("offset %d: %w", r.offset, err)
+		}
+
+		r.offset += int64(recordHeaderSize + len(body) + recordCRCSize)
+
+		switch typ {
+		case RecordSource:
+			if len(body) < 1 {
+				return Record{}, ErrCorrupted
+			}
+
+			r.sources[body[0]] = string(body[1:])
+		case RecordFrame:
+			if len(body) < frameHeaderSize {
+				return Record{}, ErrCorrupted
+			}
+
+			return Record{
+				Time:   time.Unix(0, int64(binary.LittleEndian.Uint64(body))), // nolint:gosec
+				Source: r.sources[body[8]],
+				Flags:  Flags(body[9]),
+				Frame:  body[frameHeaderSize:],
+			}, nil
+		case RecordIndex:
+			return Record{}, io.EOF
+		}
+	}
+}
+
+// Seek перемещает чтение к первому фрейму, принятому не раньше t. Без индекса файл
+// просматривается с начала.
+func (r *Reader) Seek(t time.Time) error {
+	offset := int64(fileHeaderSize)
+
+	if len(r.index) > 0 {
+		i := sort.Search(len(r.index), func(i int) bool { return r.index[i].Time.After(t) })
+		if i > 0 {
+			offset = r.index[i-1].Offset
+		}
+	}
+
+	err := r.seekOffset(offset)
+	if err != nil {
+		return err
+	}
+
+	for {
+		pos := r.offset
+
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !rec.Time.Before(t) {
+			// возврат к найденной записи
+			return r.seekOffset(pos)
+		}
+	}
+}
+
+func (r *Reader) seekOffset(offset int64) error {
+	_, err := r.f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	r.offset = offset
+	r.r = bufio.NewReader(r.f)
+
+	return nil
+}
+
+func (r *Reader) Close() error {
+	return r.f.Close()
+}
+
+func readRecord(r *bufio.Reader) (RecordType, []byte, error) {
+	header := make([]byte, recordHeaderSize)
+
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, nil, ErrCorrupted
+	}
+
+	size := binary.LittleEndian.Uint32(header[1:])
+	if size > maxRecordSize {
+		return 0, nil, ErrCorrupted
+	}
+
+	data := make([]byte, int(size)+recordCRCSize)
+
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		return 0, nil, ErrCorrupted
+	}
+
+	body, crc := data[:size], binary.LittleEndian.Uint32(data[size:])
+
+	hash := crc32.NewIEEE()
+	_, _ = hash.Write(header)
+	_, _ = hash.Write(body)
+
+	if hash.Sum32() != crc {
+		return 0, nil, ErrCorrupted
+	}
+
+	return RecordType(header[0]), bytes.Clone(body), nil
+}
@@ -0,0 +1,205 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, path string) ([]Record, error) {
+	t.Helper()
+
+	r, err := Open(path)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	var records []Record
+
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, rec)
+	}
+}
+
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"+FileExt))
+	require.NoError(t, err)
+
+	return names
+}
+
+func TestWriterReader(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Unix(1700000000, 0)
+
+	w := NewWriter(dir, "rec").WithIndexInterval(time.Second)
+
+	gnss, err := w.Source("/dev/ttyAMA5")
+	require.NoError(t, err)
+
+	imu, err := w.Source("tcp://0.0.0.0:5600")
+	require.NoError(t, err)
+
+	for i := range 10 {
+		src := gnss
+		if i%2 == 1 {
+			src = imu
+		}
+
+		require.NoError(t, w.Write(src, start.Add(time.Duration(i)*500*time.Millisecond), 0, []byte{0xFA, 0xFA, byte(i)}))
+	}
+
+	require.NoError(t, w.Write(gnss, start.Add(5*time.Second), FlagChecksumFailed, []byte{0xFA, 0xFA, 0xFF}))
+	require.NoError(t, w.Close())
+
+	names := files(t, dir)
+	require.Len(t, names, 1)
+
+	records, err := readAll(t, names[0])
+	require.NoError(t, err)
+	require.Len(t, records, 11)
+	require.Equal(t, "tcp://0.0.0.0:5600", records[1].Source)
+	require.Equal(t, []byte{0xFA, 0xFA, 0x01}, records[1].Frame)
+	require.True(t, records[1].Time.Equal(start.Add(500*time.Millisecond)))
+	require.Equal(t, FlagChecksumFailed, records[10].Flags)
+
+	t.Run("индекс и поиск", func(t *testing.T) {
+		r, err := Open(names[0])
+		require.NoError(t, err)
+
+		defer r.Close()
+
+		require.Len(t, r.Index(), 6)
+
+		require.NoError(t, r.Seek(start.Add(2200*time.Millisecond)))
+
+		rec, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, []byte{0xFA, 0xFA, 0x05}, rec.Frame)
+		require.Equal(t, "tcp://0.0.0.0:5600", rec.Source)
+	})
+
+	t.Run("обрезанный файл", func(t *testing.T) {
+		data, err := os.ReadFile(names[0])
+		require.NoError(t, err)
+
+		// индекс и половина последней записи потеряны
+		path := filepath.Join(t.TempDir(), "crash"+FileExt)
+		require.NoError(t, os.WriteFile(path, data[:len(data)-trailerSize-100], 0600))
+
+		records, err := readAll(t, path)
+		require.ErrorIs(t, err, ErrCorrupted)
+		require.NotEmpty(t, records)
+		require.Equal(t, []byte{0xFA, 0xFA, 0x00}, records[0].Frame)
+
+		r, err := Open(path)
+		require.NoError(t, err)
+
+		defer r.Close()
+
+		require.Nil(t, r.Index())
+		require.NoError(t, r.Seek(start.Add(time.Second)))
+
+		rec, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, []byte{0xFA, 0xFA, 0x02}, rec.Frame)
+	})
+}
+
+func TestWriterRotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(1700000000, 0)
+
+	w := NewWriter(dir, "rec").WithMaxSize(200).WithMaxDuration(time.Minute)
+	w.now = func() time.Time { return now }
+
+	src, err := w.Source("port")
+	require.NoError(t, err)
+
+	frame := make([]byte, 50)
+
+	// три записи по 69 байт не помещаются в файл размером 200 байт
+	for range 3 {
+		require.NoError(t, w.Write(src, now, 0, frame))
+	}
+
+	now = now.Add(time.Minute)
+
+	require.NoError(t, w.Write(src, now, 0, frame))
+	require.NoError(t, w.Close())
+
+	names := files(t, dir)
+	require.Len(t, names, 3)
+
+	total := 0
+
+	for _, name := range names {
+		records, err := readAll(t, name)
+		require.NoError(t, err)
+
+		for _, rec := range records {
+			require.Equal(t, "port", rec.Source)
+		}
+
+		total += len(records)
+	}
+
+	require.Equal(t, 4, total)
+}
+
+func TestWriterSources(t *testing.T) {
+	t.Run("наибольшее количество источников", func(t *testing.T) {
+		dir := t.TempDir()
+		now := time.Unix(1700000000, 0)
+
+		w := NewWriter(dir, "rec")
+
+		var last uint8
+
+		for i := range 0xFF {
+			id, err := w.Source(fmt.Sprintf("port%d", i))
+			require.NoError(t, err)
+
+			last = id
+		}
+
+		require.Equal(t, uint8(0xFE), last)
+
+		_, err := w.Source("port255")
+		require.Error(t, err)
+
+		require.NoError(t, w.Write(last, now, 0, []byte{0xFA}))
+		require.NoError(t, w.Close())
+
+		names := files(t, dir)
+		require.Len(t, names, 1)
+
+		r, err := Open(names[0])
+		require.NoError(t, err)
+
+		defer r.Close()
+
+		require.Len(t, r.Sources(), 0xFF)
+
+		rec, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, "port254", rec.Source)
+	})
+}
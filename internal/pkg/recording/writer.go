@@ -0,0 +1,271 @@
+package recording
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIndexInterval минимальный интервал между точками индекса по умолчанию
+	DefaultIndexInterval = time.Second
+	// DefaultSyncInterval период сброса записанных данных на диск по умолчанию
+	DefaultSyncInterval = time.Second
+	// FileExt расширение файлов записи
+	FileExt = ".asvrec"
+)
+
+// Writer записывает принятые фреймы в файлы записи в директории dir. Файлы ротируются при
+// превышении размера или длительности записи. Каждая запись добавляется в файл одной операцией
+// записи, поэтому при аварийном завершении теряется не более одной последней записи.
+// Безопасен для одновременного использования из нескольких горутин.
+type Writer struct {
+	dir           string
+	prefix        string
+	maxSize       int64
+	maxDuration   time.Duration
+	indexInterval time.Duration
+	syncInterval  time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	f         *os.File
+	size      int64
+	opened    time.Time
+	synced    time.Time
+	seq       int
+	sources   []string
+	index     []IndexEntry
+	lastIndex time.Time
+	buf       []byte
+}
+
+func NewWriter(dir, prefix string) *Writer {
+	return &Writer{
+		dir:           dir,
+		prefix:        prefix,
+		indexInterval: DefaultIndexInterval,
+		syncInterval:  DefaultSyncInterval,
+		now:           time.Now,
+	}
+}
+
+// WithMaxSize задает максимальный размер файла в байтах, 0 - без ограничения.
+func (w *Writer) WithMaxSize(size int64) *Writer {
+	w.maxSize = size
+	return w
+}
+
+// WithMaxDuration задает максимальную длительность записи одного файла, 0 - без ограничения.
+func (w *Writer) WithMaxDuration(d time.Duration) *Writer {
+	w.maxDuration = d
+	return w
+}
+
+func (w *Writer) WithIndexInterval(interval time.Duration) *Writer {
+	w.indexInterval = interval
+	return w
+}
+
+// WithSyncInterval задает период вызова fsync, 0 - после каждой записи.
+func (w *Writer) WithSyncInterval(interval time.Duration) *Writer {
+	w.syncInterval = interval
+	return w
+}
+
+// Source регистрирует источник фреймов name и возвращает его идентификатор. Повторная
+// регистрация возвращает тот же идентификатор.
+func (w *Writer) Source(name string) (uint8, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, s := range w.sources {
+		if s == name {
+			return uint8(id), nil // nolint:gosec
+		}
+	}
+
+	// идентификаторы и количество источников в индексе занимают по байту
+	if len(w.sources) >= 0xFF {
+		return 0, fmt.Errorf("too many sources")
+	}
+
+	if len(name) > maxSourceNameSize {
+		return 0, fmt.Errorf("source name %q is too long", name)
+	}
+
+	id := uint8(len(w.sources)) // nolint:gosec
+	w.sources = append(w.sources, name)
+
+	if w.f != nil {
+		err := w.append(RecordSource, appendSource(nil, id, name))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// Write записывает фрейм frame, принятый от источника source в момент t.
+func (w *Writer) Write(source uint8, t time.Time, flags Flags, frame []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if int(source) >= len(w.sources) {
+		return fmt.Errorf("unknown source %d", source)
+	}
+
+	body := appendFrame(w.buf[:0], t, source, flags, frame)
+	w.buf = body
+
+	err := w.rotateIfNeeded(int64(recordHeaderSize + len(body) + recordCRCSize))
+	if err != nil {
+		return err
+	}
+
+	offset := w.size
+
+	err = w.append(RecordFrame, body)
+	if err != nil {
+		return err
+	}
+
+	if len(w.index) == 0 || t.Sub(w.lastIndex) >= w.indexInterval {
+		w.index = append(w.index, IndexEntry{Time: t, Offset: offset})
+		w.lastIndex = t
+	}
+
+	now := w.now()
+	if now.Sub(w.synced) >= w.syncInterval {
+		w.synced = now
+
+		err = w.f.Sync()
+		if err != nil {
+			return fmt.Errorf("cannot sync recording: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close записывает индекс и закрывает текущий файл.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.closeFile()
+}
+
+// FileName возвращает имя текущего файла записи.
+func (w *Writer) FileName() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return ""
+	}
+
+	return w.f.Name()
+}
+
+func (w *Writer) rotateIfNeeded(recordSize int64) error {
+	if w.f != nil {
+		full := w.maxSize > 0 && w.size > fileHeaderSize && w.size+recordSize > w.maxSize
+		expired := w.maxDuration > 0 && w.now().Sub(w.opened) >= w.maxDuration
+
+		if !full && !expired {
+			return nil
+		}
+
+		err := w.closeFile()
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.openFile()
+}
+
+func (w *Writer) openFile() error {
+	now := w.now()
+
+	w.seq++
+	name := filepath.Join(w.dir, fmt.Sprintf("%s-%s-%04d%s", w.prefix, now.Format("20060102T150405"), w.seq, FileExt))
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0644) // nolint:gosec
+	if err != nil {
+		return fmt.Errorf("cannot create recording: %w", err)
+	}
+
+	header := make([]byte, 0, fileHeaderSize)
+	header = append(header, fileMagic...)
+	header = binary.LittleEndian.AppendUint16(header, Version)
+	header = binary.LittleEndian.AppendUint64(header, uint64(now.UnixNano())) // nolint:gosec
+
+	_, err = f.Write(header)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot write recording header: %w", err)
+	}
+
+	w.f = f
+	w.size = fileHeaderSize
+	w.opened = now
+	w.synced = now
+	w.index = w.index[:0]
+
+	for id, name := range w.sources {
+		err = w.append(RecordSource, appendSource(nil, uint8(id), name)) // nolint:gosec
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) closeFile() error {
+	if w.f == nil {
+		return nil
+	}
+
+	f := w.f
+	w.f = nil
+
+	offset := w.size
+
+	data := appendRecord(nil, RecordIndex, appendIndex(nil, w.sources, w.index))
+	data = binary.LittleEndian.AppendUint64(data, uint64(offset)) // nolint:gosec
+	data = append(data, trailerMagic...)
+
+	_, err := f.Write(data)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot write recording index: %w", err)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot sync recording: %w", err)
+	}
+
+	return f.Close()
+}
+
+func (w *Writer) append(typ RecordType, body []byte) error {
+	data := appendRecord(nil, typ, body)
+
+	n, err := w.f.Write(data)
+	w.size += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("cannot write record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package replay предоставляет воспроизведение записей регистратора в канал связи с исходными
+// интервалами между фреймами
+package replay
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Stats счетчики воспроизведения
+type Stats struct {
+	Frames  int
+	Bytes   int
+	Skipped int
+	Loops   int
+}
+
+// Player воспроизводит фреймы записей регистратора в w.
+type Player struct {
+	w       io.Writer
+	speed   float64
+	loop    bool
+	modules []proto.ModuleID
+	restamp bool
+	log     logger.Logger
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func New(w io.Writer) *Player {
+	return &Player{
+		w:     w,
+		speed: 1,
+		log:   logger.DummyLogger{},
+		now:   time.Now,
+		sleep: sleep,
+	}
+}
+
+// WithSpeed задает множитель скорости воспроизведения, неположительное значение отключает
+// ожидание между фреймами.
+func (p *Player) WithSpeed(speed float64) *Player {
+	p.speed = speed
+	return p
+}
+
+// WithLoop включает повтор воспроизведения записей до отмены контекста.
+func (p *Player) WithLoop(loop bool) *Player {
+	p.loop = loop
+	return p
+}
+
+// WithModules ограничивает воспроизведение фреймами модулей modules, фреймы, которые не удалось
+// проверить, при этом пропускаются. Пустой список - все фреймы, включая поврежденные.
+func (p *Player) WithModules(modules []proto.ModuleID) *Player {
+	p.modules = modules
+	return p
+}
+
+// WithRestamp включает замену системного времени фреймов временем от начала воспроизведения
+// в миллисекундах.
+func (p *Player) WithRestamp(restamp bool) *Player {
+	p.restamp = restamp
+	return p
+}
+
+func (p *Player) WithLogger(log logger.Logger) *Player {
+	p.log = log
+	return p
+}
+
+// Play воспроизводит записи paths по порядку. Интервалы между фреймами, в том числе между
+// файлами, сохраняются с учетом скорости воспроизведения.
+func (p *Player) Play(ctx context.Context, paths []string) (Stats, error) {
+	var stats Stats
+
+	for {
+		err := p.playOnce(ctx, paths, &stats)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Loops++
+
+		if !p.loop {
+			return stats, nil
+		}
+
+		p.log.Infof("replay finished, loop %d: %d frames", stats.Loops, stats.Frames)
+	}
+}
+
+func (p *Player) playOnce(ctx context.Context, paths []string, stats *Stats) error {
+	var (
+		first   time.Time
+		started = p.now()
+	)
+
+	for _, path := range paths {
+		r, err := recording.Open(path)
+		if err != nil {
+			return err
+		}
+
+		p.log.Infof("replaying %s", path)
+
+		err = p.playFile(ctx, r, &first, started, stats)
+
+		_ = r.Close()
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Player) playFile(ctx context.Context, r *recording.Reader, first *time.Time, started time.Time, stats *Stats) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if errors.Is(err, recording.ErrCorrupted) {
+			p.log.Warnf("stop reading file: %v", err)
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !p.match(rec) {
+			stats.Skipped++
+			continue
+		}
+
+		if first.IsZero() {
+			*first = rec.Time
+		}
+
+		if p.speed > 0 {
+			at := started.Add(time.Duration(float64(rec.Time.Sub(*first)) / p.speed))
+
+			err = p.sleep(ctx, at.Sub(p.now()))
+			if err != nil {
+				return err
+			}
+		}
+
+		frame := rec.Frame
+
+		if p.restamp && rec.Flags == 0 {
+			err = proto.SetSystemTime(frame, uint32(p.now().Sub(started).Milliseconds())) // nolint:gosec
+			if err != nil {
+				p.log.Warnf("cannot restamp frame: %v", err)
+			}
+		}
+
+		n, err := p.w.Write(frame)
+		if err != nil {
+			return fmt.Errorf("cannot write frame: %w", err)
+		}
+
+		stats.Frames++
+		stats.Bytes += n
+	}
+}
+
+func (p *Player) match(rec recording.Record) bool {
+	if len(p.modules) == 0 {
+		return true
+	}
+
+	info, err := proto.VerifyFrame(rec.Frame)
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(p.modules, info.ModuleID)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package replay
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"asvsoft/internal/pkg/recording"
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func record(t *testing.T) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	start := time.Unix(1700000000, 0)
+
+	w := recording.NewWriter(dir, "rec")
+	src, err := w.Source("port")
+	require.NoError(t, err)
+
+	msgs := []*proto.Message{
+		proto.NewMessage(proto.GNSSModuleID, proto.WritingModeB, &proto.GNSSData{Lat: 1}),
+		proto.NewMessage(proto.CheckModuleID, proto.WritingModeA, &proto.CheckData{Value: 1}),
+		proto.NewMessage(proto.GNSSModuleID, proto.WritingModeB, &proto.GNSSData{Lat: 2}),
+	}
+
+	for i, msg := range msgs {
+		msg.SystemTime = 5000
+
+		raw, err := msg.MarshalKeepTime()
+		require.NoError(t, err)
+
+		require.NoError(t, w.Write(src, start.Add(time.Duration(i)*time.Second), 0, raw))
+	}
+
+	require.NoError(t, w.Close())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+recording.FileExt))
+	require.NoError(t, err)
+
+	return paths
+}
+
+// fakeClock виртуальное время, которое продвигается ожиданием
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) sleep(_ context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+
+	return nil
+}
+
+func newPlayer(out *bytes.Buffer, clock *fakeClock) *Player {
+	p := New(out)
+	p.now = func() time.Time { return clock.now }
+	p.sleep = clock.sleep
+
+	return p
+}
+
+func TestPlayer(t *testing.T) {
+	paths := record(t)
+
+	t.Run("исходные интервалы с ускорением", func(t *testing.T) {
+		var out bytes.Buffer
+
+		clock := &fakeClock{now: time.Unix(1800000000, 0)}
+
+		stats, err := newPlayer(&out, clock).WithSpeed(2).Play(context.Background(), paths)
+		require.NoError(t, err)
+		require.Equal(t, 3, stats.Frames)
+		require.Equal(t, []time.Duration{0, 500 * time.Millisecond, 500 * time.Millisecond}, clock.sleeps)
+		require.Equal(t, stats.Bytes, out.Len())
+	})
+
+	t.Run("фильтр модулей и замена времени", func(t *testing.T) {
+		var out bytes.Buffer
+
+		clock := &fakeClock{now: time.Unix(1800000000, 0)}
+
+		stats, err := newPlayer(&out, clock).
+			WithModules([]proto.ModuleID{proto.GNSSModuleID}).
+			WithRestamp(true).
+			Play(context.Background(), paths)
+		require.NoError(t, err)
+		require.Equal(t, 2, stats.Frames)
+		require.Equal(t, 1, stats.Skipped)
+
+		var times []uint32
+
+		for out.Len() > 0 {
+			raw, err := proto.Read(&out)
+			require.NoError(t, err)
+
+			var msg proto.Message
+
+			require.NoError(t, msg.Unmarshal(raw))
+			require.Equal(t, proto.GNSSModuleID, msg.ModuleID)
+
+			times = append(times, msg.SystemTime)
+		}
+
+		require.Equal(t, []uint32{0, 2000}, times)
+	})
+
+	t.Run("повтор до отмены", func(t *testing.T) {
+		var out bytes.Buffer
+
+		clock := &fakeClock{now: time.Unix(1800000000, 0)}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		p := newPlayer(&out, clock).WithLoop(true).WithSpeed(0)
+		p.w = writerFunc(func(b []byte) (int, error) {
+			if out.Len() > 200 {
+				cancel()
+			}
+
+			return out.Write(b)
+		})
+
+		stats, err := p.Play(ctx, paths)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Positive(t, stats.Loops)
+	})
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
@@ -0,0 +1,279 @@
+// Package router предоставляет маршрутизацию полученных контроллером сообщений в другие
+// каналы связи
+package router
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultQueueSize размер очереди маршрута по умолчанию
+const DefaultQueueSize = 16
+
+// Forwarder пересылает сообщение получателю маршрута
+type Forwarder interface {
+	Forward(msg proto.Message) error
+}
+
+// Filter фильтр сообщений маршрута. Пустой список не ограничивает соответствующее поле.
+type Filter struct {
+	Modules  []proto.ModuleID
+	Messages []proto.MessageID
+}
+
+// Match проверяет, подходит ли сообщение msg под фильтр. Служебные сообщения (синхронизация,
+// подтверждения, статистика) не маршрутизируются.
+func (f Filter) Match(msg proto.Message) bool {
+	if msg.MsgID < proto.ReadingModeA || msg.MsgID > proto.WritingModeC {
+		return false
+	}
+
+	if len(f.Modules) > 0 && !slices.Contains(f.Modules, msg.ModuleID) {
+		return false
+	}
+
+	if len(f.Messages) > 0 && !slices.Contains(f.Messages, msg.MsgID) {
+		return false
+	}
+
+	return true
+}
+
+// Route маршрут: сообщения, прошедшие фильтр и прореживание, ставятся в очередь и пересылаются
+// получателю в отдельной горутине, поэтому медленный получатель не задерживает прием сообщений.
+// При заполнении очереди новые сообщения отбрасываются.
+type Route struct {
+	name     string
+	filter   Filter
+	fwd      Forwarder
+	interval time.Duration
+	reencode proto.MessageID
+	log      logger.Logger
+	queue    chan proto.Message
+
+	mu    sync.Mutex
+	last  map[proto.ModuleID]time.Time
+	stats RouteStats
+}
+
+// RouteStats счетчики сообщений маршрута
+type RouteStats struct {
+	Forwarded int
+	Failed    int
+	Decimated int
+	Dropped   int
+}
+
+func NewRoute(name string, filter Filter, fwd Forwarder) *Route {
+	return &Route{
+		name:   name,
+		filter: filter,
+		fwd:    fwd,
+		log:    logger.DummyLogger{},
+		queue:  make(chan proto.Message, DefaultQueueSize),
+		last:   make(map[proto.ModuleID]time.Time),
+	}
+}
+
+// WithRate ограничивает частоту пересылки сообщений каждого модуля rate сообщениями в секунду.
+// Неположительное значение отключает прореживание.
+func (r *Route) WithRate(rate float64) *Route {
+	r.interval = 0
+	if rate > 0 {
+		r.interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	return r
+}
+
+// WithReencode задает режим, в котором сообщения перекодируются перед пересылкой. Возвращает
+// ошибку, если сообщения модулей фильтра не могут быть закодированы в этом режиме.
+func (r *Route) WithReencode(mode proto.MessageID) (*Route, error) {
+	for _, id := range r.filter.Modules {
+		err := checkEncoding(id, mode)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	r.reencode = mode
+
+	return r, nil
+}
+
+func (r *Route) WithQueueSize(size int) *Route {
+	if size > 0 {
+		r.queue = make(chan proto.Message, size)
+	}
+
+	return r
+}
+
+func (r *Route) WithLogger(log logger.Logger) *Route {
+	r.log = log
+	return r
+}
+
+func (r *Route) Name() string {
+	return r.name
+}
+
+func (r *Route) Stats() RouteStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stats
+}
+
+// QueueLen возвращает количество сообщений в очереди маршрута.
+func (r *Route) QueueLen() int {
+	return len(r.queue)
+}
+
+// Forwarder возвращает получателя маршрута.
+func (r *Route) Forwarder() Forwarder {
+	return r.fwd
+}
+
+// Offer ставит сообщение в очередь маршрута, если оно проходит фильтр и прореживание.
+// Не блокируется.
+func (r *Route) Offer(msg proto.Message) bool {
+	if !r.filter.Match(msg) || !r.pass(msg.ModuleID) {
+		return false
+	}
+
+	select {
+	case r.queue <- msg:
+		return true
+	default:
+		r.mu.Lock()
+		r.stats.Dropped++
+		dropped := r.stats.Dropped
+		r.mu.Unlock()
+
+		r.log.Warnf("queue is full, drop message %s (dropped: %d)", msg, dropped)
+
+		return false
+	}
+}
+
+func (r *Route) pass(id proto.ModuleID) bool {
+	if r.interval == 0 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := r.last[id]; ok && now.Sub(last) < r.interval {
+		r.stats.Decimated++
+		return false
+	}
+
+	r.last[id] = now
+
+	return true
+}
+
+// Run пересылает сообщения из очереди до отмены контекста.
+func (r *Route) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-r.queue:
+			err := r.forward(msg)
+
+			r.mu.Lock()
+			if err != nil {
+				r.stats.Failed++
+			} else {
+				r.stats.Forwarded++
+			}
+			r.mu.Unlock()
+
+			if err != nil {
+				r.log.Errorf("cannot forward message %s: %v", msg, err)
+			}
+		}
+	}
+}
+
+func (r *Route) forward(msg proto.Message) (err error) {
+	if r.reencode != 0 && r.reencode != msg.MsgID {
+		// упаковка в неподдерживаемом режиме завершается паникой
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("cannot reencode message to %#X: %v", r.reencode, p)
+			}
+		}()
+
+		msg.MsgID = r.reencode
+	}
+
+	return r.fwd.Forward(msg)
+}
+
+// checkEncoding проверяет, что сообщения модуля id могут быть закодированы в режиме mode.
+func checkEncoding(id proto.ModuleID, mode proto.MessageID) (err error) {
+	payload, ok := proto.NewPayload(id)
+	if !ok {
+		return fmt.Errorf("module %s has no payload", proto.ModuleName(id))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("module %s cannot be encoded in mode %#X: %v", proto.ModuleName(id), mode, p)
+		}
+	}()
+
+	_, err = payload.Pack(mode)
+	if err != nil {
+		return fmt.Errorf("module %s cannot be encoded in mode %#X: %w", proto.ModuleName(id), mode, err)
+	}
+
+	return nil
+}
+
+// Router рассылает полученные сообщения по маршрутам
+type Router struct {
+	routes []*Route
+}
+
+func New(routes ...*Route) *Router {
+	return &Router{routes: routes}
+}
+
+// Routes возвращает маршруты.
+func (r *Router) Routes() []*Route {
+	return r.routes
+}
+
+// Dispatch предлагает сообщение всем маршрутам. Не блокируется.
+func (r *Router) Dispatch(msg proto.Message) {
+	for _, route := range r.routes {
+		route.Offer(msg)
+	}
+}
+
+// Run запускает пересылку сообщений всех маршрутов до отмены контекста.
+func (r *Router) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, route := range r.routes {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			route.Run(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
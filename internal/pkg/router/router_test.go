@@ -0,0 +1,103 @@
+package router
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeForwarder struct {
+	mu   sync.Mutex
+	msgs []proto.Message
+}
+
+func (f *fakeForwarder) Forward(msg proto.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.msgs = append(f.msgs, msg)
+
+	return nil
+}
+
+func (f *fakeForwarder) received() []proto.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]proto.Message(nil), f.msgs...)
+}
+
+func message(id proto.ModuleID, msgID proto.MessageID) proto.Message {
+	payload, _ := proto.NewPayload(id)
+	return proto.Message{ModuleID: id, MsgID: msgID, Payload: payload}
+}
+
+func TestFilter(t *testing.T) {
+	f := Filter{
+		Modules:  []proto.ModuleID{proto.GNSSModuleID, proto.DepthMeterModuleID},
+		Messages: []proto.MessageID{proto.WritingModeA},
+	}
+
+	require.True(t, f.Match(message(proto.GNSSModuleID, proto.WritingModeA)))
+	require.False(t, f.Match(message(proto.GNSSModuleID, proto.WritingModeB)))
+	require.False(t, f.Match(message(proto.IMUModuleID, proto.WritingModeA)))
+
+	t.Run("служебные сообщения не маршрутизируются", func(t *testing.T) {
+		require.False(t, Filter{}.Match(proto.Message{ModuleID: proto.GNSSModuleID, MsgID: proto.SyncRequest}))
+		require.False(t, Filter{}.Match(proto.Message{ModuleID: proto.GNSSModuleID, MsgID: proto.StatsReport}))
+	})
+}
+
+func TestRoute(t *testing.T) {
+	t.Run("прореживание по модулям", func(t *testing.T) {
+		r := NewRoute("telemetry", Filter{}, &fakeForwarder{}).WithRate(1)
+
+		require.True(t, r.Offer(message(proto.GNSSModuleID, proto.WritingModeA)))
+		require.False(t, r.Offer(message(proto.GNSSModuleID, proto.WritingModeA)))
+		require.True(t, r.Offer(message(proto.DepthMeterModuleID, proto.WritingModeA)))
+		require.Equal(t, 1, r.Stats().Decimated)
+	})
+
+	t.Run("переполнение очереди не блокирует прием", func(t *testing.T) {
+		r := NewRoute("registrar", Filter{}, &fakeForwarder{}).WithQueueSize(1)
+
+		require.True(t, r.Offer(message(proto.GNSSModuleID, proto.WritingModeA)))
+		require.False(t, r.Offer(message(proto.GNSSModuleID, proto.WritingModeA)))
+		require.Equal(t, 1, r.Stats().Dropped)
+	})
+
+	t.Run("перекодирование", func(t *testing.T) {
+		fwd := &fakeForwarder{}
+
+		r, err := NewRoute("radio", Filter{Modules: []proto.ModuleID{proto.GNSSModuleID}}, fwd).
+			WithReencode(proto.WritingModeC)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go New(r).Run(ctx)
+
+		msg := message(proto.GNSSModuleID, proto.WritingModeA)
+		msg.SystemTime = 1234
+
+		require.True(t, r.Offer(msg))
+
+		require.Eventually(t, func() bool { return len(fwd.received()) == 1 }, time.Second, time.Millisecond)
+
+		forwarded := fwd.received()[0]
+		require.Equal(t, proto.WritingModeC, forwarded.MsgID)
+		require.Equal(t, uint32(1234), forwarded.SystemTime)
+		require.Equal(t, 1, r.Stats().Forwarded)
+	})
+
+	t.Run("неподдерживаемый режим перекодирования", func(t *testing.T) {
+		_, err := NewRoute("radio", Filter{Modules: []proto.ModuleID{proto.CheckModuleID}}, &fakeForwarder{}).
+			WithReencode(proto.WritingModeB)
+		require.Error(t, err)
+	})
+}
@@ -2,9 +2,11 @@
 package serialport
 
 import (
+	"asvsoft/internal/pkg/linkstats"
 	"asvsoft/internal/pkg/logger"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.bug.st/serial"
@@ -18,7 +20,13 @@ var (
 type Wrapper struct {
 	serial.Port
 	logger logger.Logger
+	stats  *linkstats.Stats
 	Cfg    Config
+
+	// mu защищает замену Port при переоткрытии и closed
+	mu sync.Mutex
+	// closed порт закрыт вызовом Close и не переоткрывается при чтении
+	closed bool
 }
 
 type Config struct {
@@ -34,8 +42,9 @@ func New(cfg Config) (*Wrapper, error) {
 	}
 
 	return &Wrapper{
-		Port: port,
-		Cfg:  cfg,
+		Port:  port,
+		Cfg:   cfg,
+		stats: linkstats.New(),
 	}, nil
 }
 
@@ -68,9 +77,29 @@ func (w *Wrapper) Logger() logger.Logger {
 	return w.logger
 }
 
+// Stats возвращает статистику порта: количество переданных и принятых байт и переоткрытий.
+func (w *Wrapper) Stats() linkstats.Snapshot {
+	return w.stats.Snapshot()
+}
+
+// port возвращает текущий порт, который может быть заменен при переоткрытии.
+func (w *Wrapper) port() serial.Port {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.Port
+}
+
+func (w *Wrapper) Write(p []byte) (int, error) {
+	n, err := w.port().Write(p)
+	w.stats.BytesSent.Add(n)
+
+	return n, err
+}
+
 func (w *Wrapper) Read(p []byte) (n int, err error) {
 	for n < len(p) {
-		c, err := w.Port.Read(p[n:])
+		c, err := w.port().Read(p[n:])
 		if err != nil {
 			err = w.portClosedFallback(err)
 			if err != nil {
@@ -86,20 +115,33 @@ func (w *Wrapper) Read(p []byte) (n int, err error) {
 			return 0, ErrReadTimeout
 		}
 
+		w.stats.BytesReceived.Add(c)
 		n += c
 	}
 
 	return n, nil
 }
 
+// portClosedFallback переоткрывает порт, закрытый не вызовом Close.
 func (w *Wrapper) portClosedFallback(err error) error {
 	pErr := new(serial.PortError)
 	if errors.As(err, &pErr) && pErr.Code() == serial.PortClosed {
-		w.Port, err = newSerialPort(w.Cfg)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if w.closed {
+			return err
+		}
+
+		// при ошибке остается закрытый порт, следующее чтение повторит переоткрытие
+		port, err := newSerialPort(w.Cfg)
 		if err != nil {
 			return fmt.Errorf("port closed and failed to reopen: %w", err)
 		}
 
+		w.Port = port
+
+		w.stats.Reopens.Inc()
 		w.Logger().Warnf("serail port was reopened")
 
 		return nil
@@ -109,6 +151,11 @@ func (w *Wrapper) portClosedFallback(err error) error {
 }
 
 func (w *Wrapper) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+
 	if w.Port == nil {
 		return nil
 	}
@@ -0,0 +1,97 @@
+package serialport
+
+import (
+	"asvsoft/internal/pkg/serial-port/test"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapperClose(t *testing.T) {
+	t.Run("закрытие прерывает чтение без переоткрытия порта", func(t *testing.T) {
+		_, slave := test.OpenPTY(t)
+
+		w, err := New(Config{Port: slave, BaudRate: 9600})
+		require.NoError(t, err)
+
+		readErr := make(chan error, 1)
+
+		go func() {
+			_, err := w.Read(make([]byte, 1))
+			readErr <- err
+		}()
+
+		// чтение должно начаться до закрытия
+		time.Sleep(100 * time.Millisecond)
+
+		require.NoError(t, w.Close())
+
+		select {
+		case err := <-readErr:
+			require.Error(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("read is still blocked after close")
+		}
+
+		require.Zero(t, w.Stats().Reopens)
+
+		// порт освобожден и открывается повторно
+		w, err = New(Config{Port: slave, BaudRate: 9600})
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	})
+}
+
+func TestWrapperReopen(t *testing.T) {
+	t.Run("порт сохраняется при ошибке переоткрытия", func(t *testing.T) {
+		master, slave := test.OpenPTY(t)
+
+		w, err := New(Config{Port: slave, BaudRate: 9600})
+		require.NoError(t, err)
+
+		defer w.Close()
+
+		// без ведущей стороны ведомая не открывается
+		require.NoError(t, master.Close())
+		require.NoError(t, w.port().Close())
+
+		for range 2 {
+			_, err = w.Read(make([]byte, 1))
+			require.ErrorContains(t, err, "failed to reopen")
+		}
+
+		require.Zero(t, w.Stats().Reopens)
+	})
+
+	t.Run("запись во время переоткрытия", func(t *testing.T) {
+		master, slave := test.OpenPTY(t)
+
+		// ведущая сторона вычитывает записанное, чтобы запись не блокировалась
+		go func() { _, _ = io.Copy(io.Discard, master) }()
+
+		w, err := New(Config{Port: slave, BaudRate: 9600, Timeout: 10 * time.Millisecond})
+		require.NoError(t, err)
+
+		defer w.Close()
+
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			for range 100 {
+				_, _ = w.Write([]byte{0})
+			}
+		}()
+
+		require.NoError(t, w.port().Close())
+
+		_, err = w.Read(make([]byte, 1))
+		require.ErrorIs(t, err, ErrReadTimeout)
+		require.EqualValues(t, 1, w.Stats().Reopens)
+
+		<-done
+	})
+}
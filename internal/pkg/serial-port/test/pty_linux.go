@@ -0,0 +1,39 @@
+// Package test предоставляет псевдотерминалы для проверки работы с последовательным портом
+// без устройства
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenPTY открывает псевдотерминал и возвращает его ведущую сторону и путь до ведомой,
+// которая открывается как последовательный порт. Ведущая сторона закрывается по завершении
+// теста.
+func OpenPTY(t testing.TB) (*os.File, string) {
+	t.Helper()
+
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pty is not available: %v", err)
+	}
+
+	t.Cleanup(func() { _ = master.Close() })
+
+	fd := int(master.Fd())
+
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		t.Fatalf("cannot unlock pty: %v", err)
+	}
+
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatalf("cannot get pty number: %v", err)
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
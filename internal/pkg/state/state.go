@@ -0,0 +1,348 @@
+// Package state предоставляет хранилище текущего состояния аппарата: последние полученные
+// сообщения каждого модуля и режима, их частоту, устаревание и короткую историю
+package state
+
+import (
+	"asvsoft/internal/pkg/proto"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultHistorySize количество последних значений сигнала, хранимых в истории
+	DefaultHistorySize = 64
+	// DefaultStaleAfter время без новых сообщений, после которого сигнал считается устаревшим
+	DefaultStaleAfter = 5 * time.Second
+	// DefaultSubscriptionSize размер очереди уведомлений подписчика по умолчанию
+	DefaultSubscriptionSize = 16
+)
+
+// Key сигнал: сообщения одного режима одного модуля
+type Key struct {
+	Module proto.ModuleID
+	Msg    proto.MessageID
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s/%#x", proto.ModuleName(k.Module), uint8(k.Msg))
+}
+
+// Sample значение сигнала
+type Sample struct {
+	// SystemTime системное время модуля в мс (см. proto.Message)
+	SystemTime uint32
+	RecvTime   time.Time
+	Payload    proto.Packer
+}
+
+// Entry состояние сигнала на момент запроса
+type Entry struct {
+	Key
+	Latest Sample
+	// Count количество полученных сообщений
+	Count uint64
+	// Rate частота сообщений в секунду по значениям истории
+	Rate float64
+	// Age время с получения последнего сообщения
+	Age   time.Duration
+	Stale bool
+}
+
+// signal значения одного сигнала. history - кольцевой буфер, next - индекс следующей записи.
+type signal struct {
+	history []Sample
+	next    int
+	size    int
+	count   uint64
+	stale   bool
+}
+
+func (s *signal) add(sample Sample) {
+	s.history[s.next] = sample
+	s.next = (s.next + 1) % len(s.history)
+
+	if s.size < len(s.history) {
+		s.size++
+	}
+
+	s.count++
+}
+
+// samples возвращает историю от старых значений к новым.
+func (s *signal) samples() []Sample {
+	samples := make([]Sample, 0, s.size)
+
+	start := (s.next - s.size + len(s.history)) % len(s.history)
+	for i := range s.size {
+		samples = append(samples, s.history[(start+i)%len(s.history)])
+	}
+
+	return samples
+}
+
+func (s *signal) latest() Sample {
+	return s.history[(s.next-1+len(s.history))%len(s.history)]
+}
+
+func (s *signal) rate() float64 {
+	if s.size < 2 {
+		return 0
+	}
+
+	first := s.history[(s.next-s.size+len(s.history))%len(s.history)]
+
+	elapsed := s.latest().RecvTime.Sub(first.RecvTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return float64(s.size-1) / elapsed
+}
+
+// Subscription подписка на изменения состояния. При заполнении очереди уведомления
+// отбрасываются.
+type Subscription struct {
+	C       <-chan Entry
+	c       chan Entry
+	dropped atomic.Uint64
+}
+
+// Dropped возвращает количество отброшенных уведомлений.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) notify(e Entry) {
+	select {
+	case s.c <- e:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Store хранилище состояния. Безопасно для одновременного использования.
+type Store struct {
+	mu          sync.RWMutex
+	signals     map[Key]*signal
+	subs        []*Subscription
+	historySize int
+	staleAfter  time.Duration
+	now         func() time.Time
+}
+
+func New() *Store {
+	return &Store{
+		signals:     make(map[Key]*signal),
+		historySize: DefaultHistorySize,
+		staleAfter:  DefaultStaleAfter,
+		now:         time.Now,
+	}
+}
+
+// WithHistorySize задает количество хранимых значений каждого сигнала. Должен вызываться до
+// первого обновления.
+func (s *Store) WithHistorySize(size int) *Store {
+	if size > 0 {
+		s.historySize = size
+	}
+
+	return s
+}
+
+func (s *Store) WithStaleAfter(d time.Duration) *Store {
+	if d > 0 {
+		s.staleAfter = d
+	}
+
+	return s
+}
+
+// Subscribe подписывает на уведомления об изменениях: новых сообщениях и переходах сигналов
+// в устаревшие. size - размер очереди уведомлений.
+func (s *Store) Subscribe(size int) *Subscription {
+	if size <= 0 {
+		size = DefaultSubscriptionSize
+	}
+
+	c := make(chan Entry, size)
+	sub := &Subscription{C: c, c: c}
+
+	s.mu.Lock()
+	s.subs = append(s.subs, sub)
+	s.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe отменяет подписку и закрывает ее канал.
+func (s *Store) Unsubscribe(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, other := range s.subs {
+		if other == sub {
+			s.subs = append(s.subs[:i], s.subs[i+1:]...)
+			close(sub.c)
+
+			return
+		}
+	}
+}
+
+// Update сохраняет сообщение msg, полученное сейчас.
+func (s *Store) Update(msg proto.Message) {
+	s.UpdateAt(msg, s.now())
+}
+
+// UpdateAt сохраняет сообщение msg, полученное в момент recvTime.
+func (s *Store) UpdateAt(msg proto.Message, recvTime time.Time) {
+	key := Key{Module: msg.ModuleID, Msg: msg.MsgID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sig, ok := s.signals[key]
+	if !ok {
+		sig = &signal{history: make([]Sample, s.historySize)}
+		s.signals[key] = sig
+	}
+
+	sig.add(Sample{SystemTime: msg.SystemTime, RecvTime: recvTime, Payload: msg.Payload})
+	sig.stale = false
+
+	e := s.entry(key, sig, s.now())
+	for _, sub := range s.subs {
+		sub.notify(e)
+	}
+}
+
+// Handle сохраняет сообщение, позволяя подписать хранилище на шину сообщений.
+func (s *Store) Handle(msg proto.Message) error {
+	s.Update(msg)
+	return nil
+}
+
+func (s *Store) entry(key Key, sig *signal, now time.Time) Entry {
+	latest := sig.latest()
+	age := now.Sub(latest.RecvTime)
+
+	return Entry{
+		Key:    key,
+		Latest: latest,
+		Count:  sig.count,
+		Rate:   sig.rate(),
+		Age:    age,
+		Stale:  age > s.staleAfter,
+	}
+}
+
+// Get возвращает состояние сигнала режима msgID модуля moduleID.
+func (s *Store) Get(moduleID proto.ModuleID, msgID proto.MessageID) (Entry, bool) {
+	key := Key{Module: moduleID, Msg: msgID}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sig, ok := s.signals[key]
+	if !ok {
+		return Entry{}, false
+	}
+
+	return s.entry(key, sig, s.now()), true
+}
+
+// Module возвращает состояние всех сигналов модуля moduleID.
+func (s *Store) Module(moduleID proto.ModuleID) []Entry {
+	entries := s.Snapshot()
+
+	n := 0
+
+	for _, e := range entries {
+		if e.Module == moduleID {
+			entries[n] = e
+			n++
+		}
+	}
+
+	return entries[:n]
+}
+
+// Snapshot возвращает состояние всех сигналов, упорядоченное по модулю и режиму.
+func (s *Store) Snapshot() []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	entries := make([]Entry, 0, len(s.signals))
+
+	for key, sig := range s.signals {
+		entries = append(entries, s.entry(key, sig, now))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Module != entries[j].Module {
+			return entries[i].Module < entries[j].Module
+		}
+
+		return entries[i].Msg < entries[j].Msg
+	})
+
+	return entries
+}
+
+// History возвращает последние значения сигнала от старых к новым.
+func (s *Store) History(moduleID proto.ModuleID, msgID proto.MessageID) []Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sig, ok := s.signals[Key{Module: moduleID, Msg: msgID}]
+	if !ok {
+		return nil
+	}
+
+	return sig.samples()
+}
+
+// CheckStale уведомляет подписчиков о сигналах, ставших устаревшими с прошлой проверки.
+func (s *Store) CheckStale() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	for key, sig := range s.signals {
+		if sig.stale {
+			continue
+		}
+
+		e := s.entry(key, sig, now)
+		if !e.Stale {
+			continue
+		}
+
+		sig.stale = true
+
+		for _, sub := range s.subs {
+			sub.notify(e)
+		}
+	}
+}
+
+// Run проверяет устаревание сигналов до отмены контекста.
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.staleAfter / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.CheckStale()
+		}
+	}
+}
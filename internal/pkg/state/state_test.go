@@ -0,0 +1,104 @@
+package state
+
+import (
+	"asvsoft/internal/pkg/common"
+	"asvsoft/internal/pkg/proto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func depth(systemTime uint32, distance common.Uint24) proto.Message {
+	return proto.Message{
+		ModuleID:   proto.DepthMeterModuleID,
+		MsgID:      proto.WritingModeA,
+		SystemTime: systemTime,
+		Payload:    &proto.DepthMeterData{Distance: distance},
+	}
+}
+
+func TestStore(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+
+	s := New().WithHistorySize(3).WithStaleAfter(time.Second)
+	s.now = func() time.Time { return now }
+
+	sub := s.Subscribe(10)
+
+	for i := range 5 {
+		now = start.Add(time.Duration(i) * 100 * time.Millisecond)
+		s.Update(depth(uint32(i*100), common.Uint24(i)))
+	}
+
+	t.Run("последнее значение и частота", func(t *testing.T) {
+		e, ok := s.Get(proto.DepthMeterModuleID, proto.WritingModeA)
+		require.True(t, ok)
+		require.Equal(t, uint64(5), e.Count)
+		require.Equal(t, uint32(400), e.Latest.SystemTime)
+		require.Equal(t, common.Uint24(4), e.Latest.Payload.(*proto.DepthMeterData).Distance)
+		require.InDelta(t, 10, e.Rate, 1e-9)
+		require.False(t, e.Stale)
+
+		_, ok = s.Get(proto.GNSSModuleID, proto.WritingModeA)
+		require.False(t, ok)
+	})
+
+	t.Run("история ограничена размером буфера", func(t *testing.T) {
+		history := s.History(proto.DepthMeterModuleID, proto.WritingModeA)
+		require.Len(t, history, 3)
+		require.Equal(t, uint32(200), history[0].SystemTime)
+		require.Equal(t, uint32(400), history[2].SystemTime)
+	})
+
+	t.Run("уведомления о новых сообщениях", func(t *testing.T) {
+		require.Len(t, sub.C, 5)
+
+		for range 5 {
+			<-sub.C
+		}
+	})
+
+	t.Run("устаревание", func(t *testing.T) {
+		now = now.Add(2 * time.Second)
+
+		e, _ := s.Get(proto.DepthMeterModuleID, proto.WritingModeA)
+		require.True(t, e.Stale)
+		require.Equal(t, 2*time.Second, e.Age)
+
+		s.CheckStale()
+		s.CheckStale()
+
+		require.Len(t, sub.C, 1)
+		require.True(t, (<-sub.C).Stale)
+
+		s.Update(depth(3000, 7))
+		require.False(t, (<-sub.C).Stale)
+	})
+
+	t.Run("состояние модуля", func(t *testing.T) {
+		s.Update(proto.Message{ModuleID: proto.GNSSModuleID, MsgID: proto.WritingModeB, Payload: &proto.GNSSData{}})
+
+		require.Len(t, s.Snapshot(), 2)
+		require.Len(t, s.Module(proto.GNSSModuleID), 1)
+		require.Equal(t, "gnss/0x15", s.Module(proto.GNSSModuleID)[0].Key.String())
+	})
+
+	t.Run("переполненная подписка не блокирует обновление", func(t *testing.T) {
+		full := s.Subscribe(1)
+
+		s.Update(depth(4000, 1))
+		s.Update(depth(4100, 1))
+
+		require.Equal(t, uint64(1), full.Dropped())
+
+		s.Unsubscribe(full)
+
+		_, ok := <-full.C
+		require.True(t, ok)
+
+		_, ok = <-full.C
+		require.False(t, ok)
+	})
+}
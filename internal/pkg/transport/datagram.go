@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxDatagramSize максимальный размер принимаемой дейтаграммы
+const maxDatagramSize = 1 << 16
+
+// datagramConn канал поверх UDP. Каждый вызов Write отправляет одну дейтаграмму, поэтому
+// фрейм протокола, записанный одним вызовом, передается одной дейтаграммой. Чтение
+// буферизует принятую дейтаграмму и отдает ее по частям. В режиме ожидания подключения
+// ответы отправляются последнему собеседнику, от которого была получена дейтаграмма.
+type datagramConn struct {
+	ep      Endpoint
+	log     logger.Logger
+	mu      sync.Mutex
+	conn    *net.UDPConn
+	peer    net.Addr
+	buf     []byte
+	pending []byte
+	closed  bool
+}
+
+func newDatagramConn(ep Endpoint, log logger.Logger) (*datagramConn, error) {
+	c := &datagramConn{ep: ep, log: log, buf: make([]byte, maxDatagramSize)}
+
+	_, err := c.socket()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// socket возвращает текущий сокет, при необходимости создавая новый.
+func (c *datagramConn) socket() (*net.UDPConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, net.ErrClosed
+	}
+
+	if c.conn != nil {
+		return c.conn, nil
+	}
+
+	addr, err := net.ResolveUDPAddr(c.ep.Scheme, c.ep.Address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %w", c.ep, err)
+	}
+
+	if c.ep.Listen {
+		c.conn, err = net.ListenUDP(c.ep.Scheme, addr)
+	} else {
+		c.conn, err = net.DialUDP(c.ep.Scheme, nil, addr)
+		c.peer = addr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot open udp socket %s: %w", c.ep, err)
+	}
+
+	return c.conn, nil
+}
+
+func (c *datagramConn) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(c.pending) == 0 {
+			err = c.receive()
+			if err != nil {
+				return n, err
+			}
+
+			continue
+		}
+
+		copied := copy(p[n:], c.pending)
+		c.pending = c.pending[copied:]
+		n += copied
+	}
+
+	return n, nil
+}
+
+func (c *datagramConn) receive() error {
+	conn, err := c.socket()
+	if err != nil {
+		return err
+	}
+
+	if c.ep.Timeout != 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(c.ep.Timeout))
+	}
+
+	size, peer, err := conn.ReadFrom(c.buf)
+	if err != nil {
+		if isTimeout(err) {
+			return ErrReadTimeout
+		}
+
+		c.drop(conn, err)
+
+		return err
+	}
+
+	if c.ep.Listen {
+		c.mu.Lock()
+		c.peer = peer
+		c.mu.Unlock()
+	}
+
+	c.pending = c.buf[:size]
+
+	return nil
+}
+
+func (c *datagramConn) Write(p []byte) (int, error) {
+	conn, err := c.socket()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+
+	if c.ep.Listen {
+		c.mu.Lock()
+		peer := c.peer
+		c.mu.Unlock()
+
+		if peer == nil {
+			return 0, ErrNotConnected
+		}
+
+		n, err = conn.WriteTo(p, peer)
+	} else {
+		n, err = conn.Write(p)
+	}
+
+	if err != nil {
+		c.drop(conn, err)
+		return n, err
+	}
+
+	return n, nil
+}
+
+// drop закрывает сокет после ошибки, следующая операция создаст новый.
+func (c *datagramConn) drop(conn *net.UDPConn, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != conn {
+		return
+	}
+
+	_ = conn.Close()
+	c.conn = nil
+
+	c.log.Warnf("udp socket %s was dropped: %v", c.ep, err)
+}
+
+func (c *datagramConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+
+	return err
+}
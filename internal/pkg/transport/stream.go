@@ -0,0 +1,230 @@
+package transport
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// streamConn потоковый канал поверх TCP или Unix-сокета. В режиме ожидания подключения
+// обслуживает одного клиента, при его отключении принимает следующего. В режиме активного
+// подключения при обрыве связи переподключается к серверу.
+type streamConn struct {
+	ep       Endpoint
+	log      logger.Logger
+	mu       sync.Mutex
+	connMu   sync.Mutex
+	listener net.Listener
+	conn     net.Conn
+	delay    time.Duration
+	closed   bool
+}
+
+func newStreamConn(ep Endpoint, log logger.Logger) (*streamConn, error) {
+	c := &streamConn{ep: ep, log: log, delay: DefaultReconnectDelay}
+
+	if !ep.Listen {
+		return c, nil
+	}
+
+	if ep.Scheme == SchemeUnix {
+		err := os.Remove(ep.Address)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("fail to remove old socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen(ep.Scheme, ep.Address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen %s: %w", ep, err)
+	}
+
+	c.listener = listener
+
+	return c, nil
+}
+
+func (c *streamConn) Read(p []byte) (int, error) {
+	conn, err := c.connection()
+	if err != nil {
+		return 0, err
+	}
+
+	if c.ep.Timeout != 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(c.ep.Timeout))
+	}
+
+	n, err := io.ReadFull(conn, p)
+	if err != nil {
+		if isTimeout(err) {
+			return n, ErrReadTimeout
+		}
+
+		c.drop(conn, err)
+
+		return n, err
+	}
+
+	return n, nil
+}
+
+func (c *streamConn) Write(p []byte) (int, error) {
+	conn, err := c.connection()
+	if err != nil {
+		return 0, err
+	}
+
+	if c.ep.Timeout != 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(c.ep.Timeout))
+	}
+
+	n, err := conn.Write(p)
+	if err != nil {
+		c.drop(conn, err)
+		return n, err
+	}
+
+	return n, nil
+}
+
+// connection возвращает текущее соединение, при необходимости устанавливая новое.
+// Ожидание клиента и подключение выполняются без блокировки c.mu, чтобы Close мог их прервать.
+func (c *streamConn) connection() (net.Conn, error) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	c.mu.Lock()
+	closed, conn := c.closed, c.conn
+	c.mu.Unlock()
+
+	if closed {
+		return nil, net.ErrClosed
+	}
+
+	if conn != nil {
+		return conn, nil
+	}
+
+	var err error
+
+	if c.listener != nil {
+		conn, err = c.accept()
+	} else {
+		conn, err = c.dial()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		_ = conn.Close()
+		return nil, net.ErrClosed
+	}
+
+	c.conn = conn
+
+	return conn, nil
+}
+
+func (c *streamConn) accept() (net.Conn, error) {
+	if d, ok := c.listener.(interface{ SetDeadline(time.Time) error }); ok && c.ep.Timeout != 0 {
+		_ = d.SetDeadline(time.Now().Add(c.ep.Timeout))
+	}
+
+	conn, err := c.listener.Accept()
+	if err != nil {
+		if isTimeout(err) {
+			return nil, ErrNotConnected
+		}
+
+		return nil, fmt.Errorf("cannot accept connection on %s: %w", c.ep, err)
+	}
+
+	c.log.Infof("accepted connection from %s", conn.RemoteAddr())
+
+	return conn, nil
+}
+
+func (c *streamConn) dial() (net.Conn, error) {
+	timeout := c.ep.Timeout
+	if timeout == 0 {
+		timeout = MaxReconnectDelay
+	}
+
+	conn, err := net.DialTimeout(c.ep.Scheme, c.ep.Address, timeout)
+
+	c.mu.Lock()
+	delay := c.delay
+
+	if err != nil {
+		c.delay = min(2*c.delay, MaxReconnectDelay)
+	} else {
+		c.delay = DefaultReconnectDelay
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		c.log.Warnf("cannot connect to %s, next try in %v: %v", c.ep, delay, err)
+		time.Sleep(delay)
+
+		return nil, fmt.Errorf("cannot connect to %s: %w", c.ep, err)
+	}
+
+	c.log.Infof("connected to %s", c.ep)
+
+	return conn, nil
+}
+
+// drop закрывает соединение после ошибки, следующая операция установит новое.
+func (c *streamConn) drop(conn net.Conn, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != conn {
+		return
+	}
+
+	_ = conn.Close()
+	c.conn = nil
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		c.log.Warnf("connection to %s was closed by peer", c.ep)
+		return
+	}
+
+	c.log.Warnf("connection to %s was dropped: %v", c.ep, err)
+}
+
+func (c *streamConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	var errConn, errListener error
+
+	if c.conn != nil {
+		errConn = c.conn.Close()
+		c.conn = nil
+	}
+
+	if c.listener != nil {
+		errListener = c.listener.Close()
+	}
+
+	return errors.Join(errConn, errListener)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
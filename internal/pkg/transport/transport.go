@@ -0,0 +1,162 @@
+// Package transport предоставляет единый способ открытия каналов связи между модулями БКУ:
+// последовательных портов, TCP и UDP сокетов, Unix-сокетов.
+package transport
+
+import (
+	"asvsoft/internal/pkg/logger"
+	serialport "asvsoft/internal/pkg/serial-port"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SchemeSerial = "serial"
+	SchemeTCP    = "tcp"
+	SchemeUDP    = "udp"
+	SchemeUnix   = "unix"
+)
+
+const (
+	// DefaultReconnectDelay начальная задержка перед повторным подключением
+	DefaultReconnectDelay = 100 * time.Millisecond
+	// MaxReconnectDelay максимальная задержка перед повторным подключением
+	MaxReconnectDelay = 5 * time.Second
+)
+
+var (
+	// ErrReadTimeout таймаут чтения
+	ErrReadTimeout = serialport.ErrReadTimeout
+	// ErrNotConnected канал в режиме ожидания подключения еще не имеет собеседника
+	ErrNotConnected = errors.New("not connected")
+)
+
+// Endpoint адрес канала связи. Задается в виде URL:
+//
+//	serial:///dev/ttyAMA5?baud=9600&timeout=500ms
+//	tcp://192.168.0.10:5600
+//	tcp://:5600?listen=true
+//	udp://127.0.0.1:5600
+//	unix:///tmp/asvsoft.sock?listen=true
+//
+// Строка без схемы трактуется как путь до последовательного порта.
+type Endpoint struct {
+	Scheme string
+	// Address путь до устройства или сокета, либо host:port
+	Address string
+	// BaudRate скорость последовательного порта в бит/с
+	BaudRate int
+	// Timeout таймаут чтения, 0 - без таймаута
+	Timeout time.Duration
+	// Listen флаг ожидания входящего подключения вместо активного подключения
+	Listen bool
+}
+
+// ParseEndpoint разбирает адрес канала связи. Параметры, не указанные в адресе,
+// берутся из defaults.
+func ParseEndpoint(raw string, defaults Endpoint) (Endpoint, error) {
+	ep := defaults
+	ep.Scheme = SchemeSerial
+
+	if raw == "" {
+		return ep, fmt.Errorf("endpoint is empty")
+	}
+
+	if !strings.Contains(raw, "://") {
+		ep.Address = raw
+		return ep, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ep, fmt.Errorf("cannot parse endpoint %q: %w", raw, err)
+	}
+
+	ep.Scheme = u.Scheme
+
+	switch u.Scheme {
+	case SchemeSerial, SchemeUnix:
+		ep.Address = u.Path
+	case SchemeTCP, SchemeUDP:
+		ep.Address = u.Host
+	default:
+		return ep, fmt.Errorf("unknown endpoint scheme %q", u.Scheme)
+	}
+
+	if ep.Address == "" {
+		return ep, fmt.Errorf("endpoint %q has no address", raw)
+	}
+
+	query := u.Query()
+
+	if v := query.Get("baud"); v != "" {
+		ep.BaudRate, err = strconv.Atoi(v)
+		if err != nil {
+			return ep, fmt.Errorf("bad baud rate %q: %w", v, err)
+		}
+	}
+
+	if v := query.Get("timeout"); v != "" {
+		ep.Timeout, err = time.ParseDuration(v)
+		if err != nil {
+			return ep, fmt.Errorf("bad timeout %q: %w", v, err)
+		}
+	}
+
+	if v := query.Get("listen"); v != "" {
+		ep.Listen, err = strconv.ParseBool(v)
+		if err != nil {
+			return ep, fmt.Errorf("bad listen flag %q: %w", v, err)
+		}
+	}
+
+	return ep, nil
+}
+
+func (e Endpoint) String() string {
+	if e.Scheme == SchemeSerial {
+		return fmt.Sprintf("%s://%s?baud=%d", e.Scheme, e.Address, e.BaudRate)
+	}
+
+	return fmt.Sprintf("%s://%s?listen=%v", e.Scheme, e.Address, e.Listen)
+}
+
+// Resetter реализуется каналами, поддерживающими сброс буферов ввода/вывода.
+type Resetter interface {
+	ResetInputBuffer() error
+	ResetOutputBuffer() error
+}
+
+// Open открывает канал связи по адресу ep. Все каналы при чтении заполняют буфер целиком,
+// как это делает serialport.Wrapper, и самостоятельно восстанавливают соединение:
+// последовательный порт переоткрывается после закрытия, потоковые сокеты переподключаются
+// (или ожидают нового клиента) с экспоненциальной задержкой, UDP сокет пересоздается.
+func Open(ep Endpoint, log logger.Logger) (io.ReadWriteCloser, error) {
+	if log == nil {
+		log = logger.DummyLogger{}
+	}
+
+	switch ep.Scheme {
+	case SchemeSerial:
+		port, err := serialport.New(serialport.Config{
+			Port:     ep.Address,
+			BaudRate: ep.BaudRate,
+			Timeout:  ep.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return port.SetLogger(log), nil
+	case SchemeTCP, SchemeUnix:
+		return newStreamConn(ep, log)
+	case SchemeUDP:
+		return newDatagramConn(ep, log)
+	default:
+		return nil, fmt.Errorf("unknown endpoint scheme %q", ep.Scheme)
+	}
+}
@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"asvsoft/internal/pkg/logger"
+	"asvsoft/internal/pkg/proto"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	defaults := Endpoint{BaudRate: 9600, Timeout: time.Second}
+
+	tests := []struct {
+		name string
+		raw  string
+		want Endpoint
+	}{
+		{
+			name: "путь до последовательного порта без схемы",
+			raw:  "/dev/ttyAMA5",
+			want: Endpoint{Scheme: SchemeSerial, Address: "/dev/ttyAMA5", BaudRate: 9600, Timeout: time.Second},
+		},
+		{
+			name: "последовательный порт с параметрами",
+			raw:  "serial:///dev/ttyAMA5?baud=4800&timeout=500ms",
+			want: Endpoint{Scheme: SchemeSerial, Address: "/dev/ttyAMA5", BaudRate: 4800, Timeout: 500 * time.Millisecond},
+		},
+		{
+			name: "udp",
+			raw:  "udp://127.0.0.1:5600",
+			want: Endpoint{Scheme: SchemeUDP, Address: "127.0.0.1:5600", BaudRate: 9600, Timeout: time.Second},
+		},
+		{
+			name: "tcp в режиме ожидания подключения",
+			raw:  "tcp://:5600?listen=true",
+			want: Endpoint{Scheme: SchemeTCP, Address: ":5600", BaudRate: 9600, Timeout: time.Second, Listen: true},
+		},
+		{
+			name: "unix-сокет",
+			raw:  "unix:///tmp/asvsoft.sock?listen=1",
+			want: Endpoint{Scheme: SchemeUnix, Address: "/tmp/asvsoft.sock", BaudRate: 9600, Timeout: time.Second, Listen: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep, err := ParseEndpoint(tt.raw, defaults)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ep)
+		})
+	}
+
+	t.Run("неизвестная схема", func(t *testing.T) {
+		_, err := ParseEndpoint("http://localhost", defaults)
+		require.Error(t, err)
+	})
+
+	t.Run("некорректная скорость", func(t *testing.T) {
+		_, err := ParseEndpoint("serial:///dev/ttyS0?baud=fast", defaults)
+		require.Error(t, err)
+	})
+}
+
+func TestOpenExchange(t *testing.T) {
+	tests := []struct {
+		name   string
+		server Endpoint
+		client Endpoint
+	}{
+		{
+			name:   "tcp",
+			server: Endpoint{Scheme: SchemeTCP, Address: "127.0.0.1:0", Listen: true, Timeout: time.Second},
+		},
+		{
+			name:   "udp",
+			server: Endpoint{Scheme: SchemeUDP, Address: "127.0.0.1:0", Listen: true, Timeout: time.Second},
+		},
+		{
+			name:   "unix",
+			server: Endpoint{Scheme: SchemeUnix, Address: filepath.Join(t.TempDir(), "asvsoft.sock"), Listen: true, Timeout: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := Open(tt.server, logger.DummyLogger{})
+			require.NoError(t, err)
+
+			defer server.Close()
+
+			client, err := Open(Endpoint{
+				Scheme:  tt.server.Scheme,
+				Address: boundAddress(server),
+				Timeout: time.Second,
+			}, logger.DummyLogger{})
+			require.NoError(t, err)
+
+			defer client.Close()
+
+			data := proto.CheckData{Value: 42}
+
+			frame, err := proto.NewMessage(proto.CheckModuleID, proto.WritingModeA, &data).Marshal()
+			require.NoError(t, err)
+
+			// два фрейма подряд: канал должен сохранять границы и порядок байтов
+			for range 2 {
+				_, err = client.Write(frame)
+				require.NoError(t, err)
+
+				raw, err := proto.Read(server)
+				require.NoError(t, err)
+				require.Equal(t, frame, raw)
+			}
+
+			ack, err := proto.NewMessage(proto.ControlModuleID, proto.ResponseOK, nil).Marshal()
+			require.NoError(t, err)
+
+			_, err = server.Write(ack)
+			require.NoError(t, err)
+
+			raw, err := proto.Read(client)
+			require.NoError(t, err)
+			require.Equal(t, ack, raw)
+		})
+	}
+}
+
+func TestStreamReconnect(t *testing.T) {
+	server, err := Open(Endpoint{Scheme: SchemeTCP, Address: "127.0.0.1:0", Listen: true, Timeout: time.Second}, nil)
+	require.NoError(t, err)
+
+	defer server.Close()
+
+	addr := boundAddress(server)
+
+	for i := range 2 {
+		client, err := Open(Endpoint{Scheme: SchemeTCP, Address: addr, Timeout: time.Second}, nil)
+		require.NoError(t, err)
+
+		_, err = client.Write([]byte{byte(i)})
+		require.NoError(t, err)
+
+		b := make([]byte, 1)
+
+		_, err = server.Read(b)
+		require.NoError(t, err)
+		require.Equal(t, byte(i), b[0])
+
+		require.NoError(t, client.Close())
+
+		// сервер замечает отключение клиента и ожидает следующего
+		_, err = server.Read(b)
+		require.Error(t, err)
+	}
+}
+
+func boundAddress(c any) string {
+	switch conn := c.(type) {
+	case *streamConn:
+		return conn.listener.Addr().String()
+	case *datagramConn:
+		return conn.conn.LocalAddr().String()
+	default:
+		panic("unexpected conn type")
+	}
+}
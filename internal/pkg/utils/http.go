@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// HTTPReadHeaderTimeout максимальное время чтения заголовков запроса
+	HTTPReadHeaderTimeout = 5 * time.Second
+	// HTTPShutdownTimeout время на завершение обработки запросов после отмены контекста
+	HTTPShutdownTimeout = time.Second
+)
+
+// ServeHTTP обслуживает запросы, принимаемые l, обработчиком handler до отмены контекста.
+// Контекст запросов наследуется от ctx, поэтому его отмена завершает и длительные запросы.
+func ServeHTTP(ctx context.Context, l net.Listener, handler http.Handler) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: HTTPReadHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), HTTPShutdownTimeout)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	err := srv.Serve(l)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
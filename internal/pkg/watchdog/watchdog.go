@@ -0,0 +1,124 @@
+// Package watchdog предоставляет контроль молчания модулей: модуль считается молчащим, если
+// от него нет сообщений дольше таймаута, и восстановившимся после первого нового сообщения
+package watchdog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultPeriods количество пропущенных периодов ожидаемой частоты, после которого модуль
+// считается молчащим, если таймаут не задан
+const DefaultPeriods = 3
+
+// Kind вид события
+type Kind string
+
+const (
+	Silent    Kind = "silent"
+	Recovered Kind = "recovered"
+)
+
+// Event переход модуля в молчащие или восстановление
+type Event struct {
+	Module string
+	Kind   Kind
+	At     time.Time
+	// LastMessage время последнего сообщения, нулевое - сообщений не было
+	LastMessage time.Time
+	// Silence время без сообщений: на момент события Silent или до восстановления
+	Silence time.Duration
+}
+
+// Timeout возвращает таймаут молчания: timeout, если задан, иначе DefaultPeriods периодов
+// ожидаемой частоты rate сообщений в секунду. Возвращает 0, если не задано ни то, ни другое.
+func Timeout(timeout time.Duration, rate float64) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+
+	if rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(DefaultPeriods * float64(time.Second) / rate)
+}
+
+// Watchdog контроль молчания одного модуля. Feed безопасен для одновременного вызова с Check,
+// Check вызывается из одной горутины.
+type Watchdog struct {
+	module  string
+	timeout time.Duration
+	// last время последнего сообщения в нс, 0 - сообщений не было
+	last atomic.Int64
+	// start отсчет молчания до первого сообщения
+	start  time.Time
+	silent bool
+	// silentSince время последнего сообщения на момент перехода в молчащие
+	silentSince time.Time
+}
+
+// New создает контроль модуля module, молчание отсчитывается от start.
+func New(module string, timeout time.Duration, start time.Time) *Watchdog {
+	return &Watchdog{
+		module:  module,
+		timeout: timeout,
+		start:   start,
+	}
+}
+
+func (w *Watchdog) Timeout() time.Duration {
+	return w.timeout
+}
+
+// Feed отмечает сообщение модуля, полученное в момент t.
+func (w *Watchdog) Feed(t time.Time) {
+	w.last.Store(t.UnixNano())
+}
+
+func (w *Watchdog) lastMessage() time.Time {
+	last := w.last.Load()
+	if last == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, last)
+}
+
+// Check проверяет молчание модуля в момент now и возвращает событие при переходе в молчащие
+// или восстановлении.
+func (w *Watchdog) Check(now time.Time) (Event, bool) {
+	last := w.lastMessage()
+
+	since := last
+	if since.IsZero() {
+		since = w.start
+	}
+
+	ev := Event{Module: w.module, At: now, LastMessage: last}
+
+	switch {
+	case !w.silent && now.Sub(since) > w.timeout:
+		w.silent = true
+		w.silentSince = since
+
+		ev.Kind = Silent
+		ev.Silence = now.Sub(since)
+
+		return ev, true
+	case w.silent && last.After(w.silentSince):
+		w.silent = false
+
+		ev.Kind = Recovered
+		ev.Silence = last.Sub(w.silentSince)
+
+		return ev, true
+	}
+
+	return Event{}, false
+}
+
+// Silent сообщает, молчит ли модуль по результату последней проверки.
+func (w *Watchdog) Silent() bool {
+	return w.silent
+}
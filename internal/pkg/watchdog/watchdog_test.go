@@ -0,0 +1,57 @@
+package watchdog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	require.Equal(t, 2*time.Second, Timeout(2*time.Second, 10))
+	require.Equal(t, 300*time.Millisecond, Timeout(0, 10))
+	require.Zero(t, Timeout(0, 0))
+}
+
+func TestWatchdog(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	w := New("gnss", time.Second, start)
+
+	t.Run("молчание без сообщений после запуска", func(t *testing.T) {
+		_, ok := w.Check(start.Add(500 * time.Millisecond))
+		require.False(t, ok)
+
+		ev, ok := w.Check(start.Add(1500 * time.Millisecond))
+		require.True(t, ok)
+		require.Equal(t, Silent, ev.Kind)
+		require.Equal(t, "gnss", ev.Module)
+		require.True(t, ev.LastMessage.IsZero())
+		require.Equal(t, 1500*time.Millisecond, ev.Silence)
+		require.True(t, w.Silent())
+
+		_, ok = w.Check(start.Add(3 * time.Second))
+		require.False(t, ok, "событие молчания выдается один раз")
+	})
+
+	t.Run("восстановление после сообщения", func(t *testing.T) {
+		w.Feed(start.Add(4 * time.Second))
+
+		ev, ok := w.Check(start.Add(4100 * time.Millisecond))
+		require.True(t, ok)
+		require.Equal(t, Recovered, ev.Kind)
+		require.Equal(t, 4*time.Second, ev.Silence)
+		require.False(t, w.Silent())
+	})
+
+	t.Run("повторное молчание от последнего сообщения", func(t *testing.T) {
+		w.Feed(start.Add(5 * time.Second))
+
+		_, ok := w.Check(start.Add(5900 * time.Millisecond))
+		require.False(t, ok)
+
+		ev, ok := w.Check(start.Add(6100 * time.Millisecond))
+		require.True(t, ok)
+		require.Equal(t, Silent, ev.Kind)
+		require.True(t, start.Add(5*time.Second).Equal(ev.LastMessage))
+	})
+}